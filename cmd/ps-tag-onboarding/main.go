@@ -2,18 +2,27 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	_ "github.com/viniciusgferreira/ps-tag-onboarding-go/docs"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/httpserver"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/logging"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/metrics"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/publisher"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/ratelimit"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/repository"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/tracing"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/app"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 )
 
 // @title           Tag Onboarding Go API
@@ -21,44 +30,265 @@ import (
 // @description     This is an api to manager users.
 
 // @host      localhost:8080
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT bearer token, as "Bearer <token>"
 func main() {
 	cfg := config.New()
+	logger, err := logging.New(*cfg.Log, os.Stdout)
+	if err != nil {
+		panic(err)
+	}
+	slog.SetDefault(logger)
 	slog.Info("Starting the application", "app", cfg.App.Name, "env", cfg.App.Env)
 	ctx := context.Background()
-	db, err := config.Connect(ctx, *cfg.DB)
+	shutdownTracing, err := tracing.Setup(ctx, *cfg.Tracing, cfg.App.Name)
+	if err != nil {
+		panic(err)
+	}
+	appMetrics := metrics.New()
+	store, err := newStorage(ctx, cfg, appMetrics.CommandMonitor())
+	if err != nil {
+		panic(err)
+	}
+	policy, err := service.NewPolicy(cfg.Auth.Roles)
 	if err != nil {
 		panic(err)
 	}
-	userRepo := repository.NewUserRepo(db)
-	userService := service.NewUserService(userRepo)
+	userOpts := []service.Option{service.WithTransactor(store.tx), service.WithAuditLog(store.audit),
+		service.WithOutbox(store.outbox), service.WithPolicy(policy), service.WithMetrics(appMetrics)}
+	if cfg.Storage.CaseInsensitiveNames {
+		userOpts = append(userOpts, service.WithCaseInsensitiveNames())
+	}
+	userService := service.NewUserService(store.users, userOpts...)
+
+	webhookService := service.NewWebhookService(store.webhooks, publisher.NewSignedWebhookSender(nil),
+		service.WithRetryPolicy(service.RetryPolicy{
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			BaseDelay:   cfg.Webhooks.BaseDelay,
+			MaxDelay:    cfg.Webhooks.MaxDelay,
+		}),
+		service.WithDispatchInterval(cfg.Webhooks.DispatchInterval))
 
+	eventPublisher, closePublisher, err := newPublisher(*cfg.Events)
+	if err != nil {
+		panic(err)
+	}
+	// webhooks come first: a failing publisher would otherwise hold back their deliveries
+	publishers := publisher.MultiPublisher{webhookService}
+	if eventPublisher != nil {
+		publishers = append(publishers, eventPublisher)
+	}
 	var serverHandlers []httpserver.HttpHandlers
-	serverHandlers = append(serverHandlers, httpserver.NewUserHandler(userService))
+	serverHandlers = append(serverHandlers, httpserver.NewUserHandler(userService), httpserver.NewWebhookHandler(webhookService))
+
+	authenticator, err := auth.New(ctx, *cfg.Auth, cfg.HTTP.AdminKey)
+	if err != nil {
+		panic(err)
+	}
+	var checks []httpserver.HealthCheck
+	if store.ping != nil {
+		checks = append(checks, httpserver.HealthCheck{Name: cfg.Storage.Driver, Check: store.ping})
+	}
+	health := httpserver.NewHealth(cfg.HTTP.ReadinessTimeout, checks...)
+	serverOpts := []httpserver.ServerOption{
+		httpserver.WithIdempotency(store.idempotency, cfg.Idempotency.TTL, cfg.Idempotency.Lease),
+		httpserver.WithMetrics(appMetrics),
+		httpserver.WithTracing(cfg.App.Name),
+		httpserver.WithHealth(health),
+	}
+	serverOpts = append(serverOpts, httpserver.WithAuthenticator(authenticator))
+	if !authenticator.Enabled() {
+		slog.Warn("No API key nor JWT configured, requests are not authenticated")
+	}
+	if len(cfg.Auth.Roles) > 0 && !authenticator.Enabled() {
+		panic("auth.roles requires API keys or JWT settings")
+	}
+	serverOpts = append(serverOpts, httpserver.WithAuthorizer(policy))
+	limiter, err := ratelimit.New(*cfg.RateLimit, ratelimit.NewMemoryStore())
+	if err != nil {
+		panic(err)
+	}
+	if limiter.Enabled() {
+		serverOpts = append(serverOpts, httpserver.WithRateLimiter(limiter))
+	}
+	server := httpserver.NewServer(cfg.HTTP, serverHandlers, serverOpts...)
 
-	server := httpserver.NewServer(cfg.HTTP, serverHandlers)
+	runner := app.NewRunner(server,
+		app.WithDrain(health, cfg.HTTP.DrainDelay),
+		app.WithShutdownTimeout(cfg.HTTP.ShutdownTimeout),
+		app.WithWorker("outbox relay", service.NewRelay(store.outbox, publishers, cfg.Events.RelayInterval).Run),
+		app.WithWorker("webhook dispatcher", webhookService.Run),
+		// the publisher and the storage are used by the requests and the workers, traces are flushed last
+		app.WithCloser("events publisher", func(context.Context) error { return closePublisher() }),
+		app.WithCloser("storage", store.close),
+		app.WithCloser("tracing", shutdownTracing),
+	)
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	err = runner.Run(signalCtx)
+	stop()
+	if err != nil {
+		slog.Error("Failed to run the application", "error", err)
+		os.Exit(1)
+	}
+}
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+// storage holds the repositories of the storage backend, the transactor they share
+// and the functions checking and releasing their resources.
+type storage struct {
+	users       service.UserRepository
+	audit       service.AuditLog
+	outbox      service.Outbox
+	webhooks    service.WebhookRepository
+	idempotency service.IdempotencyStore
+	tx          service.Transactor
+	// ping checks the connection to the database, nil for the in-memory storage.
+	ping  func(ctx context.Context) error
+	close func(ctx context.Context) error
+}
 
-	go func() {
-		slog.Info("Server listening on " + server.Addr)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			slog.Error("Failed starting server", "error", err)
-			os.Exit(1)
+// newStorage builds the repositories of the backend selected by the storage driver and migrates them.
+func newStorage(ctx context.Context, cfg config.Config, monitor *event.CommandMonitor) (*storage, error) {
+	switch cfg.Storage.Driver {
+	case config.DriverMemory:
+		slog.Warn("Using in-memory storage, users are lost on shutdown")
+		userRepo := repository.NewUserMemoryRepo(cfg.Storage.CaseInsensitiveNames)
+		auditRepo := repository.NewAuditMemoryRepo()
+		outboxRepo := repository.NewOutboxMemoryRepo()
+		return &storage{
+			users:       userRepo,
+			audit:       auditRepo,
+			outbox:      outboxRepo,
+			webhooks:    repository.NewWebhookMemoryRepo(),
+			idempotency: repository.NewIdempotencyMemoryRepo(),
+			tx:          repository.NewMemoryTransactor(),
+			close:       func(context.Context) error { return nil },
+		}, nil
+	case config.DriverMongo:
+		if cfg.DB == nil {
+			return nil, errors.New("storage driver mongo needs the mongo section of the config")
 		}
-	}()
-
-	sig := <-sigCh
-	slog.Info("Shutting down...", "Received signal", sig)
-	if err := db.Client().Disconnect(ctx); err != nil {
-		slog.Error("Failed to disconnect from MongoDB", "error", err)
+		db, err := config.Connect(ctx, *cfg.DB, monitor)
+		if err != nil {
+			return nil, err
+		}
+		userRepo := repository.NewUserRepo(db, cfg.Storage.CaseInsensitiveNames)
+		if err := userRepo.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		auditRepo := repository.NewAuditMongoRepo(db)
+		if err := auditRepo.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		outboxRepo := repository.NewOutboxMongoRepo(db)
+		if err := outboxRepo.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		webhookRepo := repository.NewWebhookMongoRepo(db)
+		if err := webhookRepo.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		idempotencyRepo := repository.NewIdempotencyMongoRepo(db)
+		if err := idempotencyRepo.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		var tx service.Transactor
+		transactional, err := repository.SupportsTransactions(ctx, db.Client())
+		if err != nil {
+			return nil, fmt.Errorf("checking mongo transaction support: %w", err)
+		}
+		if transactional {
+			tx = repository.NewMongoTransactor(db.Client())
+		} else {
+			slog.Warn("Mongo is not a replica set: writes run without transactions and atomic imports are rejected")
+		}
+		return &storage{
+			users:       userRepo,
+			audit:       auditRepo,
+			outbox:      outboxRepo,
+			webhooks:    webhookRepo,
+			idempotency: idempotencyRepo,
+			tx:          tx,
+			ping: func(ctx context.Context) error {
+				return db.Client().Ping(ctx, readpref.Primary())
+			},
+			close: db.Client().Disconnect,
+		}, nil
+	case config.DriverPostgres:
+		if cfg.Postgres == nil {
+			return nil, errors.New("storage driver postgres needs the postgres section of the config")
+		}
+		db, err := config.ConnectPostgres(ctx, *cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		userRepo := repository.NewUserPostgresRepo(db, cfg.Storage.CaseInsensitiveNames)
+		if err := userRepo.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		return &storage{
+			users:       userRepo,
+			audit:       repository.NewAuditPostgresRepo(db),
+			outbox:      repository.NewOutboxPostgresRepo(db),
+			webhooks:    repository.NewWebhookPostgresRepo(db),
+			idempotency: repository.NewIdempotencyPostgresRepo(db),
+			tx:          repository.NewSQLTransactor(db),
+			ping:        db.PingContext,
+			close:       closeSQL(db),
+		}, nil
+	case config.DriverSQLite:
+		db, err := config.ConnectSQLite(ctx, *cfg.SQLite)
+		if err != nil {
+			return nil, err
+		}
+		userRepo := repository.NewUserSQLiteRepo(db, cfg.Storage.CaseInsensitiveNames)
+		if err := userRepo.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		return &storage{
+			users:       userRepo,
+			audit:       repository.NewAuditSQLiteRepo(db),
+			outbox:      repository.NewOutboxSQLiteRepo(db),
+			webhooks:    repository.NewWebhookSQLiteRepo(db),
+			idempotency: repository.NewIdempotencySQLiteRepo(db),
+			tx:          repository.NewSQLTransactor(db),
+			ping:        db.PingContext,
+			close:       closeSQL(db),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
 	}
+}
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		slog.Error("Failed to shutdown server", "error", err)
-		os.Exit(1)
+func closeSQL(db *sql.DB) func(context.Context) error {
+	return func(context.Context) error { return db.Close() }
+}
+
+// newPublisher builds the publisher selected by the events config, nil when events are only delivered to webhooks,
+// and the function releasing its resources.
+func newPublisher(cfg config.Events) (service.Publisher, func() error, error) {
+	noop := func() error { return nil }
+	switch cfg.Publisher {
+	case config.PublisherFile:
+		filePublisher, file, err := publisher.NewFilePublisher(cfg.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		return filePublisher, file.Close, nil
+	case config.PublisherWebhook:
+		if cfg.WebhookURL == "" {
+			return nil, nil, errors.New("the webhook publisher needs events.webhookUrl")
+		}
+		return publisher.NewWebhookPublisher(cfg.WebhookURL, nil), noop, nil
+	case config.PublisherNone:
+		return nil, noop, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown events publisher %q", cfg.Publisher)
 	}
-	slog.Info("Server shutdown complete.")
 }
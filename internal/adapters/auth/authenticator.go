@@ -0,0 +1,113 @@
+// Package auth authenticates API requests with static API keys and JWT bearer tokens.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	// AdminKeyHeader carries the admin key, an API key with every permission.
+	AdminKeyHeader = "X-Admin-Key"
+	// AdminSubject is the subject, and role, of the callers authenticated by the admin key.
+	AdminSubject = "admin"
+	bearerPrefix = "Bearer "
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator identifies the caller of a request from its X-API-Key or X-Admin-Key header or its Authorization
+// bearer token.
+type Authenticator struct {
+	// apiKeys are indexed by the SHA-256 hash of the key, so that looking them up does not leak them through timing.
+	apiKeys map[[sha256.Size]byte]service.Principal
+	jwt     *jwtVerifier
+	// required is set when API keys or JWT settings are configured: requests without credentials are rejected.
+	required bool
+}
+
+// New builds the Authenticator configured by cfg, registering adminKey, unless empty, as the API key of the
+// AdminSubject. The keys of a JWKS URL are fetched before it returns.
+func New(ctx context.Context, cfg config.Auth, adminKey string) (*Authenticator, error) {
+	a := &Authenticator{apiKeys: map[[sha256.Size]byte]service.Principal{}, required: len(cfg.APIKeys) > 0 || cfg.JWT != nil}
+	if adminKey != "" {
+		a.apiKeys[sha256.Sum256([]byte(adminKey))] = service.Principal{
+			Subject: AdminSubject,
+			Roles:   []string{AdminSubject},
+			Method:  service.AuthAdminKey,
+		}
+	}
+	for i, apiKey := range cfg.APIKeys {
+		if apiKey.Key == "" || apiKey.Subject == "" {
+			return nil, fmt.Errorf("api key %d requires a key and a subject", i)
+		}
+		hash := sha256.Sum256([]byte(apiKey.Key))
+		if _, ok := a.apiKeys[hash]; ok {
+			return nil, fmt.Errorf("api key %d is duplicated, or is the admin key", i)
+		}
+		a.apiKeys[hash] = service.Principal{
+			Subject: apiKey.Subject,
+			Roles:   slices.Clone(apiKey.Roles),
+			Scopes:  slices.Clone(apiKey.Scopes),
+			Method:  service.AuthAPIKey,
+		}
+	}
+	if cfg.JWT != nil {
+		verifier, err := newJWTVerifier(ctx, *cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = verifier
+	}
+	return a, nil
+}
+
+// Enabled reports whether requests must be authenticated, that is whether API keys or JWT settings are configured.
+// The admin key alone does not require the other requests to be authenticated.
+func (a *Authenticator) Enabled() bool {
+	return a.required
+}
+
+// Authenticate returns the caller of r, nil for requests without credentials when authentication is not Enabled.
+// Errors wrap ErrMissingCredentials or ErrInvalidCredentials.
+func (a *Authenticator) Authenticate(r *http.Request) (*service.Principal, error) {
+	for _, header := range []string{APIKeyHeader, AdminKeyHeader} {
+		if key := r.Header.Get(header); key != "" {
+			principal, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+			}
+			principal.Roles, principal.Scopes = slices.Clone(principal.Roles), slices.Clone(principal.Scopes)
+			return &principal, nil
+		}
+	}
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		if !a.required {
+			return nil, nil
+		}
+		return nil, ErrMissingCredentials
+	}
+	if len(authorization) < len(bearerPrefix) || !strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		return nil, fmt.Errorf("%w: unsupported authorization scheme", ErrInvalidCredentials)
+	}
+	if a.jwt == nil {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidCredentials)
+	}
+	principal, err := a.jwt.verify(r.Context(), strings.TrimSpace(authorization[len(bearerPrefix):]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	return principal, nil
+}
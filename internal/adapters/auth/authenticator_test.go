@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const hmacSecret = "0123456789abcdef0123456789abcdef"
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	return key
+}
+
+// jwks encodes the public keys by key ID as a JSON Web Key Set.
+func jwks(keys map[string]*rsa.PrivateKey) []byte {
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA", Kid: kid, Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	content, _ := json.Marshal(set)
+	return content
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	return signed
+}
+
+func request(header string, value string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	if header != "" {
+		r.Header.Set(header, value)
+	}
+	return r
+}
+
+func TestAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	rsaKey := newRSAKey(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, jwks(map[string]*rsa.PrivateKey{"k1": rsaKey}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := New(ctx, config.Auth{
+		APIKeys: []config.APIKey{{Key: "key-1", Subject: "reporting", Roles: []string{"reader"}, Scopes: []string{"users:read"}}},
+		JWT:     &config.JWT{HMACSecret: hmacSecret, JWKSFile: jwksFile, Issuer: "https://issuer.example.com", Audience: "users-api"},
+	}, "admin-key")
+	if err != nil {
+		t.Fatalf("error building authenticator: %v", err)
+	}
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"sub": "alice", "iss": "https://issuer.example.com", "aud": "users-api",
+			"exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"admin"}, "scope": "users:read users:write",
+		}
+		for name, value := range overrides {
+			claims[name] = value
+		}
+		return claims
+	}
+	alice := &service.Principal{Subject: "alice", Roles: []string{"admin"}, Scopes: []string{"users:read", "users:write"}, Method: service.AuthJWT}
+
+	t.Run("Authenticate API keys", func(t *testing.T) {
+		principal, err := authenticator.Authenticate(request(APIKeyHeader, "key-1"))
+		expected := &service.Principal{Subject: "reporting", Roles: []string{"reader"}, Scopes: []string{"users:read"}, Method: service.AuthAPIKey}
+		if err != nil || !reflect.DeepEqual(principal, expected) {
+			t.Errorf("expected: %+v, result: %+v, %v", expected, principal, err)
+		}
+	})
+	t.Run("Authenticate the admin key", func(t *testing.T) {
+		principal, err := authenticator.Authenticate(request(AdminKeyHeader, "admin-key"))
+		expected := &service.Principal{Subject: AdminSubject, Roles: []string{AdminSubject}, Method: service.AuthAdminKey}
+		if err != nil || !reflect.DeepEqual(principal, expected) {
+			t.Errorf("expected: %+v, result: %+v, %v", expected, principal, err)
+		}
+	})
+	t.Run("Authenticate HS256 and RS256 tokens", func(t *testing.T) {
+		for name, token := range map[string]string{
+			"HS256": sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", claims(nil)),
+			"RS256": sign(t, jwt.SigningMethodRS256, rsaKey, "k1", claims(nil)),
+		} {
+			principal, err := authenticator.Authenticate(request("Authorization", "Bearer "+token))
+			if err != nil || !reflect.DeepEqual(principal, alice) {
+				t.Errorf("%s: expected: %+v, result: %+v, %v", name, alice, principal, err)
+			}
+		}
+	})
+	t.Run("Should return error with missing credentials", func(t *testing.T) {
+		if _, err := authenticator.Authenticate(request("", "")); !errors.Is(err, ErrMissingCredentials) {
+			t.Errorf("expected: %v, result: %v", ErrMissingCredentials, err)
+		}
+	})
+	t.Run("Should return error with invalid credentials", func(t *testing.T) {
+		otherKey := newRSAKey(t)
+		for name, r := range map[string]*http.Request{
+			"unknown api key":  request(APIKeyHeader, "key-2"),
+			"wrong admin key":  request(AdminKeyHeader, "key-2"),
+			"basic scheme":     request("Authorization", "Basic YWxpY2U6c2VjcmV0"),
+			"malformed token":  request("Authorization", "Bearer not-a-token"),
+			"wrong secret":     request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte("another secret"), "", claims(nil))),
+			"unknown key":      request("Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, otherKey, "k2", claims(nil))),
+			"forged key id":    request("Authorization", "Bearer "+sign(t, jwt.SigningMethodRS256, otherKey, "k1", claims(nil))),
+			"unexpected alg":   request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS384, []byte(hmacSecret), "", claims(nil))),
+			"expired":          request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}))),
+			"no expiration":    request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", jwt.MapClaims{"sub": "alice", "iss": "https://issuer.example.com", "aud": "users-api"})),
+			"wrong issuer":     request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", claims(jwt.MapClaims{"iss": "https://evil.example.com"}))),
+			"wrong audience":   request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", claims(jwt.MapClaims{"aud": "billing-api"}))),
+			"missing subject":  request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", claims(jwt.MapClaims{"sub": ""}))),
+			"none algorithm":   request("Authorization", "Bearer "+sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil))),
+			"tampered payload": request("Authorization", "Bearer "+sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", claims(nil))+"x"),
+		} {
+			if principal, err := authenticator.Authenticate(r); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("%s: expected: %v, result: %+v, %v", name, ErrInvalidCredentials, principal, err)
+			}
+		}
+	})
+	t.Run("Read roles from a custom claim and scopes from scp", func(t *testing.T) {
+		custom, err := New(ctx, config.Auth{JWT: &config.JWT{HMACSecret: hmacSecret, RolesClaim: "groups"}}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := sign(t, jwt.SigningMethodHS256, []byte(hmacSecret), "", jwt.MapClaims{
+			"sub": "bob", "exp": time.Now().Add(time.Hour).Unix(), "groups": "support", "scp": []string{"users:read"},
+		})
+		principal, err := custom.Authenticate(request("Authorization", "bearer "+token))
+		expected := &service.Principal{Subject: "bob", Roles: []string{"support"}, Scopes: []string{"users:read"}, Method: service.AuthJWT}
+		if err != nil || !reflect.DeepEqual(principal, expected) {
+			t.Errorf("expected: %+v, result: %+v, %v", expected, principal, err)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+	authenticator, err := New(ctx, config.Auth{}, "admin-key")
+	if err != nil || authenticator.Enabled() {
+		t.Fatalf("expected a disabled authenticator, result: %v", err)
+	}
+	if principal, err := authenticator.Authenticate(request("", "")); principal != nil || err != nil {
+		t.Errorf("expected an anonymous request, result: %+v, %v", principal, err)
+	}
+	if principal, err := authenticator.Authenticate(request(AdminKeyHeader, "admin-key")); err != nil || principal.Subject != AdminSubject {
+		t.Errorf("expected the admin, result: %+v, %v", principal, err)
+	}
+	for name, cfg := range map[string]config.Auth{
+		"api key without subject": {APIKeys: []config.APIKey{{Key: "key-1"}}},
+		"duplicated api key":      {APIKeys: []config.APIKey{{Key: "key-1", Subject: "a"}, {Key: "key-1", Subject: "b"}}},
+		"jwt without keys":        {JWT: &config.JWT{Issuer: "https://issuer.example.com"}},
+		"missing jwks file":       {JWT: &config.JWT{JWKSFile: filepath.Join(t.TempDir(), "missing.json")}},
+		"api key of the admin":    {APIKeys: []config.APIKey{{Key: "admin-key", Subject: "a"}}},
+	} {
+		if _, err := New(ctx, cfg, "admin-key"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
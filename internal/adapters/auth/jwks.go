@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSRefresh = time.Hour
+	// minJWKSRefresh limits the fetches, failed ones included, triggered by tokens signed with unknown keys.
+	minJWKSRefresh = time.Minute
+	jwksTimeout    = 10 * time.Second
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet holds the RSA public keys of a JSON Web Key Set (RFC 7517), by key ID. Keys read from a URL
+// are fetched again once stale or when a token is signed with an unknown key. It is safe for concurrent use.
+type keySet struct {
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	url       string
+	client    *http.Client
+	refresh   time.Duration
+	fetchedAt time.Time
+	// attemptedAt is the time of the last fetch, successful or not.
+	attemptedAt time.Time
+	now         func() time.Time
+}
+
+func loadKeySetFile(path string) (*keySet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKS(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &keySet{keys: keys, now: time.Now}, nil
+}
+
+// fetchKeySet reads the keys at url, fetched again every refresh, defaultJWKSRefresh when zero.
+func fetchKeySet(ctx context.Context, url string, refresh time.Duration) (*keySet, error) {
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+	set := &keySet{url: url, client: &http.Client{Timeout: jwksTimeout}, refresh: refresh, now: time.Now}
+	if err := set.fetch(ctx); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func (k *keySet) fetch(ctx context.Context) error {
+	k.attemptedAt = k.now()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := k.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: %s", k.url, response.Status)
+	}
+	var content json.RawMessage
+	if err := json.NewDecoder(response.Body).Decode(&content); err != nil {
+		return fmt.Errorf("%s: %w", k.url, err)
+	}
+	keys, err := parseJWKS(content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", k.url, err)
+	}
+	k.keys, k.fetchedAt = keys, k.now()
+	return nil
+}
+
+// key returns the key with the given ID. A token without key ID can only be verified by a set of a single key.
+// The keys are fetched at most once every minJWKSRefresh, so that tokens with forged key IDs cannot flood the
+// JWKS endpoint; a stale key is used until the keys are fetched again.
+func (k *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	k.mu.RLock()
+	key, stale := k.lookup(kid), k.url != "" && k.now().Sub(k.fetchedAt) > k.refresh
+	canFetch := k.url != "" && k.now().Sub(k.attemptedAt) > minJWKSRefresh
+	k.mu.RUnlock()
+	if (key != nil && !stale) || !canFetch {
+		return k.found(key, kid)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	// another request may have fetched the keys meanwhile
+	if k.now().Sub(k.attemptedAt) > minJWKSRefresh {
+		if err := k.fetch(ctx); err != nil && key == nil {
+			return nil, err
+		}
+	}
+	return k.found(k.lookup(kid), kid)
+}
+
+func (k *keySet) lookup(kid string) *rsa.PublicKey {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key
+		}
+	}
+	return k.keys[kid]
+}
+
+func (k *keySet) found(key *rsa.PublicKey, kid string) (*rsa.PublicKey, error) {
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// parseJWKS reads the RSA signing keys of a JSON Web Key Set. Other keys are ignored.
+func parseJWKS(content []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err := errors.Join(errN, errE); err != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA key %q", jwk.Kid)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA signing key")
+	}
+	return keys, nil
+}
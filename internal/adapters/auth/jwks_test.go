@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeySet_Fetch(t *testing.T) {
+	ctx := context.Background()
+	first, second := newRSAKey(t), newRSAKey(t)
+	served := map[string]*rsa.PrivateKey{"k1": first}
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(jwks(served))
+	}))
+	t.Cleanup(server.Close)
+
+	set, err := fetchKeySet(ctx, server.URL, time.Hour)
+	if err != nil {
+		t.Fatalf("error fetching keys: %v", err)
+	}
+	now := time.Now()
+	set.now = func() time.Time { return now }
+	set.fetchedAt, set.attemptedAt = now, now
+
+	t.Run("Find keys by ID, or the single key without ID", func(t *testing.T) {
+		for _, kid := range []string{"k1", ""} {
+			if key, err := set.key(ctx, kid); err != nil || !key.Equal(&first.PublicKey) {
+				t.Errorf("%q: expected the first key, result: %v", kid, err)
+			}
+		}
+	})
+	t.Run("Fetch unknown keys at most once a minute", func(t *testing.T) {
+		served = map[string]*rsa.PrivateKey{"k1": first, "k2": second}
+		if _, err := set.key(ctx, "k2"); err == nil || fetches.Load() != 1 {
+			t.Fatalf("expected no fetch right after the previous one, result: %d fetches, %v", fetches.Load(), err)
+		}
+		now = now.Add(2 * time.Minute)
+		if key, err := set.key(ctx, "k2"); err != nil || !key.Equal(&second.PublicKey) || fetches.Load() != 2 {
+			t.Errorf("expected the rotated key, result: %d fetches, %v", fetches.Load(), err)
+		}
+		if _, err := set.key(ctx, ""); err == nil {
+			t.Errorf("expected an error without key ID in a set of 2 keys")
+		}
+	})
+	t.Run("Fetch stale keys again", func(t *testing.T) {
+		served = map[string]*rsa.PrivateKey{"k2": second}
+		now = now.Add(2 * time.Hour)
+		if _, err := set.key(ctx, "k1"); err == nil || fetches.Load() != 3 {
+			t.Errorf("expected the removed key to be unknown, result: %d fetches, %v", fetches.Load(), err)
+		}
+	})
+	t.Run("Fetch at most once a minute after a failed fetch", func(t *testing.T) {
+		failing.Store(true)
+		now = now.Add(2 * time.Minute)
+		for _, kid := range []string{"forged-1", "forged-2", "forged-3"} {
+			if _, err := set.key(ctx, kid); err == nil {
+				t.Errorf("%q: expected an error", kid)
+			}
+		}
+		if fetches.Load() != 4 {
+			t.Errorf("expected a single failed fetch, result: %d fetches", fetches.Load())
+		}
+		if key, err := set.key(ctx, "k2"); err != nil || !key.Equal(&second.PublicKey) {
+			t.Errorf("expected the known key, result: %v", err)
+		}
+	})
+}
+
+func TestParseJWKS(t *testing.T) {
+	for name, content := range map[string]string{
+		"not json":        `keys`,
+		"no rsa key":      `{"keys":[{"kty":"EC","kid":"k1","crv":"P-256"}]}`,
+		"encryption key":  `{"keys":[{"kty":"RSA","kid":"k1","use":"enc","n":"AQAB","e":"AQAB"}]}`,
+		"invalid modulus": `{"keys":[{"kty":"RSA","kid":"k1","n":"***","e":"AQAB"}]}`,
+	} {
+		if _, err := parseJWKS([]byte(content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
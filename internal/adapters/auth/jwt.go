@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRolesClaim = "roles"
+	// clockSkew is the tolerance for the time based claims, as issuers and the server clocks drift.
+	clockSkew = 30 * time.Second
+)
+
+// jwtVerifier verifies HS256 tokens with a shared secret and RS256 tokens with the keys of a JWKS.
+type jwtVerifier struct {
+	secret     []byte
+	keys       *keySet
+	parser     *jwt.Parser
+	rolesClaim string
+}
+
+func newJWTVerifier(ctx context.Context, cfg config.JWT) (*jwtVerifier, error) {
+	v := &jwtVerifier{secret: []byte(cfg.HMACSecret), rolesClaim: cfg.RolesClaim}
+	if v.rolesClaim == "" {
+		v.rolesClaim = defaultRolesClaim
+	}
+	var methods []string
+	if len(v.secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	var err error
+	switch {
+	case cfg.JWKSFile != "" && cfg.JWKSURL != "":
+		return nil, errors.New("jwksFile and jwksUrl are exclusive")
+	case cfg.JWKSFile != "":
+		v.keys, err = loadKeySetFile(cfg.JWKSFile)
+	case cfg.JWKSURL != "":
+		v.keys, err = fetchKeySet(ctx, cfg.JWKSURL, cfg.JWKSRefresh)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading JWKS: %w", err)
+	}
+	if v.keys != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("jwt requires hmacSecret, jwksFile or jwksUrl")
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithLeeway(clockSkew)}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v, nil
+}
+
+func (v *jwtVerifier) verify(ctx context.Context, token string) (*service.Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		if t.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+			return v.secret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("token has no sub claim")
+	}
+	return &service.Principal{
+		Subject: subject,
+		Roles:   stringList(claims[v.rolesClaim]),
+		Scopes:  append(stringList(claims["scope"]), stringList(claims["scp"])...),
+		Method:  service.AuthJWT,
+	}, nil
+}
+
+// stringList reads a claim holding either a list of strings or a space separated string, as the scope claim.
+func stringList(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -9,13 +10,17 @@ import (
 	"time"
 )
 
-func Connect(ctx context.Context, db DB) (*mongo.Database, error) {
+// Connect connects to the database of db. A non-nil monitor observes the commands sent by the client.
+func Connect(ctx context.Context, db DB, monitor *event.CommandMonitor) (*mongo.Database, error) {
 	slog.Info("Connecting to mongodb database")
 	opts := options.Client().ApplyURI(db.Uri).SetAuth(
 		options.Credential{
 			Username: db.User,
 			Password: db.Password,
 		})
+	if monitor != nil {
+		opts.SetMonitor(monitor)
+	}
 	conn, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		slog.Error("connecting to database", "error", err)
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"log/slog"
+	"time"
+)
+
+func ConnectPostgres(ctx context.Context, pg Postgres) (*sql.DB, error) {
+	slog.Info("Connecting to postgres database")
+	db, err := sql.Open("pgx", pg.DSN)
+	if err != nil {
+		slog.Error("connecting to database", "error", err)
+		return nil, err
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		slog.Error("pinging database", "error", err)
+		_ = db.Close()
+		return nil, err
+	}
+	slog.Info("Database Connected")
+	return db, nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	_ "modernc.org/sqlite"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sqlitePragmas make every committed write durable (WAL journal synced on commit) and make writers
+// wait for each other instead of failing with SQLITE_BUSY. Transactions take the write lock when they
+// begin, so they never fail upgrading a read lock. Readers are not blocked by the writer.
+const sqlitePragmas = "?_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+func ConnectSQLite(ctx context.Context, lite SQLite) (*sql.DB, error) {
+	slog.Info("Opening sqlite database", "path", lite.Path)
+	if err := os.MkdirAll(filepath.Dir(lite.Path), 0o755); err != nil {
+		slog.Error("creating database directory", "error", err)
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+lite.Path+sqlitePragmas)
+	if err != nil {
+		slog.Error("opening database", "error", err)
+		return nil, err
+	}
+	// the pool keeps several connections, writers queuing on the write lock: a streamed export holds its
+	// connection while the client downloads it, and must not block the other requests
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		slog.Error("pinging database", "error", err)
+		_ = db.Close()
+		return nil, err
+	}
+	slog.Info("Database Opened")
+	return db, nil
+}
@@ -0,0 +1,22 @@
+package dto
+
+const (
+	HealthOK       = "ok"
+	HealthReady    = "ready"
+	HealthNotReady = "not_ready"
+	HealthDraining = "draining"
+	HealthUp       = "up"
+	HealthDown     = "down"
+)
+
+// HealthResponse reports the status of the application and, on readiness probes, of each of its dependencies.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the status of a dependency. The cause of a failure is logged only: it may name hosts or users.
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+}
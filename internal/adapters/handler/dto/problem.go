@@ -0,0 +1,22 @@
+package dto
+
+// Problem is the RFC 7807 application/problem+json body of every error response.
+// Code is a stable machine-readable identifier of the problem, also found at the end of Type.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code"`
+	Errors   []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError is one of the causes of a problem, such as an invalid field. Row is set for the causes
+// located in a row of an import batch.
+type ProblemError struct {
+	Row     int    `json:"row,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
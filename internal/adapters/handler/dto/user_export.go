@@ -0,0 +1,11 @@
+package dto
+
+type UserExportQuery struct {
+	FirstName string `form:"firstName"`
+	LastName  string `form:"lastName"`
+	Email     string `form:"email"`
+	MinAge    int    `form:"minAge"`
+	MaxAge    int    `form:"maxAge"`
+	Format    string `form:"format"`
+	Fields    string `form:"fields"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+
+type HistoryQuery struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+type HistoryMeta struct {
+	NextCursor string `json:"nextCursor,omitempty"`
+	Limit      int    `json:"limit"`
+}
+
+type HistoryResponse struct {
+	Data []model.AuditEntry `json:"data"`
+	Meta HistoryMeta        `json:"meta"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+
+// Statuses of an import row.
+const (
+	ImportCreated = "created"
+	ImportFailed  = "failed"
+)
+
+type ImportRowResult struct {
+	Row    int            `json:"row"`
+	Status string         `json:"status"`
+	User   *model.User    `json:"user,omitempty"`
+	Code   string         `json:"code,omitempty"`
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	Atomic  bool              `json:"atomic"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Results []ImportRowResult `json:"results"`
+}
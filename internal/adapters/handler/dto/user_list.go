@@ -0,0 +1,25 @@
+package dto
+
+import "github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+
+type UserListQuery struct {
+	FirstName string `form:"firstName"`
+	LastName  string `form:"lastName"`
+	Email     string `form:"email"`
+	MinAge    int    `form:"minAge"`
+	MaxAge    int    `form:"maxAge"`
+	Sort      string `form:"sort"`
+	Cursor    string `form:"cursor"`
+	Limit     int    `form:"limit"`
+}
+
+type PageMeta struct {
+	NextCursor string `json:"nextCursor,omitempty"`
+	TotalCount int64  `json:"totalCount"`
+	Limit      int    `json:"limit"`
+}
+
+type UserListResponse struct {
+	Data []model.User `json:"data"`
+	Meta PageMeta     `json:"meta"`
+}
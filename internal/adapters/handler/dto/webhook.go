@@ -0,0 +1,23 @@
+package dto
+
+import "github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+
+type WebhookInput struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs the deliveries. It is never returned.
+	Secret string `json:"secret"`
+}
+
+type WebhookListResponse struct {
+	Data []model.Webhook `json:"data"`
+}
+
+type DeliveryQuery struct {
+	Status string `form:"status"`
+	Limit  int    `form:"limit"`
+}
+
+type DeliveryListResponse struct {
+	Data []model.Delivery `json:"data"`
+}
@@ -0,0 +1,43 @@
+package httpserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// accessLog logs a record per request once it is served, with the route template matched by the request,
+// so that the requests to a route can be aggregated whatever the IDs in their path.
+// Server errors are logged at error level, client errors at warn level, and successful health probes at debug level.
+func accessLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case isProbe(ctx.Request.URL.Path):
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", ctx.FullPath()),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(ctx.Writer.Size(), 0)),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+		if principal, ok := service.PrincipalFrom(ctx.Request.Context()); ok {
+			attrs = append(attrs, slog.String("subject", principal.Subject))
+		}
+		slog.LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
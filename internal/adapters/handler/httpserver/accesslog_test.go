@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/logging"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	logger, _ := logging.New(config.Log{}, &out)
+	defaultLogger := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	mockUserService := &UserMockService{}
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)}, WithAuthenticator(adminAuthenticator(t)))
+	serve := func(request *http.Request) (*httptest.ResponseRecorder, map[string]any) {
+		out.Reset()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var record map[string]any
+		if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+			t.Fatalf("expected a single JSON record, result: %q", out.String())
+		}
+		return recorder, record
+	}
+
+	t.Run("Log a record per request with its route", func(t *testing.T) {
+		mockUserService.On("FindById", mock.Anything, "42").Return(&model.User{ID: "42"}, nil).Once()
+		request := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		request.Header.Set(requestIDHeader, "req-1")
+		request.Header.Set(auth.AdminKeyHeader, "secret")
+		recorder, record := serve(request)
+
+		assert.Equal(t, "request", record["msg"])
+		assert.Equal(t, "INFO", record["level"])
+		assert.Equal(t, "req-1", record[logging.RequestIDKey])
+		assert.Equal(t, http.MethodGet, record["method"])
+		assert.Equal(t, "/users/:id", record["route"])
+		assert.Equal(t, "/users/42", record["path"])
+		assert.EqualValues(t, http.StatusOK, record["status"])
+		assert.EqualValues(t, recorder.Body.Len(), record["bytes"])
+		assert.Equal(t, auth.AdminSubject, record["subject"])
+		assert.Contains(t, record, "latency_ms")
+	})
+	t.Run("Log the requests rejected by the middlewares", func(t *testing.T) {
+		_, record := serve(httptest.NewRequest(http.MethodGet, "/accounts", nil))
+
+		assert.Equal(t, "WARN", record["level"])
+		assert.EqualValues(t, http.StatusNotFound, record["status"])
+		assert.Equal(t, "", record["route"])
+		assert.Len(t, record[logging.RequestIDKey], 32)
+	})
+	t.Run("Replace invalid request IDs", func(t *testing.T) {
+		mockUserService.On("FindById", mock.Anything, "42").Return(&model.User{ID: "42"}, nil).Once()
+		request := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+		request.Header.Set(requestIDHeader, strings.Repeat("a", maxRequestIDLength+1))
+		recorder, record := serve(request)
+
+		assert.Len(t, recorder.Header().Get(requestIDHeader), 32)
+		assert.Equal(t, recorder.Header().Get(requestIDHeader), record[logging.RequestIDKey])
+	})
+	mockUserService.AssertExpectations(t)
+}
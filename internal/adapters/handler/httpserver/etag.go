@@ -0,0 +1,83 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func etag(u *model.User) string {
+	return `"` + strconv.FormatInt(u.Version, 10) + `"`
+}
+
+func setETag(ctx *gin.Context, u *model.User) {
+	ctx.Header("ETag", etag(u))
+}
+
+// ifMatchVersion resolves the If-Match header of ctx into the version the user with id must have, zero when any
+// version is accepted. When the header lists several tags, the user must have one of them: its current version,
+// read first, is required then. Versions start at 1: the storage migrations backfill the users created before
+// versioning, so no ETag is "0".
+func (h *UserHandler) ifMatchVersion(ctx *gin.Context, id string) (int64, error) {
+	versions, ok := ifMatchVersions(ctx.GetHeader("If-Match"))
+	switch {
+	case !ok:
+		return 0, service.ErrVersionConflict
+	case len(versions) == 0:
+		return 0, nil
+	case len(versions) == 1:
+		return versions[0], nil
+	}
+	current, err := h.service.FindById(ctx, id)
+	if err != nil {
+		return 0, ifMatchErr(ctx, err)
+	}
+	if !slices.Contains(versions, current.Version) {
+		return 0, service.ErrVersionConflict
+	}
+	return current.Version, nil
+}
+
+// ifMatchVersions returns the versions of the tags listed by an If-Match header, none when any version is accepted.
+// ok is false when no tag can ever match: weak and malformed tags never match.
+func ifMatchVersions(header string) (versions []int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 3 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+	return versions, len(versions) > 0
+}
+
+// ifMatchErr turns the ErrUserNotFound of a request with an If-Match header into ErrVersionConflict: no tag, not even
+// "*", matches a user that does not exist.
+func ifMatchErr(ctx *gin.Context, err error) error {
+	if errors.Is(err, service.ErrUserNotFound) && ctx.GetHeader("If-Match") != "" {
+		return service.ErrVersionConflict
+	}
+	return err
+}
+
+// noneMatch reports whether an If-None-Match header matches the user, using weak comparison.
+func noneMatch(header string, u *model.User) bool {
+	current := etag(u)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package httpserver
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// HealthCheck checks that a dependency of the application, such as its database, is available.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Health reports the liveness of the application, and its readiness to serve requests: whether its dependencies
+// are available, and it is not draining before shutting down.
+type Health struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// NewHealth returns the Health running checks, within timeout, on every readiness probe.
+func NewHealth(timeout time.Duration, checks ...HealthCheck) *Health {
+	return &Health{checks: checks, timeout: timeout}
+}
+
+// Drain fails the readiness probes from now on, so that load balancers stop sending requests to the server
+// before it shuts down.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// WithHealth serves the liveness probe on /healthz and the readiness probe of h on /readyz.
+func WithHealth(h *Health) ServerOption {
+	return func(o *serverOptions) {
+		o.health = h
+	}
+}
+
+func isProbe(path string) bool {
+	return path == healthzPath || path == readyzPath
+}
+
+// Liveness godoc
+// @Summary      Liveness probe
+// @Description  Answers as long as the server is serving requests.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  dto.HealthResponse
+// @Router       /healthz [get]
+func (h *Health) Liveness(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.HealthResponse{Status: dto.HealthOK})
+}
+
+// Readiness godoc
+// @Summary      Readiness probe
+// @Description  Checks the dependencies of the application, failing when one is unavailable or when the server is draining before shutting down.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  dto.HealthResponse
+// @Failure      503  {object}  dto.HealthResponse
+// @Router       /readyz [get]
+func (h *Health) Readiness(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	if h.draining.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, dto.HealthResponse{Status: dto.HealthDraining})
+		return
+	}
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+	response := dto.HealthResponse{Status: dto.HealthReady, Checks: make(map[string]dto.HealthCheck, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Check(checkCtx)
+			result := dto.HealthCheck{Status: dto.HealthUp, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				slog.WarnContext(ctx.Request.Context(), "dependency is unavailable", "dependency", check.Name, "error", err)
+				result.Status = dto.HealthDown
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[check.Name] = result
+			if err != nil {
+				response.Status = dto.HealthNotReady
+			}
+		}(check)
+	}
+	wg.Wait()
+	status := http.StatusOK
+	if response.Status != dto.HealthReady {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, response)
+}
@@ -0,0 +1,82 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	var dbErr error
+	health := NewHealth(50*time.Millisecond,
+		HealthCheck{Name: "mongo", Check: func(ctx context.Context) error { return dbErr }},
+		HealthCheck{Name: "slow", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	limiter, _ := ratelimit.New(config.RateLimit{Default: &config.RateLimitRule{Requests: 1, Per: time.Hour}}, ratelimit.NewMemoryStore())
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(&UserMockService{})},
+		WithAuthenticator(tokenAuthenticator{token: "t1"}), WithRateLimiter(limiter), WithHealth(health))
+	probe := func(path string) (int, dto.HealthResponse) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		var response dto.HealthResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response
+	}
+
+	t.Run("Live without credentials nor rate limit", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			status, response := probe(healthzPath)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, dto.HealthOK, response.Status)
+		}
+	})
+	t.Run("Not ready when a dependency does not answer in time", func(t *testing.T) {
+		start := time.Now()
+		status, response := probe(readyzPath)
+
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, dto.HealthNotReady, response.Status)
+		assert.Equal(t, dto.HealthUp, response.Checks["mongo"].Status)
+		assert.Equal(t, dto.HealthDown, response.Checks["slow"].Status)
+	})
+	t.Run("Ready when every dependency is available", func(t *testing.T) {
+		ready := newRouter(&config.HTTP{GinMode: gin.TestMode}, nil, WithHealth(NewHealth(time.Second,
+			HealthCheck{Name: "mongo", Check: func(ctx context.Context) error { return nil }})))
+		recorder := httptest.NewRecorder()
+		ready.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"status":"ready"`)
+		assert.Contains(t, recorder.Body.String(), `"mongo":{"status":"up"`)
+	})
+	t.Run("Report the failing dependency without its error", func(t *testing.T) {
+		dbErr = errors.New("dial tcp mongo.internal:27017: connection refused")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+
+		assert.Contains(t, recorder.Body.String(), `"mongo":{"status":"down"`)
+		assert.NotContains(t, recorder.Body.String(), "mongo.internal")
+	})
+	t.Run("Not ready while draining", func(t *testing.T) {
+		health.Drain()
+		status, response := probe(readyzPath)
+
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, dto.HealthDraining, response.Status)
+		status, _ = probe(healthzPath)
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
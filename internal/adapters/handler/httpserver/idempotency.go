@@ -0,0 +1,179 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// replayedHeader flags the responses replayed from the idempotency store.
+	replayedHeader          = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+// replayedHeaders are the response headers stored with the response of idempotent requests.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+type idempotency struct {
+	store service.IdempotencyStore
+	ttl   time.Duration
+	lease time.Duration
+}
+
+// WithIdempotency stores in store the responses of the mutating requests sent with an Idempotency-Key header
+// for ttl, replaying them to the retries of the requests. A request holds its key for lease while it is being
+// processed, so that the key of a request that never completes can be used again.
+func WithIdempotency(store service.IdempotencyStore, ttl time.Duration, lease time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.idempotency = &idempotency{store: store, ttl: ttl, lease: lease}
+	}
+}
+
+// idempotent makes the route replay the response of a request to its retries with the same Idempotency-Key.
+// Keys belong to the caller, they are rejected when reused with another request, and while the first request
+// is being processed. Responses with a 5xx status are not stored: the request can be retried.
+// Without idempotency store, the header is ignored.
+func (r *Router) idempotent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeader)
+		if r.idempotency == nil || key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithProblem(ctx, http.StatusBadRequest, CodeInvalidParameters, fmt.Sprintf("%s is limited to %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortWithProblem(ctx, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, fmt.Sprintf("uploads are limited to %d bytes", tooLarge.Limit))
+			return
+		}
+		if err != nil {
+			abortWithProblem(ctx, http.StatusBadRequest, CodeMalformedRequest, "invalid request body", dto.ProblemError{Message: err.Error()})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		record := model.IdempotencyRecord{
+			Key:         idempotencyKey(ctx.Request.Context(), key),
+			Fingerprint: fingerprint(ctx.Request, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(r.idempotency.lease),
+		}
+		existing, err := r.idempotency.store.Reserve(ctx, record)
+		switch {
+		case err != nil:
+			checkErr(ctx, err)
+		case existing == nil:
+			r.idempotency.serve(ctx, record)
+		case existing.Fingerprint != record.Fingerprint:
+			abortWithProblem(ctx, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, idempotencyKeyHeader+" was already used with another request")
+		case !existing.Completed():
+			ctx.Header("Retry-After", "1")
+			abortWithProblem(ctx, http.StatusConflict, CodeIdempotencyInProgress, "the request with this "+idempotencyKeyHeader+" is still being processed")
+		default:
+			replay(ctx, existing)
+		}
+	}
+}
+
+// idempotencyKey scopes key to the caller of ctx, identified by how it was authenticated and its subject. The fields
+// are length-prefixed, then hashed, so that no subject nor key can collide with the scope of another caller.
+func idempotencyKey(ctx context.Context, key string) string {
+	var method, subject string
+	if principal, ok := service.PrincipalFrom(ctx); ok {
+		method, subject = principal.Method, principal.Subject
+	}
+	hash := sha256.New()
+	for _, field := range []string{method, subject, key} {
+		_, _ = fmt.Fprintf(hash, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// serve runs the handlers of the reserved request, then stores their response, or releases the key on 5xx responses.
+func (i *idempotency) serve(ctx *gin.Context, record model.IdempotencyRecord) {
+	// the response is sent: the client may be gone
+	storeCtx := context.WithoutCancel(ctx.Request.Context())
+	release := func() {
+		if err := i.store.Release(storeCtx, record.Key); err != nil {
+			slog.ErrorContext(ctx, "failed to release idempotency key", "error", err)
+		}
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			release()
+			panic(err)
+		}
+	}()
+	recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+	ctx.Writer = recorder
+	ctx.Next()
+
+	if ctx.Writer.Status() >= http.StatusInternalServerError {
+		release()
+		return
+	}
+	record.StatusCode, record.Header, record.Body = ctx.Writer.Status(), http.Header{}, recorder.body.Bytes()
+	record.ExpiresAt = record.CreatedAt.Add(i.ttl)
+	for _, name := range replayedHeaders {
+		if value := ctx.Writer.Header().Get(name); value != "" {
+			record.Header.Set(name, value)
+		}
+	}
+	if err := i.store.Complete(storeCtx, record); err != nil {
+		slog.ErrorContext(ctx, "failed to store idempotent response", "error", err)
+	}
+}
+
+func replay(ctx *gin.Context, record *model.IdempotencyRecord) {
+	for name, values := range record.Header {
+		for _, value := range values {
+			ctx.Writer.Header().Add(name, value)
+		}
+	}
+	ctx.Header(replayedHeader, "true")
+	ctx.Status(record.StatusCode)
+	_, _ = ctx.Writer.Write(record.Body)
+	ctx.Abort()
+}
+
+// fingerprint identifies a request by its method, URL and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.RequestURI())
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder copies the response body written by the handlers.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
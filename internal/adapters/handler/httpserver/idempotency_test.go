@@ -0,0 +1,130 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/repository"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotent(t *testing.T) {
+	mockUserService := &UserMockService{}
+	store := repository.NewIdempotencyMemoryRepo()
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)},
+		WithAuthenticator(adminAuthenticator(t)), WithIdempotency(store, time.Hour, time.Minute))
+	body := `{"firstName":"John","lastName":"Doe","email":"john@doe.com","age":30}`
+	input := model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30}
+	created := &model.User{ID: "1", FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30, Version: 1}
+	newRequest := func(key string, body string) *http.Request {
+		request := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		request.Header.Set(idempotencyKeyHeader, key)
+		return request
+	}
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("Replay the response to retries", func(t *testing.T) {
+		mockUserService.On("Save", mock.Anything, input).Return(created, nil).Once()
+		first := serve(newRequest("k1", body))
+		retry := serve(newRequest("k1", body))
+
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+		assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+		assert.Empty(t, first.Header().Get(replayedHeader))
+		assert.Equal(t, "true", retry.Header().Get(replayedHeader))
+	})
+	t.Run("Keys belong to their caller", func(t *testing.T) {
+		mockUserService.On("Save", mock.Anything, input).Return(nil, errors.New("connection refused")).Once()
+		request := newRequest("k1", body)
+		request.Header.Set(auth.AdminKeyHeader, "secret")
+
+		assert.Equal(t, http.StatusInternalServerError, serve(request).Code)
+	})
+	t.Run("Keys belong to the authentication method of their caller", func(t *testing.T) {
+		authenticator, _ := auth.New(context.Background(), config.Auth{APIKeys: []config.APIKey{{Key: "impostor", Subject: auth.AdminSubject}}}, "secret")
+		authenticated := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)},
+			WithAuthenticator(authenticator), WithIdempotency(store, time.Hour, time.Minute))
+		mockUserService.On("Save", mock.Anything, input).Return(created, nil).Twice()
+		admin, impostor := newRequest("k6", body), newRequest("k6", body)
+		admin.Header.Set(auth.AdminKeyHeader, "secret")
+		impostor.Header.Set(auth.APIKeyHeader, "impostor")
+		responses := make([]*httptest.ResponseRecorder, 2)
+		for i, request := range []*http.Request{admin, impostor} {
+			responses[i] = httptest.NewRecorder()
+			authenticated.ServeHTTP(responses[i], request)
+		}
+
+		assert.Equal(t, http.StatusCreated, responses[0].Code)
+		assert.Equal(t, http.StatusCreated, responses[1].Code)
+		assert.Empty(t, responses[1].Header().Get(replayedHeader))
+	})
+	t.Run("Reject keys reused with another request", func(t *testing.T) {
+		recorder := serve(newRequest("k1", `{"firstName":"Jane"}`))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), CodeIdempotencyKeyReused)
+	})
+	t.Run("Reject retries while the request is processed", func(t *testing.T) {
+		request := newRequest("k2", body)
+		now := time.Now().UTC()
+		_, _ = store.Reserve(context.Background(), model.IdempotencyRecord{
+			Key: idempotencyKey(context.Background(), "k2"), Fingerprint: fingerprint(request, []byte(body)), CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+		})
+		recorder := serve(request)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+		assert.Contains(t, recorder.Body.String(), CodeIdempotencyInProgress)
+	})
+	t.Run("Hold keys for the lease while the request is processed, then for the TTL", func(t *testing.T) {
+		var reserved *model.IdempotencyRecord
+		mockUserService.On("Save", mock.Anything, input).Run(func(mock.Arguments) {
+			reserved, _ = store.Reserve(context.Background(), model.IdempotencyRecord{Key: idempotencyKey(context.Background(), "k5"), CreatedAt: time.Now().UTC()})
+		}).Return(created, nil).Once()
+		serve(newRequest("k5", body))
+		completed, _ := store.Reserve(context.Background(), model.IdempotencyRecord{Key: idempotencyKey(context.Background(), "k5"), CreatedAt: time.Now().UTC()})
+
+		if assert.NotNil(t, reserved) && assert.NotNil(t, completed) {
+			assert.Equal(t, time.Minute, reserved.ExpiresAt.Sub(reserved.CreatedAt))
+			assert.Equal(t, time.Hour, completed.ExpiresAt.Sub(completed.CreatedAt))
+		}
+	})
+	t.Run("Failed requests can be retried", func(t *testing.T) {
+		mockUserService.On("Save", mock.Anything, input).Return(nil, errors.New("connection refused")).Once()
+		mockUserService.On("Save", mock.Anything, input).Return(created, nil).Once()
+
+		assert.Equal(t, http.StatusInternalServerError, serve(newRequest("k3", body)).Code)
+		assert.Equal(t, http.StatusCreated, serve(newRequest("k3", body)).Code)
+	})
+	t.Run("Client errors are replayed", func(t *testing.T) {
+		first := serve(newRequest("k4", `{"firstName":`))
+		retry := serve(newRequest("k4", `{"firstName":`))
+
+		assert.Equal(t, http.StatusBadRequest, first.Code)
+		assert.Equal(t, http.StatusBadRequest, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get(replayedHeader))
+	})
+	t.Run("Should return error with a too long key", func(t *testing.T) {
+		recorder := serve(newRequest(strings.Repeat("k", maxIdempotencyKeyLength+1), body))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), CodeInvalidParameters)
+	})
+	mockUserService.AssertExpectations(t)
+}
@@ -0,0 +1,31 @@
+package httpserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const metricsPath = "/metrics"
+
+// Metrics observes the served requests and exposes the collected metrics.
+type Metrics interface {
+	ObserveRequest(method string, route string, status int, elapsed time.Duration)
+	Handler() http.Handler
+}
+
+// WithMetrics observes every request in m, and serves the metrics on /metrics.
+func WithMetrics(m Metrics) ServerOption {
+	return func(o *serverOptions) {
+		o.metrics = m
+	}
+}
+
+// observe observes the requests by route template, which keeps the cardinality of the metrics bounded.
+func observe(m Metrics) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		m.ObserveRequest(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,28 @@
+package httpserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(&UserMockService{})},
+		WithAuthenticator(tokenAuthenticator{token: "t1"}), WithMetrics(metrics.New()))
+	serve := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("/users/42").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("/users/43").Code)
+	recorder := serve(metricsPath)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `http_requests_total{method="GET",route="/users/:id",status="401"} 2`)
+}
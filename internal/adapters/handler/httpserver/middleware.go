@@ -0,0 +1,106 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+	"strings"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds the request IDs accepted from clients, which are copied in every log record.
+	maxRequestIDLength = 128
+	// publicPathPrefix is the path of the API documentation, served without authentication.
+	publicPathPrefix = "/swagger/"
+)
+
+// publicPaths are the operational endpoints, served without authentication to scrapers and probes.
+var publicPaths = map[string]bool{metricsPath: true, healthzPath: true, readyzPath: true}
+
+func isPublic(path string) bool {
+	return publicPaths[path] || strings.HasPrefix(path, publicPathPrefix)
+}
+
+// requestID puts the request ID in the request context, where the audit log, the events and the logs read it.
+// The request ID comes from the X-Request-ID header, or is generated, and is echoed in the response.
+func requestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		ctx.Header(requestIDHeader, requestID)
+		ctx.Request = ctx.Request.WithContext(service.ContextWithRequestID(ctx.Request.Context(), requestID))
+		ctx.Next()
+	}
+}
+
+// Authenticator identifies the caller of a request, including the admin. It returns a nil principal for the
+// anonymous requests it accepts.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*service.Principal, error)
+}
+
+// authenticate puts the caller in the request context, rejecting the requests a does not authenticate.
+// Requests to public paths, and every request without authenticator, are anonymous.
+func authenticate(a Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if a == nil || isPublic(ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+		principal, err := a.Authenticate(ctx.Request)
+		if err != nil {
+			ctx.Header("WWW-Authenticate", "Bearer")
+			abortWithProblem(ctx, http.StatusUnauthorized, CodeUnauthenticated, err.Error())
+			return
+		}
+		if principal != nil {
+			ctx.Request = ctx.Request.WithContext(service.ContextWithPrincipal(ctx.Request.Context(), *principal))
+		}
+		ctx.Next()
+	}
+}
+
+// Authorizer checks the permissions of the caller of a request.
+type Authorizer interface {
+	// Require fails when the caller of ctx has no access to permission, not even to its own user.
+	Require(ctx context.Context, permission string) error
+}
+
+// require rejects the requests whose caller lacks permission. Without authorizer, every request is allowed.
+// Services check the permission again against the users requests access, callers may only have it on their own.
+func (r *Router) require(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if r.authorizer != nil {
+			if err := r.authorizer.Require(ctx.Request.Context(), permission); err != nil {
+				checkErr(ctx, err)
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// validRequestID accepts the IDs of at most maxRequestIDLength printable ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
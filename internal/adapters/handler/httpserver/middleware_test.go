@@ -0,0 +1,129 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tokenAuthenticator authenticates the requests bearing its token as its principal.
+type tokenAuthenticator struct {
+	token     string
+	principal service.Principal
+}
+
+func (a tokenAuthenticator) Authenticate(r *http.Request) (*service.Principal, error) {
+	switch r.Header.Get("Authorization") {
+	case "":
+		return nil, errors.New("missing credentials")
+	case "Bearer " + a.token:
+		return &a.principal, nil
+	}
+	return nil, errors.New("invalid credentials")
+}
+
+// adminAuthenticator authenticates the requests carrying the "secret" admin key, the other requests are anonymous.
+func adminAuthenticator(t *testing.T) *auth.Authenticator {
+	t.Helper()
+	authenticator, err := auth.New(context.Background(), config.Auth{}, "secret")
+	if err != nil {
+		t.Fatalf("error building authenticator: %v", err)
+	}
+	return authenticator
+}
+
+func TestAuthenticate(t *testing.T) {
+	mockUserService := &UserMockService{}
+	id := primitive.NewObjectID().Hex()
+	alice := config.APIKey{Key: "t1", Subject: "alice", Roles: []string{"support"}}
+	authenticator, _ := auth.New(context.Background(), config.Auth{APIKeys: []config.APIKey{alice}}, "secret")
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)}, WithAuthenticator(authenticator))
+	serve := func(header string, value string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodDelete, "/users/"+id, nil)
+		if header != "" {
+			request.Header.Set(header, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("Put the authenticated caller in the request context", func(t *testing.T) {
+		authenticated := mock.MatchedBy(func(ctx context.Context) bool {
+			principal, ok := service.PrincipalFrom(ctx)
+			return ok && principal.Subject == alice.Subject && service.ActorFrom(ctx) == alice.Subject
+		})
+		mockUserService.On("Delete", authenticated, id, false).Return(nil).Once()
+
+		assert.Equal(t, http.StatusNoContent, serve(auth.APIKeyHeader, "t1").Code)
+	})
+	t.Run("The admin key authenticates the admin", func(t *testing.T) {
+		admin := mock.MatchedBy(func(ctx context.Context) bool {
+			principal, ok := service.PrincipalFrom(ctx)
+			return ok && principal.Method == service.AuthAdminKey && principal.Subject == auth.AdminSubject
+		})
+		mockUserService.On("Delete", admin, id, false).Return(nil).Once()
+
+		assert.Equal(t, http.StatusNoContent, serve(auth.AdminKeyHeader, "secret").Code)
+	})
+	t.Run("Reject unauthenticated requests", func(t *testing.T) {
+		for _, recorder := range []*httptest.ResponseRecorder{serve("", ""), serve("Authorization", "Bearer t2"), serve(auth.AdminKeyHeader, "wrong")} {
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+			assert.Equal(t, "Bearer", recorder.Header().Get("WWW-Authenticate"))
+			assert.Contains(t, recorder.Body.String(), CodeUnauthenticated)
+		}
+	})
+	t.Run("The API documentation is public", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/swagger/index.html", nil))
+
+		assert.NotEqual(t, http.StatusUnauthorized, recorder.Code)
+	})
+	mockUserService.AssertExpectations(t)
+}
+
+func TestRequire(t *testing.T) {
+	mockUserService := &UserMockService{}
+	id := primitive.NewObjectID().Hex()
+	policy, _ := service.NewPolicy(map[string][]string{"reader": {service.PermUsersRead}})
+	reader := config.APIKey{Key: "t1", Subject: "reporting", Roles: []string{"reader"}}
+	authenticator, _ := auth.New(context.Background(), config.Auth{APIKeys: []config.APIKey{reader}}, "secret")
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)},
+		WithAuthenticator(authenticator), WithAuthorizer(policy))
+	serve := func(method string, header string, value string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/users/"+id, nil)
+		request.Header.Set(header, value)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("Allow callers with the permission of the route", func(t *testing.T) {
+		mockUserService.On("FindById", mock.Anything, id).Return(&model.User{ID: id}, nil).Once()
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, auth.APIKeyHeader, "t1").Code)
+	})
+	t.Run("Reject callers lacking the permission of the route", func(t *testing.T) {
+		recorder := serve(http.MethodDelete, auth.APIKeyHeader, "t1")
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), CodeForbidden)
+		assert.Contains(t, recorder.Body.String(), "reporting lacks the users:delete permission")
+	})
+	t.Run("The admin has every permission", func(t *testing.T) {
+		mockUserService.On("Delete", mock.Anything, id, false).Return(nil).Once()
+
+		assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, auth.AdminKeyHeader, "secret").Code)
+	})
+	mockUserService.AssertExpectations(t)
+}
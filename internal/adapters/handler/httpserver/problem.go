@@ -0,0 +1,118 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:tag-onboarding:problem:"
+)
+
+// Problem codes returned in the code member of every error response. They are part of the API contract.
+const (
+	CodeValidationFailed      = "validation-failed"
+	CodeInvalidParameters     = "invalid-parameters"
+	CodeMalformedRequest      = "malformed-request"
+	CodeUnsupportedMediaType  = "unsupported-media-type"
+	CodeNotAcceptable         = "not-acceptable"
+	CodePayloadTooLarge       = "payload-too-large"
+	CodeUserNotFound          = "user-not-found"
+	CodeWebhookNotFound       = "webhook-not-found"
+	CodeDeliveryNotFound      = "delivery-not-found"
+	CodeUsernameTaken         = "username-taken"
+	CodeVersionConflict       = "version-conflict"
+	CodeIdempotencyKeyReused  = "idempotency-key-reused"
+	CodeIdempotencyInProgress = "idempotency-key-in-progress"
+	CodeDuplicateInBatch      = "duplicate-in-batch"
+	CodeImportAborted         = "import-aborted"
+	CodeBatchRejected         = "batch-rejected"
+	CodeTransactions          = "transactions-unsupported"
+	CodeAuditDisabled         = "audit-disabled"
+	CodeUnauthenticated       = "unauthenticated"
+	CodeForbidden             = "forbidden"
+	CodeRateLimited           = "rate-limited"
+	CodeNotFound              = "not-found"
+	CodeMethodNotAllowed      = "method-not-allowed"
+	CodeInternalError         = "internal-error"
+)
+
+func newProblem(status int, code string, detail string, errs ...dto.ProblemError) dto.Problem {
+	return dto.Problem{
+		Type:   problemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: errs,
+	}
+}
+
+// abortWithProblem aborts the request with an application/problem+json response.
+func abortWithProblem(ctx *gin.Context, status int, code string, detail string, errs ...dto.ProblemError) {
+	writeProblem(ctx, newProblem(status, code, detail, errs...))
+}
+
+func writeProblem(ctx *gin.Context, problem dto.Problem) {
+	if ctx.Request != nil && ctx.Request.URL != nil {
+		problem.Instance = ctx.Request.URL.Path
+	}
+	ctx.Header("Content-Type", problemContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// problemFor describes a service error. Unknown errors are internal errors, their message is not disclosed.
+func problemFor(err error) dto.Problem {
+	var validationErr service.ValidationError
+	var fieldErrs model.ValidationErrors
+	var forbiddenErr service.ForbiddenError
+	switch {
+	case errors.As(err, &fieldErrs):
+		return newProblem(http.StatusUnprocessableEntity, CodeValidationFailed, "user did not pass validation", fieldProblems(fieldErrs)...)
+	case errors.As(err, &validationErr):
+		return newProblem(http.StatusBadRequest, CodeInvalidParameters, validationErr.Message, detailProblems(validationErr.Details)...)
+	case errors.As(err, &forbiddenErr):
+		return newProblem(http.StatusForbidden, CodeForbidden, forbiddenErr.Error())
+	case errors.Is(err, service.ErrUserNotFound):
+		return newProblem(http.StatusNotFound, CodeUserNotFound, err.Error())
+	case errors.Is(err, service.ErrWebhookNotFound):
+		return newProblem(http.StatusNotFound, CodeWebhookNotFound, err.Error())
+	case errors.Is(err, service.ErrDeliveryNotFound):
+		return newProblem(http.StatusNotFound, CodeDeliveryNotFound, err.Error())
+	case errors.Is(err, service.ErrUsernameTaken):
+		return newProblem(http.StatusBadRequest, CodeUsernameTaken, err.Error())
+	case errors.Is(err, service.ErrVersionConflict):
+		return newProblem(http.StatusPreconditionFailed, CodeVersionConflict, err.Error())
+	case errors.Is(err, service.ErrDuplicateInBatch):
+		return newProblem(http.StatusBadRequest, CodeDuplicateInBatch, err.Error())
+	case errors.Is(err, service.ErrImportAborted):
+		return newProblem(http.StatusConflict, CodeImportAborted, err.Error())
+	case errors.Is(err, service.ErrTransactionsUnsupported):
+		return newProblem(http.StatusNotImplemented, CodeTransactions, err.Error())
+	case errors.Is(err, service.ErrAuditDisabled):
+		return newProblem(http.StatusNotImplemented, CodeAuditDisabled, err.Error())
+	default:
+		return newProblem(http.StatusInternalServerError, CodeInternalError, "")
+	}
+}
+
+func fieldProblems(fieldErrs model.ValidationErrors) []dto.ProblemError {
+	errs := make([]dto.ProblemError, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		errs[i] = dto.ProblemError{Field: fieldErr.Field, Code: fieldErr.Code, Message: fieldErr.Message}
+	}
+	return errs
+}
+
+func detailProblems(details []string) []dto.ProblemError {
+	var errs []dto.ProblemError
+	for _, detail := range details {
+		errs = append(errs, dto.ProblemError{Message: detail})
+	}
+	return errs
+}
@@ -0,0 +1,73 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckErr(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{model.ValidationErrors{{Field: model.FieldAge, Code: model.RuleMinAge, Message: "too young"}}, http.StatusUnprocessableEntity, CodeValidationFailed},
+		{service.ValidationError{Message: "invalid list parameters"}, http.StatusBadRequest, CodeInvalidParameters},
+		{service.ErrUserNotFound, http.StatusNotFound, CodeUserNotFound},
+		{service.ErrUsernameTaken, http.StatusBadRequest, CodeUsernameTaken},
+		{service.ErrVersionConflict, http.StatusPreconditionFailed, CodeVersionConflict},
+		{service.ErrAuditDisabled, http.StatusNotImplemented, CodeAuditDisabled},
+		{service.ForbiddenError{Subject: "alice", Permission: service.PermUsersWrite}, http.StatusForbidden, CodeForbidden},
+		{errors.New("connection refused"), http.StatusInternalServerError, CodeInternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/users/42", nil)
+
+			checkErr(ctx, tt.err)
+			var problem dto.Problem
+			_ = json.Unmarshal(recorder.Body.Bytes(), &problem)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, problemContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, problemTypePrefix+tt.code, problem.Type)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, http.StatusText(tt.status), problem.Title)
+			assert.Equal(t, "/users/42", problem.Instance)
+		})
+	}
+}
+
+func TestRouter_Problems(t *testing.T) {
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(&UserMockService{})})
+
+	t.Run("Unknown route", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/accounts", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, problemContentType, recorder.Header().Get("Content-Type"))
+	})
+	t.Run("Method not allowed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/42/restore", nil))
+		var problem dto.Problem
+		_ = json.Unmarshal(recorder.Body.Bytes(), &problem)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+		assert.Equal(t, CodeMethodNotAllowed, problem.Code)
+	})
+}
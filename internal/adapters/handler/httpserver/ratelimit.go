@@ -0,0 +1,58 @@
+package httpserver
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/ratelimit"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimiter takes a token from the bucket of the client of a request to a route, e.g. "POST /users".
+// Both methods return nil when the request is not limited.
+type RateLimiter interface {
+	// AllowIP charges the request to its IP, before it is authenticated.
+	AllowIP(r *http.Request, route string) (*ratelimit.Decision, error)
+	// Allow charges the request to its client, once authenticated.
+	Allow(r *http.Request, route string) (*ratelimit.Decision, error)
+}
+
+// rateLimit rejects the requests of clients exceeding their rate limit with 429, and describes the limit
+// in the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers. allow selects the bucket charged,
+// RateLimiter.AllowIP before authentication and RateLimiter.Allow after it. Requests are allowed when the
+// limiter fails, the API staying available when a shared store is not. Without limiter, requests are not limited.
+// Health probes are never limited: an orchestrator would take the rejections for failures.
+func rateLimit(l RateLimiter, allow func(RateLimiter, *http.Request, string) (*ratelimit.Decision, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if l == nil || isProbe(ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+		decision, err := allow(l, ctx.Request, ctx.Request.Method+" "+ctx.FullPath())
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to apply the rate limit", "error", err)
+		}
+		if decision == nil {
+			ctx.Next()
+			return
+		}
+		ctx.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		ctx.Header("RateLimit-Reset", seconds(decision.Reset))
+		if !decision.Allowed {
+			retryAfter := seconds(decision.RetryAfter)
+			ctx.Header("Retry-After", retryAfter)
+			abortWithProblem(ctx, http.StatusTooManyRequests, CodeRateLimited, fmt.Sprintf("rate limit exceeded, retry in %s seconds", retryAfter))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// seconds formats d in whole seconds, rounded up: clients retrying earlier would be rejected again.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/ratelimit"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// failingLimiter stands for a shared store that cannot be reached.
+type failingLimiter struct{}
+
+func (failingLimiter) AllowIP(*http.Request, string) (*ratelimit.Decision, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingLimiter) Allow(*http.Request, string) (*ratelimit.Decision, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	mockUserService := &UserMockService{}
+	limiter, _ := ratelimit.New(config.RateLimit{
+		Routes: map[string]config.RateLimitRule{"GET /users": {Requests: 2, Per: time.Minute}},
+	}, ratelimit.NewMemoryStore())
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)}, WithRateLimiter(limiter))
+	serve := func(router *Router, target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+	mockUserService.On("List", mock.Anything, mock.Anything).Return(&service.UserPage{}, nil)
+
+	t.Run("Describe the limit of allowed requests", func(t *testing.T) {
+		recorder := serve(router, "/users")
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+	})
+	t.Run("Reject requests exceeding the limit", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(router, "/users").Code)
+		recorder := serve(router, "/users?limit=5")
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+		assert.Contains(t, recorder.Body.String(), CodeRateLimited)
+	})
+	t.Run("Routes without rule are not limited", func(t *testing.T) {
+		recorder := serve(router, "/accounts")
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	})
+	t.Run("Limit requests with invalid credentials by IP", func(t *testing.T) {
+		alice := service.Principal{Subject: "alice", Method: service.AuthJWT}
+		limiter, _ := ratelimit.New(config.RateLimit{IP: &config.RateLimitRule{Requests: 2, Per: time.Minute}}, ratelimit.NewMemoryStore())
+		authenticated := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)},
+			WithAuthenticator(tokenAuthenticator{token: "t1", principal: alice}), WithRateLimiter(limiter))
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request.RemoteAddr = "10.0.0.9:1234"
+		request.Header.Set("Authorization", "Bearer forged")
+		codes := make([]int, 3)
+		for i := range codes {
+			recorder := httptest.NewRecorder()
+			authenticated.ServeHTTP(recorder, request)
+			codes[i] = recorder.Code
+		}
+
+		assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+	})
+	t.Run("Allow requests when the limiter fails", func(t *testing.T) {
+		failing := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)}, WithRateLimiter(failingLimiter{}))
+
+		assert.Equal(t, http.StatusOK, serve(failing, "/users").Code)
+	})
+}
@@ -10,19 +10,92 @@ import (
 
 type Router struct {
 	*gin.Engine
+	authorizer  Authorizer
+	idempotency *idempotency
 }
 
-func NewServer(cfg *config.HTTP, serverHandlers []HttpHandlers) *http.Server {
+type serverOptions struct {
+	authenticator Authenticator
+	authorizer    Authorizer
+	rateLimiter   RateLimiter
+	idempotency   *idempotency
+	metrics       Metrics
+	tracing       gin.HandlerFunc
+	health        *Health
+}
+
+type ServerOption func(*serverOptions)
+
+// WithAuthenticator requires every request, except the API documentation, to be authenticated by a.
+func WithAuthenticator(a Authenticator) ServerOption {
+	return func(o *serverOptions) {
+		o.authenticator = a
+	}
+}
+
+// WithAuthorizer restricts the routes requiring a permission to the callers a grants it.
+func WithAuthorizer(a Authorizer) ServerOption {
+	return func(o *serverOptions) {
+		o.authorizer = a
+	}
+}
+
+// WithRateLimiter limits the requests of each client with l.
+func WithRateLimiter(l RateLimiter) ServerOption {
+	return func(o *serverOptions) {
+		o.rateLimiter = l
+	}
+}
+
+func NewServer(cfg *config.HTTP, serverHandlers []HttpHandlers, opts ...ServerOption) *http.Server {
 	return &http.Server{
 		Addr:    cfg.URL + ":" + cfg.Port,
-		Handler: newRouter(cfg.GinMode, serverHandlers),
+		Handler: newRouter(cfg, serverHandlers, opts...),
 	}
 }
 
-func newRouter(ginMode string, handlers []HttpHandlers) *Router {
-	gin.SetMode(ginMode)
-	router := &Router{gin.Default()}
+func newRouter(cfg *config.HTTP, handlers []HttpHandlers, opts ...ServerOption) *Router {
+	var options serverOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	gin.SetMode(cfg.GinMode)
+	router := &Router{Engine: gin.New(), authorizer: options.authorizer, idempotency: options.idempotency}
+	router.HandleMethodNotAllowed = true
+	// handlers pass the gin context to the service, which reads the request context values through it
+	router.ContextWithFallback = true
+	router.NoRoute(func(ctx *gin.Context) {
+		abortWithProblem(ctx, http.StatusNotFound, CodeNotFound, "no route matches "+ctx.Request.URL.Path)
+	})
+	router.NoMethod(func(ctx *gin.Context) {
+		abortWithProblem(ctx, http.StatusMethodNotAllowed, CodeMethodNotAllowed, ctx.Request.Method+" is not allowed on "+ctx.Request.URL.Path)
+	})
+	// the span, the request ID, the access log and the metrics come first, to cover the requests rejected by
+	// other middlewares
+	if options.tracing != nil {
+		router.Use(options.tracing)
+	}
+	router.Use(requestID(), accessLog())
+	if options.metrics != nil {
+		router.Use(observe(options.metrics))
+	}
+	router.Use(gin.CustomRecovery(func(ctx *gin.Context, err any) {
+		if err == http.ErrAbortHandler {
+			// Let net/http abort the response, as handlers do when a streamed response fails midway.
+			panic(err)
+		}
+		abortWithProblem(ctx, http.StatusInternalServerError, CodeInternalError, "")
+	}))
+	router.Use(rateLimit(options.rateLimiter, RateLimiter.AllowIP), authenticate(options.authenticator),
+		rateLimit(options.rateLimiter, RateLimiter.Allow))
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	if options.metrics != nil {
+		router.GET(metricsPath, gin.WrapH(options.metrics.Handler()))
+	}
+	if options.health != nil {
+		router.GET(healthzPath, options.health.Liveness)
+		router.GET(readyzPath, options.health.Readiness)
+	}
 
 	for _, handler := range handlers {
 		handler.SetupRoutes(router)
@@ -0,0 +1,16 @@
+package httpserver
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"net/http"
+)
+
+// WithTracing starts a span of serviceName for every request, named after its route template, as the child of
+// the span of the caller sent in the W3C traceparent header. Scrapes of the metrics and health probes are not traced.
+func WithTracing(serviceName string) ServerOption {
+	return func(o *serverOptions) {
+		o.tracing = otelgin.Middleware(serviceName, otelgin.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != metricsPath && !isProbe(r.URL.Path)
+		}))
+	}
+}
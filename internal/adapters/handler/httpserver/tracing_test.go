@@ -0,0 +1,53 @@
+package httpserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/metrics"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	defaultProvider, defaultPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(defaultProvider)
+		otel.SetTextMapPropagator(defaultPropagator)
+	})
+
+	mockUserService := &UserMockService{}
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)},
+		WithAuthenticator(adminAuthenticator(t)), WithTracing("onboarding-test"), WithMetrics(metrics.New()))
+	var handlerSpan trace.SpanContext
+	mockUserService.On("FindById", mock.Anything, "42").Run(func(args mock.Arguments) {
+		handlerSpan = trace.SpanContextFromContext(args.Get(0).(*gin.Context).Request.Context())
+	}).Return(&model.User{ID: "42"}, nil).Once()
+
+	request := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	request.Header.Set(auth.AdminKeyHeader, "secret")
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, metricsPath, nil))
+
+	ended := spans.Ended()
+	if assert.Len(t, ended, 1) {
+		assert.Equal(t, "/users/:id", ended[0].Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", ended[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", ended[0].Parent().SpanID().String())
+		assert.Equal(t, ended[0].SpanContext().SpanID(), handlerSpan.SpanID())
+	}
+	mockUserService.AssertExpectations(t)
+}
@@ -0,0 +1,320 @@
+package httpserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Export formats, selected by the format query parameter or the Accept header.
+const (
+	exportCSV     = "csv"
+	exportNDJSON  = "ndjson"
+	exportParquet = "parquet"
+
+	parquetContentType = "application/vnd.apache.parquet"
+
+	// exportFlushRows is the number of users after which the exported content is flushed to the client.
+	exportFlushRows = 500
+	// parquetRowGroupSize bounds the number of users a Parquet export buffers before writing them.
+	parquetRowGroupSize = 10000
+)
+
+var exportContentTypes = map[string]string{
+	exportCSV:     csvContentType,
+	exportNDJSON:  ndjsonContentType,
+	exportParquet: parquetContentType,
+}
+
+// exportFields are the user fields an export can select, in their default order.
+var exportFields = []string{"id", model.FieldFirstName, model.FieldLastName, model.FieldEmail, model.FieldAge, "version"}
+
+// userEncoder writes users one at a time. Flush writes the buffered users, Close completes the document.
+type userEncoder interface {
+	Encode(u model.User) error
+	Flush() error
+	Close() error
+}
+
+// negotiateExportFormat picks the format named by the format query parameter or, without it,
+// the first format accepted by the Accept header. CSV is the default.
+func negotiateExportFormat(format string, accept string) (string, bool) {
+	if format != "" {
+		_, ok := exportContentTypes[format]
+		return format, ok
+	}
+	if accept == "" {
+		return exportCSV, true
+	}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if mediaType == "*/*" || mediaType == "text/*" {
+			return exportCSV, true
+		}
+		for format, contentType := range exportContentTypes {
+			if mediaType == contentType {
+				return format, true
+			}
+		}
+	}
+	return "", false
+}
+
+// parseExportFields validates the comma-separated list of distinct fields to export, all fields when empty.
+func parseExportFields(list string) ([]string, error) {
+	if list == "" {
+		return exportFields, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if userField(model.User{}, field) == nil {
+			return nil, fmt.Errorf("cannot export field %q", field)
+		}
+		if slices.Contains(fields, field) {
+			return nil, fmt.Errorf("field %q is listed more than once", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// userExport writes the exported users to the response. The headers are written with the first user,
+// so that an export failing before any user is read can still be answered with a problem.
+type userExport struct {
+	ctx     *gin.Context
+	format  string
+	fields  []string
+	gzip    *gzip.Writer
+	encoder userEncoder
+	rows    int
+}
+
+func newUserExport(ctx *gin.Context, format string, fields []string) *userExport {
+	return &userExport{ctx: ctx, format: format, fields: fields}
+}
+
+func (e *userExport) started() bool {
+	return e.encoder != nil
+}
+
+func (e *userExport) start() error {
+	header := e.ctx.Writer.Header()
+	header.Set("Content-Type", exportContentTypes[e.format])
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, e.format))
+	var w io.Writer = e.ctx.Writer
+	if e.format != exportParquet {
+		header.Add("Vary", "Accept-Encoding")
+		if acceptsGzip(e.ctx.GetHeader("Accept-Encoding")) {
+			header.Set("Content-Encoding", "gzip")
+			e.gzip = gzip.NewWriter(e.ctx.Writer)
+			w = e.gzip
+		}
+	}
+	e.ctx.Status(http.StatusOK)
+	var err error
+	e.encoder, err = newUserEncoder(e.format, w, e.fields)
+	return err
+}
+
+func (e *userExport) write(u model.User) error {
+	if !e.started() {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if err := e.encoder.Encode(u); err != nil {
+		return err
+	}
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// flush sends the users encoded so far. Parquet encoders only write complete row groups.
+func (e *userExport) flush() error {
+	if err := e.encoder.Flush(); err != nil {
+		return err
+	}
+	if e.gzip != nil {
+		if err := e.gzip.Flush(); err != nil {
+			return err
+		}
+	}
+	e.ctx.Writer.Flush()
+	return nil
+}
+
+// close completes the exported document, which is empty but well-formed when no user matched.
+func (e *userExport) close() error {
+	if !e.started() {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if err := e.encoder.Close(); err != nil {
+		return err
+	}
+	if e.gzip != nil {
+		if err := e.gzip.Close(); err != nil {
+			return err
+		}
+	}
+	e.ctx.Writer.Flush()
+	return nil
+}
+
+func userField(u model.User, field string) any {
+	switch field {
+	case "id":
+		return u.ID
+	case model.FieldFirstName:
+		return u.FirstName
+	case model.FieldLastName:
+		return u.LastName
+	case model.FieldEmail:
+		return u.Email
+	case model.FieldAge:
+		return int64(u.Age)
+	case "version":
+		return u.Version
+	}
+	return nil
+}
+
+func newUserEncoder(format string, w io.Writer, fields []string) (userEncoder, error) {
+	switch format {
+	case exportNDJSON:
+		return &ndjsonEncoder{w: w, fields: fields}, nil
+	case exportParquet:
+		return newParquetEncoder(w, fields), nil
+	default:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(fields); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: writer, fields: fields}, nil
+	}
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	fields []string
+}
+
+func (e *csvEncoder) Encode(u model.User) error {
+	record := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		switch value := userField(u, field).(type) {
+		case string:
+			record[i] = value
+		case int64:
+			record[i] = strconv.FormatInt(value, 10)
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	return e.Flush()
+}
+
+// ndjsonEncoder writes a JSON object per line, with the fields in the selected order.
+type ndjsonEncoder struct {
+	w      io.Writer
+	fields []string
+	buf    bytes.Buffer
+}
+
+func (e *ndjsonEncoder) Encode(u model.User) error {
+	e.buf.Reset()
+	e.buf.WriteByte('{')
+	for i, field := range e.fields {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		value, err := json.Marshal(userField(u, field))
+		if err != nil {
+			return err
+		}
+		e.buf.WriteString(strconv.Quote(field) + ":")
+		e.buf.Write(value)
+	}
+	e.buf.WriteString("}\n")
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+type parquetEncoder struct {
+	w      *parquet.Writer
+	fields []string
+}
+
+func newParquetEncoder(w io.Writer, fields []string) *parquetEncoder {
+	group := parquet.Group{}
+	for _, field := range fields {
+		if _, ok := userField(model.User{}, field).(int64); ok {
+			group[field] = parquet.Int(64)
+		} else {
+			group[field] = parquet.String()
+		}
+	}
+	schema := parquet.NewSchema("user", group)
+	return &parquetEncoder{w: parquet.NewWriter(w, schema, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)), fields: fields}
+}
+
+func (e *parquetEncoder) Encode(u model.User) error {
+	row := make(map[string]any, len(e.fields))
+	for _, field := range e.fields {
+		row[field] = userField(u, field)
+	}
+	return e.w.Write(row)
+}
+
+func (e *parquetEncoder) Flush() error {
+	return nil
+}
+
+func (e *parquetEncoder) Close() error {
+	return e.w.Close()
+}
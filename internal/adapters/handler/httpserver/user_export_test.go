@@ -0,0 +1,159 @@
+package httpserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateExportFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		accept   string
+		expected string
+		ok       bool
+	}{
+		{"Default", "", "", exportCSV, true},
+		{"Any type", "", "*/*", exportCSV, true},
+		{"Accept header", "", "application/xml;q=0.9, application/x-ndjson", exportNDJSON, true},
+		{"Refused type", "", "application/vnd.apache.parquet;q=0, text/csv", exportCSV, true},
+		{"Format over Accept header", exportParquet, "text/csv", exportParquet, true},
+		{"Unknown format", "xml", "", "xml", false},
+		{"Nothing acceptable", "", "application/xml", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, ok := negotiateExportFormat(tt.format, tt.accept)
+
+			assert.Equal(t, tt.expected, format)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestUserHandler_Export(t *testing.T) {
+	mockUserService := &UserMockService{}
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)})
+	users := []model.User{
+		{ID: "1", FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30, Version: 1},
+		{ID: "2", FirstName: "Jane", LastName: "Roe, Jr.", Email: "jane@roe.com", Age: 25, Version: 3},
+	}
+	mockUserService.On("Export", mock.Anything, service.UserFilter{}).Return(users, nil)
+	mockUserService.On("Export", mock.Anything, service.UserFilter{LastName: "Nobody"}).Return(nil, nil)
+	mockUserService.On("Export", mock.Anything, service.UserFilter{MinAge: 40, MaxAge: 30}).
+		Return(nil, service.ValidationError{Message: "invalid export parameters"})
+	mockUserService.On("Export", mock.Anything, service.UserFilter{FirstName: "Broken"}).Return(users, errors.New("cursor failed"))
+
+	export := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("CSV with selected fields", func(t *testing.T) {
+		recorder := export("/users/export?fields=lastName,age", nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, csvContentType, recorder.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="users.csv"`, recorder.Header().Get("Content-Disposition"))
+		assert.Equal(t, "lastName,age\nDoe,30\n\"Roe, Jr.\",25\n", recorder.Body.String())
+	})
+	t.Run("NDJSON from Accept header", func(t *testing.T) {
+		recorder := export("/users/export", map[string]string{"Accept": ndjsonContentType})
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, ndjsonContentType, recorder.Header().Get("Content-Type"))
+		lines := bytes.Split(bytes.TrimSpace(recorder.Body.Bytes()), []byte("\n"))
+		assert.Len(t, lines, 2)
+		assert.Equal(t, `{"id":"1","firstName":"John","lastName":"Doe","email":"john@doe.com","age":30,"version":1}`, string(lines[0]))
+		var user model.User
+		assert.NoError(t, json.Unmarshal(lines[1], &user))
+		assert.Equal(t, users[1], user)
+	})
+	t.Run("Parquet", func(t *testing.T) {
+		recorder := export("/users/export?format=parquet&fields=id,age", nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, parquetContentType, recorder.Header().Get("Content-Type"))
+		body := recorder.Body.Bytes()
+		file, err := parquet.OpenFile(bytes.NewReader(body), int64(len(body)))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), file.NumRows())
+		reader := parquet.NewReader(file)
+		row := map[string]any{}
+		assert.NoError(t, reader.Read(&row))
+		assert.Equal(t, map[string]any{"id": "1", "age": int64(30)}, row)
+	})
+	t.Run("Gzip", func(t *testing.T) {
+		recorder := export("/users/export?format=ndjson&fields=id", map[string]string{"Accept-Encoding": "br, gzip"})
+
+		assert.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+		reader, err := gzip.NewReader(recorder.Body)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n", string(content))
+	})
+	t.Run("No matching users", func(t *testing.T) {
+		recorder := export("/users/export?lastName=Nobody", nil)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "id,firstName,lastName,email,age,version\n", recorder.Body.String())
+	})
+	t.Run("Unknown field", func(t *testing.T) {
+		recorder := export("/users/export?fields=id,password", nil)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, CodeInvalidParameters, decodeProblem(t, recorder).Code)
+	})
+	t.Run("Duplicate field", func(t *testing.T) {
+		for _, format := range []string{"csv", "parquet"} {
+			recorder := export("/users/export?format="+format+"&fields=id,age,id", nil)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, CodeInvalidParameters, decodeProblem(t, recorder).Code)
+		}
+	})
+	t.Run("Invalid filter", func(t *testing.T) {
+		recorder := export("/users/export?minAge=40&maxAge=30", nil)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, CodeInvalidParameters, decodeProblem(t, recorder).Code)
+	})
+	t.Run("Not acceptable", func(t *testing.T) {
+		recorder := export("/users/export", map[string]string{"Accept": "application/xml"})
+
+		assert.Equal(t, http.StatusNotAcceptable, recorder.Code)
+		assert.Equal(t, CodeNotAcceptable, decodeProblem(t, recorder).Code)
+	})
+	t.Run("Failure after the first user", func(t *testing.T) {
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			export("/users/export?firstName=Broken&fields=id", nil)
+		})
+	})
+}
+
+func decodeProblem(t *testing.T, recorder *httptest.ResponseRecorder) dto.Problem {
+	t.Helper()
+	var problem dto.Problem
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	return problem
+}
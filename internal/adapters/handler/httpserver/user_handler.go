@@ -3,22 +3,16 @@ package httpserver
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/golodash/galidator"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"io"
 	"log/slog"
 	"net/http"
 )
 
-var validator = galidator.New().Validator(dto.UserInput{})
-
-type ErrorResponse struct {
-	Message string   `json:"error"`
-	Details []string `json:"details,omitempty"`
-}
-
 type UserHandler struct {
 	service UserService
 }
@@ -31,12 +25,27 @@ type UserService interface {
 	Save(ctx context.Context, u model.User) (*model.User, error)
 	Update(ctx context.Context, u model.User) (*model.User, error)
 	FindById(ctx context.Context, id string) (*model.User, error)
+	List(ctx context.Context, params service.ListParams) (*service.UserPage, error)
+	Patch(ctx context.Context, id string, version int64, patch service.UserPatch) (*model.User, error)
+	Delete(ctx context.Context, id string, hard bool) error
+	Restore(ctx context.Context, id string) (*model.User, error)
+	Import(ctx context.Context, rows []service.ImportRow, atomic bool) (*service.ImportReport, error)
+	Export(ctx context.Context, f service.UserFilter, fn func(u model.User) error) error
+	History(ctx context.Context, id string, params service.HistoryParams) (*service.HistoryPage, error)
 }
 
 func (h *UserHandler) SetupRoutes(r *Router) {
-	r.Handle(http.MethodGet, "/users/:id", h.FindById)
-	r.Handle(http.MethodPost, "/users", h.Create)
-	r.Handle(http.MethodPut, "/users/:id", h.Update)
+	read, write, del := r.require(service.PermUsersRead), r.require(service.PermUsersWrite), r.require(service.PermUsersDelete)
+	r.Handle(http.MethodGet, "/users", read, h.List)
+	r.Handle(http.MethodGet, "/users/export", read, h.Export)
+	r.Handle(http.MethodGet, "/users/:id", read, h.FindById)
+	r.Handle(http.MethodPost, "/users", write, r.idempotent(), h.Create)
+	r.Handle(http.MethodPost, "/users:method", write, r.idempotent(), h.customMethod)
+	r.Handle(http.MethodPut, "/users/:id", write, h.Update)
+	r.Handle(http.MethodPatch, "/users/:id", write, h.Patch)
+	r.Handle(http.MethodDelete, "/users/:id", del, h.Delete)
+	r.Handle(http.MethodPost, "/users/:id/restore", del, h.Restore)
+	r.Handle(http.MethodGet, "/users/:id/history", read, h.History)
 }
 
 // FindById godoc
@@ -45,9 +54,16 @@ func (h *UserHandler) SetupRoutes(r *Router) {
 // @Tags users
 // @Produce json
 // @Param id path string true "id"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Sucess 201 {object} model.User
-// @Failure 404
-// @Failure 400
+// @Header 200 {string} ETag "User version"
+// @Failure 304
+// @Failure 404 {object} dto.Problem
+// @Failure 400 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /users/{id} [get]
 func (h *UserHandler) FindById(ctx *gin.Context) {
 	user, err := h.service.FindById(ctx, ctx.Param("id"))
@@ -55,9 +71,62 @@ func (h *UserHandler) FindById(ctx *gin.Context) {
 		checkErr(ctx, err)
 		return
 	}
+	setETag(ctx, user)
+	if noneMatch(ctx.GetHeader("If-None-Match"), user) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
 	ctx.JSON(http.StatusOK, user)
 }
 
+// List godoc
+// @Summary List users
+// @Description List users page by page, filtered and sorted by query parameters
+// @Tags users
+// @Produce json
+// @Param firstName query string false "Exact first name"
+// @Param lastName query string false "Exact last name"
+// @Param email query string false "Exact email"
+// @Param minAge query int false "Minimum age"
+// @Param maxAge query int false "Maximum age"
+// @Param sort query string false "Sort field (id, firstName, lastName, email, age), prefix with - for descending order"
+// @Param cursor query string false "Cursor returned as meta.nextCursor by the previous page"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} dto.UserListResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users [get]
+func (h *UserHandler) List(ctx *gin.Context) {
+	query := dto.UserListQuery{}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeInvalidParameters, "invalid list parameters", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	page, err := h.service.List(ctx, service.ListParams{
+		Filter: service.UserFilter{
+			FirstName: query.FirstName,
+			LastName:  query.LastName,
+			Email:     query.Email,
+			MinAge:    query.MinAge,
+			MaxAge:    query.MaxAge,
+		},
+		Sort:   query.Sort,
+		Cursor: query.Cursor,
+		Limit:  query.Limit,
+	})
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.UserListResponse{
+		Data: page.Users,
+		Meta: dto.PageMeta{NextCursor: page.NextCursor, TotalCount: page.TotalCount, Limit: page.Limit},
+	})
+}
+
 // Create godoc
 // @Summary Create a new user
 // @Description Create new user based on request body input
@@ -65,24 +134,33 @@ func (h *UserHandler) FindById(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param User body model.User true "User input"
+// @Param Idempotency-Key header string false "Key replaying the response to retries"
 // @Sucess 201 {object} modes.User
-// @Failure 400
+// @Failure 400 {object} dto.Problem
+// @Failure 422 {object} dto.Problem
+// @Failure 409 {object} dto.Problem "A request with the same Idempotency-Key is being processed"
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /users [post]
 func (h *UserHandler) Create(ctx *gin.Context) {
 	userInput := dto.UserInput{}
 	if err := ctx.ShouldBindJSON(&userInput); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorDTO{
-			Message: "user did not pass validation",
-			Details: validator.DecryptErrors(err),
-		})
+		abortWithProblem(ctx, http.StatusBadRequest, CodeMalformedRequest, "invalid request body", dto.ProblemError{Message: err.Error()})
 		return
 	}
 	user, err := model.NewUser(ctx.Param("id"), userInput.FirstName, userInput.LastName, userInput.Email, userInput.Age)
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
 	savedUser, err := h.service.Save(ctx, *user)
 	if err != nil {
 		checkErr(ctx, err)
 		return
 	}
+	setETag(ctx, savedUser)
 	ctx.IndentedJSON(http.StatusCreated, savedUser)
 }
 
@@ -93,38 +171,314 @@ func (h *UserHandler) Create(ctx *gin.Context) {
 // @Produce json
 // @Param id path int true "ID"
 // @Param User body model.User true "User input"
+// @Param If-Match header string false "ETags, one of which the user must still have, or * for any existing user"
 // @Sucess 200 {object} model.User
-// @Failure 404 {object} model.User
+// @Header 200 {string} ETag "User version"
+// @Failure 404 {object} dto.Problem
+// @Failure 412 {object} dto.Problem
+// @Failure 422 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /users/{id} [put]
 func (h *UserHandler) Update(ctx *gin.Context) {
+	version, err := h.ifMatchVersion(ctx, ctx.Param("id"))
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
 	userInput := dto.UserInput{}
 	if err := ctx.ShouldBindJSON(&userInput); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorDTO{
-			Message: "user did not pass validation",
-			Details: validator.DecryptErrors(err),
-		})
+		abortWithProblem(ctx, http.StatusBadRequest, CodeMalformedRequest, "invalid request body", dto.ProblemError{Message: err.Error()})
 		return
 	}
 	user, err := model.NewUser(ctx.Param("id"), userInput.FirstName, userInput.LastName, userInput.Email, userInput.Age)
-	updatedUser, err := h.service.Update(ctx, *user)
 	if err != nil {
 		checkErr(ctx, err)
 		return
 	}
+	user.Version = version
+	updatedUser, err := h.service.Update(ctx, *user)
+	if err != nil {
+		checkErr(ctx, ifMatchErr(ctx, err))
+		return
+	}
+	setETag(ctx, updatedUser)
 	ctx.JSON(http.StatusOK, updatedUser)
 }
 
-func checkErr(ctx *gin.Context, err error) {
-	var validationErr service.ValidationError
-	switch {
-	case errors.As(err, &validationErr):
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Message: validationErr.Message, Details: validationErr.Details})
-	case errors.Is(err, service.ErrUserNotFound):
-		ctx.AbortWithStatusJSON(http.StatusNotFound, ErrorResponse{Message: err.Error()})
-	case errors.Is(err, service.ErrUsernameTaken):
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+// Patch godoc
+// @Summary Partially update user by ID
+// @Description Apply a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) to the user
+// @Tags users
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "ID"
+// @Param Patch body object true "Merge patch object or JSON Patch operations"
+// @Param If-Match header string false "ETags, one of which the user must still have, or * for any existing user"
+// @Success 200 {object} model.User
+// @Header 200 {string} ETag "User version"
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 412 {object} dto.Problem
+// @Failure 415 {object} dto.Problem
+// @Failure 422 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/{id} [patch]
+func (h *UserHandler) Patch(ctx *gin.Context) {
+	version, err := h.ifMatchVersion(ctx, ctx.Param("id"))
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeMalformedRequest, "could not read request body")
+		return
+	}
+	patch, err := newUserPatch(ctx.ContentType(), body)
+	if errors.Is(err, errUnsupportedPatch) {
+		abortWithProblem(ctx, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, err.Error(),
+			dto.ProblemError{Message: mergePatchContentType}, dto.ProblemError{Message: jsonPatchContentType})
+		return
+	}
+	if err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeMalformedRequest, "invalid patch document", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	patchedUser, err := h.service.Patch(ctx, ctx.Param("id"), version, patch)
+	if err != nil {
+		checkErr(ctx, ifMatchErr(ctx, err))
+		return
+	}
+	setETag(ctx, patchedUser)
+	ctx.JSON(http.StatusOK, patchedUser)
+}
+
+// Delete godoc
+// @Summary Delete user by ID
+// @Description Soft-delete user by default, hard=true removes the user for good and requires the users:delete:hard permission
+// @Tags users
+// @Param id path string true "ID"
+// @Param hard query bool false "Remove the user permanently"
+// @Param X-Admin-Key header string false "Admin key, which has every permission"
+// @Success 204
+// @Failure 403 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/{id} [delete]
+func (h *UserHandler) Delete(ctx *gin.Context) {
+	if err := h.service.Delete(ctx, ctx.Param("id"), ctx.Query("hard") == "true"); err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Restore godoc
+// @Summary Restore a soft-deleted user
+// @Tags users
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} model.User
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/{id}/restore [post]
+func (h *UserHandler) Restore(ctx *gin.Context) {
+	restoredUser, err := h.service.Restore(ctx, ctx.Param("id"))
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	setETag(ctx, restoredUser)
+	ctx.JSON(http.StatusOK, restoredUser)
+}
+
+// History godoc
+// @Summary Audit history of a user
+// @Description List the recorded changes of a user, newest first: who made them, when, in which request
+// @Description and the value of every changed field before and after. Users removed for good keep their history.
+// @Tags users
+// @Produce json
+// @Param id path string true "ID"
+// @Param cursor query string false "Cursor returned as meta.nextCursor by the previous page"
+// @Param limit query int false "Page size, up to 100"
+// @Success 200 {object} dto.HistoryResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/{id}/history [get]
+func (h *UserHandler) History(ctx *gin.Context) {
+	query := dto.HistoryQuery{}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeInvalidParameters, "invalid history parameters", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	page, err := h.service.History(ctx, ctx.Param("id"), service.HistoryParams{Cursor: query.Cursor, Limit: query.Limit})
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.HistoryResponse{
+		Data: page.Entries,
+		Meta: dto.HistoryMeta{NextCursor: page.NextCursor, Limit: page.Limit},
+	})
+}
+
+// customMethod dispatches the POST /users:<method> custom methods. gin reads the part following /users,
+// colon included, as the method path parameter.
+func (h *UserHandler) customMethod(ctx *gin.Context) {
+	switch ctx.Param("method") {
+	case ":batch":
+		h.Import(ctx)
 	default:
-		slog.Error(ctx.Request.RequestURI, "error", err.Error())
-		ctx.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Message: "Internal Server Error"})
+		abortWithProblem(ctx, http.StatusNotFound, CodeNotFound, "no route matches "+ctx.Request.URL.Path)
+	}
+}
+
+// Import godoc
+// @Summary Import users in bulk
+// @Description Create the users of a JSON array, NDJSON or CSV upload (header row: firstName,lastName,email,age)
+// @Description and report the outcome of every row. With atomic=true no user is created unless every row succeeds.
+// @Tags users
+// @Accept json
+// @Accept application/x-ndjson
+// @Accept text/csv
+// @Produce json
+// @Param atomic query bool false "Create all the users or none"
+// @Param Users body []dto.UserInput true "Users to create"
+// @Param Idempotency-Key header string false "Key replaying the response to retries"
+// @Success 201 {object} dto.ImportReport "Every user was created"
+// @Success 207 {object} dto.ImportReport "Some rows failed"
+// @Failure 400 {object} dto.Problem
+// @Failure 413 {object} dto.Problem
+// @Failure 415 {object} dto.Problem
+// @Failure 422 {object} dto.Problem "Atomic import rejected, no user was created"
+// @Failure 409 {object} dto.Problem "A request with the same Idempotency-Key is being processed"
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users:batch [post]
+func (h *UserHandler) Import(ctx *gin.Context) {
+	atomic := ctx.Query("atomic") == "true"
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportBodySize)
+	rows, err := decodeImport(ctx.ContentType(), body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, errUnsupportedImport):
+		abortWithProblem(ctx, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, err.Error(),
+			dto.ProblemError{Message: jsonContentType}, dto.ProblemError{Message: ndjsonContentType}, dto.ProblemError{Message: csvContentType})
+		return
+	case errors.As(err, &tooLarge):
+		abortWithProblem(ctx, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, fmt.Sprintf("uploads are limited to %d bytes", tooLarge.Limit))
+		return
+	case err != nil:
+		abortWithProblem(ctx, http.StatusBadRequest, CodeMalformedRequest, "invalid import upload", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	report, err := h.service.Import(ctx, rows, atomic)
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	for _, result := range report.Results {
+		if result.Err != nil && problemFor(result.Err).Status == http.StatusInternalServerError {
+			slog.ErrorContext(ctx, ctx.Request.RequestURI, "row", result.Row, "error", result.Err.Error())
+		}
+	}
+	if atomic && report.Failed > 0 {
+		abortWithProblem(ctx, http.StatusUnprocessableEntity, CodeBatchRejected, "no user was created because some rows failed", rejectedRows(report)...)
+		return
+	}
+	status := http.StatusCreated
+	if report.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	ctx.JSON(status, newImportReport(report))
+}
+
+// Export godoc
+// @Summary Export users
+// @Description Stream every user matching the filters as CSV, NDJSON or Parquet. The format query parameter
+// @Description takes precedence over the Accept header; CSV is the default. CSV and NDJSON are gzip-compressed
+// @Description when the client accepts it.
+// @Tags users
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.apache.parquet
+// @Param firstName query string false "Exact first name"
+// @Param lastName query string false "Exact last name"
+// @Param email query string false "Exact email"
+// @Param minAge query int false "Minimum age"
+// @Param maxAge query int false "Maximum age"
+// @Param format query string false "Export format (csv, ndjson, parquet)"
+// @Param fields query string false "Comma-separated distinct fields to export (id, firstName, lastName, email, age, version)"
+// @Success 200 {file} file
+// @Failure 400 {object} dto.Problem
+// @Failure 406 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/export [get]
+func (h *UserHandler) Export(ctx *gin.Context) {
+	query := dto.UserExportQuery{}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeInvalidParameters, "invalid export parameters", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	format, ok := negotiateExportFormat(query.Format, ctx.GetHeader("Accept"))
+	if !ok {
+		abortWithProblem(ctx, http.StatusNotAcceptable, CodeNotAcceptable, "no supported export format is acceptable",
+			dto.ProblemError{Message: csvContentType}, dto.ProblemError{Message: ndjsonContentType}, dto.ProblemError{Message: parquetContentType})
+		return
+	}
+	fields, err := parseExportFields(query.Fields)
+	if err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeInvalidParameters, "invalid export parameters", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	export := newUserExport(ctx, format, fields)
+	err = h.service.Export(ctx, service.UserFilter{
+		FirstName: query.FirstName,
+		LastName:  query.LastName,
+		Email:     query.Email,
+		MinAge:    query.MinAge,
+		MaxAge:    query.MaxAge,
+	}, export.write)
+	if err == nil {
+		err = export.close()
+	}
+	if err != nil && !export.started() {
+		checkErr(ctx, err)
+		return
+	}
+	if err != nil {
+		// The response is already under way: abort it unterminated so that the client detects the truncation.
+		slog.ErrorContext(ctx, ctx.Request.RequestURI, "error", err.Error())
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func checkErr(ctx *gin.Context, err error) {
+	problem := problemFor(err)
+	if problem.Status == http.StatusInternalServerError {
+		slog.ErrorContext(ctx, ctx.Request.RequestURI, "error", err.Error())
 	}
+	writeProblem(ctx, problem)
 }
@@ -2,9 +2,14 @@ package httpserver
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -70,13 +75,42 @@ func TestUserHandler_Create(t *testing.T) {
 		mockUserService.On("Save", ctx, user).Return(nil, err).Once()
 
 		handler.Create(ctx)
-		var responseBody ErrorResponse
+		var responseBody dto.Problem
 		_ = json.Unmarshal(recorder.Body.Bytes(), &responseBody)
 
 		assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
-		assert.NotEmpty(t, responseBody.Message)
-		assert.Equal(t, err.Error(), responseBody.Message)
-		assert.Nil(t, responseBody.Details)
+		assert.Equal(t, CodeUsernameTaken, responseBody.Code)
+		assert.NotEmpty(t, responseBody.Detail)
+		assert.Equal(t, err.Error(), responseBody.Detail)
+		assert.Nil(t, responseBody.Errors)
+	})
+	t.Run("Invalid fields are all reported", func(t *testing.T) {
+		t.Cleanup(reset)
+		ctx.Request = &http.Request{
+			Body: io.NopCloser(bytes.NewBufferString(`{"firstName":"John","email":"john","age":16}`)),
+		}
+
+		handler.Create(ctx)
+		var responseBody dto.Problem
+		_ = json.Unmarshal(recorder.Body.Bytes(), &responseBody)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, ctx.Writer.Status())
+		assert.Equal(t, CodeValidationFailed, responseBody.Code)
+		assert.Equal(t, []dto.ProblemError{
+			{Field: model.FieldLastName, Code: model.RuleRequired, Message: "last name is required"},
+			{Field: model.FieldEmail, Code: model.RuleEmail, Message: "invalid email"},
+			{Field: model.FieldAge, Code: model.RuleMinAge, Message: "user must be at least 18 years old"},
+		}, responseBody.Errors)
+	})
+	t.Run("Malformed body", func(t *testing.T) {
+		t.Cleanup(reset)
+		ctx.Request = &http.Request{
+			Body: io.NopCloser(bytes.NewBufferString(`{"age":"old"}`)),
+		}
+
+		handler.Create(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
 	})
 }
 
@@ -101,6 +135,7 @@ func TestUserHandler_Find(t *testing.T) {
 			Key:   "id",
 			Value: id,
 		}}
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/users/"+id, nil)
 		user := model.User{
 			ID:        id,
 			FirstName: "John",
@@ -121,6 +156,22 @@ func TestUserHandler_Find(t *testing.T) {
 		assert.Equal(t, user.Email, responseUser.Email)
 		assert.Equal(t, user.Age, responseUser.Age)
 	})
+	t.Run("User not modified", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/users/"+id, nil)
+		ctx.Request.Header.Set("If-None-Match", `"1", W/"2"`)
+		user := model.User{ID: id, FirstName: "John", LastName: "Doe", Email: "john@email.com", Age: 18, Version: 2}
+		mockUserService.On("FindById", ctx, id).Return(&user, nil).Once()
+
+		handler.FindById(ctx)
+		ctx.Writer.WriteHeaderNow()
+
+		assert.Equal(t, http.StatusNotModified, recorder.Code)
+		assert.Equal(t, `"2"`, recorder.Header().Get("ETag"))
+		assert.Empty(t, recorder.Body.Bytes())
+	})
 	t.Run("User not found", func(t *testing.T) {
 		t.Cleanup(reset)
 		id := primitive.NewObjectID().String()
@@ -132,13 +183,14 @@ func TestUserHandler_Find(t *testing.T) {
 		mockUserService.On("FindById", ctx, id).Return(nil, err).Once()
 
 		handler.FindById(ctx)
-		var responseBody ErrorResponse
+		var responseBody dto.Problem
 		_ = json.Unmarshal(recorder.Body.Bytes(), &responseBody)
 
 		assert.Equal(t, http.StatusNotFound, ctx.Writer.Status())
-		assert.NotEmpty(t, responseBody.Message)
-		assert.Equal(t, err.Error(), responseBody.Message)
-		assert.Nil(t, responseBody.Details)
+		assert.Equal(t, CodeUserNotFound, responseBody.Code)
+		assert.NotEmpty(t, responseBody.Detail)
+		assert.Equal(t, err.Error(), responseBody.Detail)
+		assert.Nil(t, responseBody.Errors)
 	})
 }
 
@@ -187,6 +239,27 @@ func TestUserHandler_Update(t *testing.T) {
 		assert.Equal(t, updatedUser.Email, responseUser.Email)
 		assert.Equal(t, updatedUser.Age, responseUser.Age)
 	})
+	t.Run("Outdated If-Match version", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		updatedUser := model.User{
+			FirstName: "John",
+			LastName:  "Doe",
+			Email:     "johndoe@email.com",
+			Age:       18,
+		}
+		jsonBody, _ := json.Marshal(updatedUser)
+		ctx.Request = httptest.NewRequest(http.MethodPut, "/users/"+id, bytes.NewBuffer(jsonBody))
+		ctx.Request.Header.Set("If-Match", `"2"`)
+		updatedUser.ID = id
+		updatedUser.Version = 2
+		mockUserService.On("Update", ctx, updatedUser).Return(nil, service.ErrVersionConflict).Once()
+
+		handler.Update(ctx)
+
+		assert.Equal(t, http.StatusPreconditionFailed, ctx.Writer.Status())
+	})
 	t.Run("User not found", func(t *testing.T) {
 		t.Cleanup(reset)
 		id := primitive.NewObjectID().String()
@@ -209,12 +282,374 @@ func TestUserHandler_Update(t *testing.T) {
 		mockUserService.On("Update", ctx, updatedUser).Return(nil, err).Once()
 
 		handler.Update(ctx)
-		var responseBody ErrorResponse
+		var responseBody dto.Problem
+		_ = json.Unmarshal(recorder.Body.Bytes(), &responseBody)
+
+		assert.Equal(t, http.StatusNotFound, ctx.Writer.Status())
+		assert.Equal(t, CodeUserNotFound, responseBody.Code)
+		assert.NotEmpty(t, responseBody.Detail)
+		assert.Equal(t, err.Error(), responseBody.Detail)
+		assert.Nil(t, responseBody.Errors)
+	})
+}
+
+func TestUserHandler_List(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUserService := &UserMockService{}
+	handler := NewUserHandler(mockUserService)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	// assert all expectations, reset recorder and create new context
+	reset := func() {
+		mockUserService.AssertExpectations(t)
+		mockUserService.ExpectedCalls = nil
+		recorder = httptest.NewRecorder()
+		ctx, _ = gin.CreateTestContext(recorder)
+	}
+	t.Run("List users sucessfully", func(t *testing.T) {
+		t.Cleanup(reset)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/users?lastName=Doe&minAge=18&sort=-age&limit=1", nil)
+		users := []model.User{{
+			ID:        primitive.NewObjectID().Hex(),
+			FirstName: "John",
+			LastName:  "Doe",
+			Email:     "john@email.com",
+			Age:       30,
+		}}
+		params := service.ListParams{
+			Filter: service.UserFilter{LastName: "Doe", MinAge: 18},
+			Sort:   "-age",
+			Limit:  1,
+		}
+		page := &service.UserPage{Users: users, NextCursor: "next", TotalCount: 2, Limit: 1}
+		mockUserService.On("List", ctx, params).Return(page, nil).Once()
+
+		handler.List(ctx)
+		var responseBody dto.UserListResponse
 		_ = json.Unmarshal(recorder.Body.Bytes(), &responseBody)
 
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+		assert.Equal(t, users, responseBody.Data)
+		assert.Equal(t, "next", responseBody.Meta.NextCursor)
+		assert.Equal(t, int64(2), responseBody.Meta.TotalCount)
+		assert.Equal(t, 1, responseBody.Meta.Limit)
+	})
+	t.Run("Invalid list parameters", func(t *testing.T) {
+		t.Cleanup(reset)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/users?sort=password", nil)
+		err := service.ValidationError{Message: "invalid list parameters", Details: []string{`cannot sort by "password"`}}
+		mockUserService.On("List", ctx, service.ListParams{Sort: "password"}).Return(nil, err).Once()
+
+		handler.List(ctx)
+		var responseBody dto.Problem
+		_ = json.Unmarshal(recorder.Body.Bytes(), &responseBody)
+
+		assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+		assert.Equal(t, CodeInvalidParameters, responseBody.Code)
+		assert.Equal(t, err.Message, responseBody.Detail)
+		assert.Equal(t, []dto.ProblemError{{Message: err.Details[0]}}, responseBody.Errors)
+	})
+	t.Run("Malformed query parameters", func(t *testing.T) {
+		t.Cleanup(reset)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/users?minAge=old", nil)
+
+		handler.List(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+	})
+}
+
+func TestUserHandler_Delete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUserService := &UserMockService{}
+	handler := NewUserHandler(mockUserService)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	// assert all expectations, reset recorder and create new context
+	reset := func() {
+		mockUserService.AssertExpectations(t)
+		mockUserService.ExpectedCalls = nil
+		recorder = httptest.NewRecorder()
+		ctx, _ = gin.CreateTestContext(recorder)
+	}
+	t.Run("Soft delete user sucessfully", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodDelete, "/users/"+id, nil)
+		mockUserService.On("Delete", ctx, id, false).Return(nil).Once()
+
+		handler.Delete(ctx)
+		ctx.Writer.WriteHeaderNow()
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+	})
+	t.Run("Hard delete without permission", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodDelete, "/users/"+id+"?hard=true", nil)
+		mockUserService.On("Delete", ctx, id, true).Return(service.ForbiddenError{Subject: "bob", Permission: service.PermUsersDeleteHard}).Once()
+
+		handler.Delete(ctx)
+
+		assert.Equal(t, http.StatusForbidden, ctx.Writer.Status())
+	})
+	t.Run("Hard delete", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodDelete, "/users/"+id+"?hard=true", nil)
+		mockUserService.On("Delete", ctx, id, true).Return(nil).Once()
+
+		handler.Delete(ctx)
+		ctx.Writer.WriteHeaderNow()
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+	})
+	t.Run("User not found", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodDelete, "/users/"+id, nil)
+		mockUserService.On("Delete", ctx, id, false).Return(service.ErrUserNotFound).Once()
+
+		handler.Delete(ctx)
+
+		assert.Equal(t, http.StatusNotFound, ctx.Writer.Status())
+	})
+}
+
+func TestUserHandler_Restore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUserService := &UserMockService{}
+	handler := NewUserHandler(mockUserService)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	// assert all expectations, reset recorder and create new context
+	reset := func() {
+		mockUserService.AssertExpectations(t)
+		mockUserService.ExpectedCalls = nil
+		recorder = httptest.NewRecorder()
+		ctx, _ = gin.CreateTestContext(recorder)
+	}
+	t.Run("Restore user sucessfully", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		user := model.User{ID: id, FirstName: "John", LastName: "Doe", Email: "john@email.com", Age: 18}
+		mockUserService.On("Restore", ctx, id).Return(&user, nil).Once()
+
+		handler.Restore(ctx)
+		var responseUser model.User
+		_ = json.Unmarshal(recorder.Body.Bytes(), &responseUser)
+
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+		assert.Equal(t, user, responseUser)
+	})
+	t.Run("Name taken by another user", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		mockUserService.On("Restore", ctx, id).Return(nil, service.ErrUsernameTaken).Once()
+
+		handler.Restore(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+	})
+}
+
+func TestUserHandler_Patch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUserService := &UserMockService{}
+	handler := NewUserHandler(mockUserService)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	// assert all expectations, reset recorder and create new context
+	reset := func() {
+		mockUserService.AssertExpectations(t)
+		mockUserService.ExpectedCalls = nil
+		recorder = httptest.NewRecorder()
+		ctx, _ = gin.CreateTestContext(recorder)
+	}
+	t.Run("Patch user sucessfully", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"age":40}`))
+		ctx.Request.Header.Set("Content-Type", mergePatchContentType)
+		ctx.Request.Header.Set("If-Match", `"3"`)
+		user := model.User{ID: id, FirstName: "John", LastName: "Doe", Email: "john@email.com", Age: 30, Version: 3}
+		appliesPatch := mock.MatchedBy(func(patch service.UserPatch) bool {
+			patched, err := patch(user)
+			return err == nil && patched.Age == 40
+		})
+		patchedUser := user
+		patchedUser.Age = 40
+		patchedUser.Version = 4
+		mockUserService.On("Patch", ctx, id, int64(3), appliesPatch).Return(&patchedUser, nil).Once()
+
+		handler.Patch(ctx)
+		var responseUser model.User
+		_ = json.Unmarshal(recorder.Body.Bytes(), &responseUser)
+
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+		assert.Equal(t, patchedUser, responseUser)
+		assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+	})
+	t.Run("Unsupported content type", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"age":40}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Patch(ctx)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, ctx.Writer.Status())
+	})
+	t.Run("Weak If-Match never matches", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"age":40}`))
+		ctx.Request.Header.Set("Content-Type", mergePatchContentType)
+		ctx.Request.Header.Set("If-Match", `W/"3"`)
+
+		handler.Patch(ctx)
+
+		assert.Equal(t, http.StatusPreconditionFailed, ctx.Writer.Status())
+	})
+	t.Run("If-Match list matching the current version", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"age":40}`))
+		ctx.Request.Header.Set("Content-Type", mergePatchContentType)
+		ctx.Request.Header.Set("If-Match", `"2", W/"4", "3"`)
+		user := model.User{ID: id, FirstName: "John", LastName: "Doe", Email: "john@email.com", Age: 30, Version: 3}
+		patchedUser := user
+		patchedUser.Age, patchedUser.Version = 40, 4
+		mockUserService.On("FindById", ctx, id).Return(&user, nil).Once()
+		mockUserService.On("Patch", ctx, id, int64(3), mock.Anything).Return(&patchedUser, nil).Once()
+
+		handler.Patch(ctx)
+
+		assert.Equal(t, http.StatusOK, ctx.Writer.Status())
+	})
+	t.Run("If-Match list without the current version", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"age":40}`))
+		ctx.Request.Header.Set("Content-Type", mergePatchContentType)
+		ctx.Request.Header.Set("If-Match", `"1","2"`)
+		mockUserService.On("FindById", ctx, id).Return(&model.User{ID: id, Version: 3}, nil).Once()
+
+		handler.Patch(ctx)
+
+		assert.Equal(t, http.StatusPreconditionFailed, ctx.Writer.Status())
+	})
+	t.Run("If-Match on a missing user", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"age":40}`))
+		ctx.Request.Header.Set("Content-Type", mergePatchContentType)
+		ctx.Request.Header.Set("If-Match", "*")
+		mockUserService.On("Patch", ctx, id, int64(0), mock.Anything).Return(nil, service.ErrUserNotFound).Once()
+
+		handler.Patch(ctx)
+
+		assert.Equal(t, http.StatusPreconditionFailed, ctx.Writer.Status())
+	})
+	t.Run("Malformed patch", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"op":"add"}`))
+		ctx.Request.Header.Set("Content-Type", jsonPatchContentType)
+
+		handler.Patch(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, ctx.Writer.Status())
+	})
+	t.Run("User not found", func(t *testing.T) {
+		t.Cleanup(reset)
+		id := primitive.NewObjectID().Hex()
+		ctx.Params = []gin.Param{{Key: "id", Value: id}}
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/users/"+id, bytes.NewBufferString(`{"age":40}`))
+		ctx.Request.Header.Set("Content-Type", mergePatchContentType)
+		mockUserService.On("Patch", ctx, id, int64(0), mock.Anything).Return(nil, service.ErrUserNotFound).Once()
+
+		handler.Patch(ctx)
+
 		assert.Equal(t, http.StatusNotFound, ctx.Writer.Status())
-		assert.NotEmpty(t, responseBody.Message)
-		assert.Equal(t, err.Error(), responseBody.Message)
-		assert.Nil(t, responseBody.Details)
 	})
 }
+
+func TestUserHandler_History(t *testing.T) {
+	mockUserService := &UserMockService{}
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)}, WithAuthenticator(adminAuthenticator(t)))
+	id := primitive.NewObjectID().Hex()
+	page := &service.HistoryPage{
+		Entries: []model.AuditEntry{{
+			ID:        "2",
+			UserID:    id,
+			Operation: model.OperationUpdate,
+			Actor:     auth.AdminSubject,
+			Changes:   []model.FieldChange{{Field: model.FieldAge, Before: float64(30), After: float64(31)}},
+		}},
+		NextCursor: "next",
+		Limit:      1,
+	}
+
+	t.Run("Read a page of history", func(t *testing.T) {
+		mockUserService.On("History", mock.Anything, id, service.HistoryParams{Cursor: "abc", Limit: 1}).Return(page, nil).Once()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/"+id+"/history?cursor=abc&limit=1", nil))
+
+		var response dto.HistoryResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, page.Entries, response.Data)
+		assert.Equal(t, dto.HistoryMeta{NextCursor: "next", Limit: 1}, response.Meta)
+	})
+	t.Run("Unknown user", func(t *testing.T) {
+		mockUserService.On("History", mock.Anything, "unknown", service.HistoryParams{}).Return(nil, service.ErrUserNotFound).Once()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/unknown/history", nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+	t.Run("Changes carry the actor and request ID", func(t *testing.T) {
+		audited := mock.MatchedBy(func(ctx context.Context) bool {
+			return service.ActorFrom(ctx) == auth.AdminSubject && service.RequestIDFrom(ctx) == "req-1"
+		})
+		mockUserService.On("Delete", audited, id, false).Return(nil).Once()
+		request := httptest.NewRequest(http.MethodDelete, "/users/"+id, nil)
+		request.Header.Set(auth.AdminKeyHeader, "secret")
+		request.Header.Set(requestIDHeader, "req-1")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Equal(t, "req-1", recorder.Header().Get(requestIDHeader))
+	})
+	t.Run("Request IDs are generated when missing", func(t *testing.T) {
+		anonymous := mock.MatchedBy(func(ctx context.Context) bool {
+			return service.ActorFrom(ctx) == service.AnonymousActor && service.RequestIDFrom(ctx) != ""
+		})
+		mockUserService.On("Delete", anonymous, id, false).Return(nil).Once()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/users/"+id, nil))
+
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.Len(t, recorder.Header().Get(requestIDHeader), 32)
+	})
+	mockUserService.AssertExpectations(t)
+}
@@ -0,0 +1,199 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonContentType   = "application/json"
+	ndjsonContentType = "application/x-ndjson"
+	csvContentType    = "text/csv"
+
+	// maxImportBodySize bounds the size of an import upload.
+	maxImportBodySize = 32 << 20
+	maxNDJSONLineSize = 1 << 20
+)
+
+// Codes of the import row decoding failures.
+const (
+	ruleType      = "type"
+	ruleMalformed = "malformed"
+)
+
+var errUnsupportedImport = errors.New("unsupported import content type")
+
+// importDecoders read the rows of an import batch, by content type. They fail when the upload itself
+// is malformed and report rows that cannot be decoded as rows with an error.
+var importDecoders = map[string]func(r io.Reader) ([]service.ImportRow, error){
+	jsonContentType:   decodeJSONImport,
+	ndjsonContentType: decodeNDJSONImport,
+	csvContentType:    decodeCSVImport,
+}
+
+func decodeImport(contentType string, r io.Reader) ([]service.ImportRow, error) {
+	decode, ok := importDecoders[contentType]
+	if !ok {
+		return nil, errUnsupportedImport
+	}
+	return decode(r)
+}
+
+// decodeJSONImport reads a JSON array of users.
+func decodeJSONImport(r io.Reader) ([]service.ImportRow, error) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("body must be a JSON array of users")
+	}
+	var rows []service.ImportRow
+	for decoder.More() {
+		if len(rows) > service.MaxImportSize {
+			return rows, nil
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		rows = append(rows, decodeJSONRow(raw))
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// decodeNDJSONImport reads one JSON user per line, skipping blank lines.
+func decodeNDJSONImport(r io.Reader) ([]service.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	var rows []service.ImportRow
+	for scanner.Scan() && len(rows) <= service.MaxImportSize {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rows = append(rows, decodeJSONRow(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func decodeJSONRow(data []byte) service.ImportRow {
+	var input dto.UserInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return service.ImportRow{Err: model.ValidationErrors{
+				{Field: typeErr.Field, Code: ruleType, Message: fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type)},
+			}}
+		}
+		return service.ImportRow{Err: model.ValidationErrors{{Code: ruleMalformed, Message: err.Error()}}}
+	}
+	return service.ImportRow{User: model.User{FirstName: input.FirstName, LastName: input.LastName, Email: input.Email, Age: input.Age}}
+}
+
+// decodeCSVImport reads users from CSV with a header row naming the firstName, lastName, email and age columns.
+func decodeCSVImport(r io.Reader) ([]service.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, field := range []string{model.FieldFirstName, model.FieldLastName, model.FieldEmail, model.FieldAge} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("CSV header has no %s column", field)
+		}
+	}
+	var rows []service.ImportRow
+	for len(rows) <= service.MaxImportSize {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, csv.ErrFieldCount) {
+			rows = append(rows, service.ImportRow{Err: model.ValidationErrors{{Code: ruleMalformed, Message: err.Error()}}})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, decodeCSVRow(record, columns))
+	}
+	return rows, nil
+}
+
+func decodeCSVRow(record []string, columns map[string]int) service.ImportRow {
+	user := model.User{
+		FirstName: record[columns[model.FieldFirstName]],
+		LastName:  record[columns[model.FieldLastName]],
+		Email:     record[columns[model.FieldEmail]],
+	}
+	if age := strings.TrimSpace(record[columns[model.FieldAge]]); age != "" {
+		var err error
+		if user.Age, err = strconv.Atoi(age); err != nil {
+			return service.ImportRow{Err: model.ValidationErrors{
+				{Field: model.FieldAge, Code: ruleType, Message: "age must be an integer"},
+			}}
+		}
+	}
+	return service.ImportRow{User: user}
+}
+
+func newImportReport(report *service.ImportReport) dto.ImportReport {
+	response := dto.ImportReport{
+		Atomic:  report.Atomic,
+		Total:   len(report.Results),
+		Created: report.Created,
+		Failed:  report.Failed,
+		Results: make([]dto.ImportRowResult, len(report.Results)),
+	}
+	for i, result := range report.Results {
+		row := dto.ImportRowResult{Row: result.Row, Status: dto.ImportCreated, User: result.User}
+		if result.Err != nil {
+			problem := problemFor(result.Err)
+			row.Status = dto.ImportFailed
+			row.Code = problem.Code
+			row.Errors = problem.Errors
+			if len(row.Errors) == 0 && problem.Detail != "" {
+				row.Errors = []dto.ProblemError{{Message: problem.Detail}}
+			}
+		}
+		response.Results[i] = row
+	}
+	return response
+}
+
+// rejectedRows lists the causes of the rows that made an all-or-nothing import fail.
+func rejectedRows(report *service.ImportReport) []dto.ProblemError {
+	var errs []dto.ProblemError
+	for _, row := range newImportReport(report).Results {
+		if row.Code == CodeImportAborted {
+			continue
+		}
+		for _, rowErr := range row.Errors {
+			rowErr.Row = row.Row
+			if rowErr.Code == "" {
+				rowErr.Code = row.Code
+			}
+			errs = append(errs, rowErr)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,128 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeImport(t *testing.T) {
+	john := service.ImportRow{User: model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30}}
+	ageType := model.ValidationErrors{{Field: model.FieldAge, Code: ruleType}}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    []service.ImportRow
+	}{
+		{"JSON array", jsonContentType, `[{"firstName":"John","lastName":"Doe","email":"john@doe.com","age":30},{"age":"old"}]`,
+			[]service.ImportRow{john, {Err: ageType}}},
+		{"NDJSON", ndjsonContentType, "{\"firstName\":\"John\",\"lastName\":\"Doe\",\"email\":\"john@doe.com\",\"age\":30}\n\n{\"age\":\"old\"}\n",
+			[]service.ImportRow{john, {Err: ageType}}},
+		{"CSV", csvContentType, "email,firstName,lastName,age\njohn@doe.com,John,Doe,30\njane@doe.com,Jane,Doe,old\n",
+			[]service.ImportRow{john, {Err: ageType}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := decodeImport(tt.contentType, strings.NewReader(tt.body))
+
+			assert.NoError(t, err)
+			assert.Len(t, rows, len(tt.expected))
+			for i, row := range rows {
+				assert.Equal(t, tt.expected[i].User, row.User)
+				var fieldErrs model.ValidationErrors
+				if tt.expected[i].Err != nil && assert.True(t, errors.As(row.Err, &fieldErrs)) {
+					assert.Equal(t, model.FieldAge, fieldErrs[0].Field)
+					assert.Equal(t, ruleType, fieldErrs[0].Code)
+				}
+			}
+		})
+	}
+	t.Run("Malformed uploads", func(t *testing.T) {
+		for contentType, body := range map[string]string{
+			jsonContentType: `{"firstName":"John"}`,
+			csvContentType:  "firstName,lastName,email\nJohn,Doe,john@doe.com\n",
+		} {
+			_, err := decodeImport(contentType, strings.NewReader(body))
+			assert.Error(t, err, contentType)
+		}
+	})
+	t.Run("Unsupported content type", func(t *testing.T) {
+		_, err := decodeImport("application/xml", strings.NewReader("<users/>"))
+		assert.ErrorIs(t, err, errUnsupportedImport)
+	})
+}
+
+func TestUserHandler_Import(t *testing.T) {
+	mockUserService := &UserMockService{}
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewUserHandler(mockUserService)})
+	john := model.User{ID: "1", FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30, Version: 1}
+	body := `[{"firstName":"John","lastName":"Doe","email":"john@doe.com","age":30},{"firstName":"Jim"}]`
+	rows := []service.ImportRow{
+		{User: model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30}},
+		{User: model.User{FirstName: "Jim"}},
+	}
+	fieldErrs := model.ValidationErrors{{Field: model.FieldLastName, Code: model.RuleRequired, Message: "last name is required"}}
+
+	importUsers := func(target string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		request.Header.Set("Content-Type", jsonContentType)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("Report every row", func(t *testing.T) {
+		t.Cleanup(func() { mockUserService.AssertExpectations(t); mockUserService.ExpectedCalls = nil })
+		report := &service.ImportReport{Created: 1, Failed: 1, Results: []service.ImportResult{
+			{Row: 1, User: &john},
+			{Row: 2, Err: fieldErrs},
+		}}
+		mockUserService.On("Import", mock.Anything, rows, false).Return(report, nil).Once()
+
+		recorder := importUsers("/users:batch")
+		var response dto.ImportReport
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+
+		assert.Equal(t, http.StatusMultiStatus, recorder.Code)
+		assert.Equal(t, 2, response.Total)
+		assert.Equal(t, dto.ImportRowResult{Row: 1, Status: dto.ImportCreated, User: &john}, response.Results[0])
+		assert.Equal(t, dto.ImportRowResult{Row: 2, Status: dto.ImportFailed, Code: CodeValidationFailed, Errors: []dto.ProblemError{
+			{Field: model.FieldLastName, Code: model.RuleRequired, Message: "last name is required"},
+		}}, response.Results[1])
+	})
+	t.Run("Atomic import rejected", func(t *testing.T) {
+		t.Cleanup(func() { mockUserService.AssertExpectations(t); mockUserService.ExpectedCalls = nil })
+		report := &service.ImportReport{Atomic: true, Failed: 2, Results: []service.ImportResult{
+			{Row: 1, Err: service.ErrImportAborted},
+			{Row: 2, Err: fieldErrs},
+		}}
+		mockUserService.On("Import", mock.Anything, rows, true).Return(report, nil).Once()
+
+		recorder := importUsers("/users:batch?atomic=true")
+		var problem dto.Problem
+		_ = json.Unmarshal(recorder.Body.Bytes(), &problem)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		assert.Equal(t, CodeBatchRejected, problem.Code)
+		assert.Equal(t, []dto.ProblemError{
+			{Row: 2, Field: model.FieldLastName, Code: model.RuleRequired, Message: "last name is required"},
+		}, problem.Errors)
+	})
+	t.Run("Unknown custom method", func(t *testing.T) {
+		recorder := importUsers("/users:merge")
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/mock"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
 )
 
 type UserMockService struct {
@@ -46,3 +47,86 @@ func (m *UserMockService) FindById(ctx context.Context, id string) (*model.User,
 	}
 	return nil, err
 }
+func (m *UserMockService) List(ctx context.Context, params service.ListParams) (*service.UserPage, error) {
+	called := m.Called(ctx, params)
+	if len(called) == 0 {
+		panic("no return value specified for List")
+	}
+	page := called.Get(0)
+	err := called.Error(1)
+	if page != nil {
+		return page.(*service.UserPage), err
+	}
+	return nil, err
+}
+func (m *UserMockService) Delete(ctx context.Context, id string, hard bool) error {
+	called := m.Called(ctx, id, hard)
+	if len(called) == 0 {
+		panic("no return value specified for Delete")
+	}
+	return called.Error(0)
+}
+func (m *UserMockService) Restore(ctx context.Context, id string) (*model.User, error) {
+	called := m.Called(ctx, id)
+	if len(called) == 0 {
+		panic("no return value specified for Restore")
+	}
+	resultUser := called.Get(0)
+	err := called.Error(1)
+	if resultUser != nil {
+		return resultUser.(*model.User), err
+	}
+	return nil, err
+}
+func (m *UserMockService) Patch(ctx context.Context, id string, version int64, patch service.UserPatch) (*model.User, error) {
+	called := m.Called(ctx, id, version, patch)
+	if len(called) == 0 {
+		panic("no return value specified for Patch")
+	}
+	resultUser := called.Get(0)
+	err := called.Error(1)
+	if resultUser != nil {
+		return resultUser.(*model.User), err
+	}
+	return nil, err
+}
+func (m *UserMockService) Import(ctx context.Context, rows []service.ImportRow, atomic bool) (*service.ImportReport, error) {
+	called := m.Called(ctx, rows, atomic)
+	if len(called) == 0 {
+		panic("no return value specified for Import")
+	}
+	report := called.Get(0)
+	err := called.Error(1)
+	if report != nil {
+		return report.(*service.ImportReport), err
+	}
+	return nil, err
+}
+
+// Export passes the users returned by the expectation to fn, then returns its error.
+func (m *UserMockService) Export(ctx context.Context, f service.UserFilter, fn func(u model.User) error) error {
+	called := m.Called(ctx, f)
+	if len(called) == 0 {
+		panic("no return value specified for Export")
+	}
+	users, _ := called.Get(0).([]model.User)
+	for _, user := range users {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return called.Error(1)
+}
+
+func (m *UserMockService) History(ctx context.Context, id string, params service.HistoryParams) (*service.HistoryPage, error) {
+	called := m.Called(ctx, id, params)
+	if len(called) == 0 {
+		panic("no return value specified for History")
+	}
+	page := called.Get(0)
+	err := called.Error(1)
+	if page != nil {
+		return page.(*service.HistoryPage), err
+	}
+	return nil, err
+}
@@ -0,0 +1,73 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+var errUnsupportedPatch = errors.New("unsupported patch content type")
+
+// patchDocument is the JSON representation patches are applied to; it only exposes the editable fields.
+type patchDocument struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Age       int    `json:"age"`
+}
+
+// newUserPatch builds the patch described by body, either a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902).
+func newUserPatch(contentType string, body []byte) (service.UserPatch, error) {
+	var apply func(doc []byte) ([]byte, error)
+	switch contentType {
+	case mergePatchContentType:
+		if !json.Valid(body) {
+			return nil, errors.New("merge patch is not valid JSON")
+		}
+		apply = func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, body)
+		}
+	case jsonPatchContentType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, err
+		}
+		apply = patch.Apply
+	default:
+		return nil, errUnsupportedPatch
+	}
+	return func(current model.User) (model.User, error) {
+		doc, err := json.Marshal(patchDocument{
+			FirstName: current.FirstName,
+			LastName:  current.LastName,
+			Email:     current.Email,
+			Age:       current.Age,
+		})
+		if err != nil {
+			return model.User{}, err
+		}
+		patched, err := apply(doc)
+		if err != nil {
+			return model.User{}, service.ValidationError{Message: "patch could not be applied", Details: []string{err.Error()}}
+		}
+		var result patchDocument
+		decoder := json.NewDecoder(bytes.NewReader(patched))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&result); err != nil {
+			return model.User{}, service.ValidationError{Message: "patch could not be applied", Details: []string{err.Error()}}
+		}
+		current.FirstName = result.FirstName
+		current.LastName = result.LastName
+		current.Email = result.Email
+		current.Age = result.Age
+		return current, nil
+	}, nil
+}
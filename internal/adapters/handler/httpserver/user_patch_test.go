@@ -0,0 +1,70 @@
+package httpserver
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"testing"
+)
+
+func TestNewUserPatch(t *testing.T) {
+	current := model.User{ID: "id", FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30}
+
+	t.Run("Merge patch", func(t *testing.T) {
+		patch, err := newUserPatch(mergePatchContentType, []byte(`{"email":"new@doe.com","age":31}`))
+		assert.NoError(t, err)
+
+		result, err := patch(current)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.User{ID: "id", FirstName: "John", LastName: "Doe", Email: "new@doe.com", Age: 31}, result)
+	})
+	t.Run("Merge patch removing a field", func(t *testing.T) {
+		patch, _ := newUserPatch(mergePatchContentType, []byte(`{"lastName":null}`))
+
+		result, err := patch(current)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.LastName)
+	})
+	t.Run("JSON patch", func(t *testing.T) {
+		patch, err := newUserPatch(jsonPatchContentType, []byte(`[
+			{"op":"test","path":"/firstName","value":"John"},
+			{"op":"replace","path":"/firstName","value":"Johnny"}
+		]`))
+		assert.NoError(t, err)
+
+		result, err := patch(current)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Johnny", result.FirstName)
+		assert.Equal(t, current.Email, result.Email)
+	})
+	t.Run("JSON patch failing test operation", func(t *testing.T) {
+		patch, _ := newUserPatch(jsonPatchContentType, []byte(`[{"op":"test","path":"/firstName","value":"Jane"}]`))
+
+		_, err := patch(current)
+
+		var validationErr service.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+	t.Run("Patch adding unknown fields", func(t *testing.T) {
+		patch, _ := newUserPatch(mergePatchContentType, []byte(`{"id":"other"}`))
+
+		_, err := patch(current)
+
+		var validationErr service.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+	t.Run("Malformed patch documents", func(t *testing.T) {
+		_, err := newUserPatch(mergePatchContentType, []byte(`{`))
+		assert.Error(t, err)
+		_, err = newUserPatch(jsonPatchContentType, []byte(`{"op":"replace"}`))
+		assert.Error(t, err)
+	})
+	t.Run("Unsupported content type", func(t *testing.T) {
+		_, err := newUserPatch("application/json", []byte(`{}`))
+		assert.ErrorIs(t, err, errUnsupportedPatch)
+	})
+}
@@ -0,0 +1,214 @@
+package httpserver
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+)
+
+// WebhookHandler serves the webhook subscriptions and the inspection of their deliveries. Every route
+// requires the webhooks:manage permission: webhooks receive every user change.
+type WebhookHandler struct {
+	service WebhookService
+}
+
+func NewWebhookHandler(s WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: s}
+}
+
+type WebhookService interface {
+	Register(ctx context.Context, w model.Webhook) (*model.Webhook, error)
+	Webhooks(ctx context.Context) ([]model.Webhook, error)
+	Webhook(ctx context.Context, id string) (*model.Webhook, error)
+	Unregister(ctx context.Context, id string) error
+	Deliveries(ctx context.Context, webhookID string, params service.DeliveryParams) ([]model.Delivery, error)
+	Delivery(ctx context.Context, webhookID string, id string) (*model.Delivery, error)
+	Redeliver(ctx context.Context, webhookID string, id string) (*model.Delivery, error)
+}
+
+func (h *WebhookHandler) SetupRoutes(r *Router) {
+	webhooks := r.Group("/webhooks", r.require(service.PermWebhooksManage))
+	webhooks.Handle(http.MethodPost, "", h.Register)
+	webhooks.Handle(http.MethodGet, "", h.List)
+	webhooks.Handle(http.MethodGet, "/:id", h.Find)
+	webhooks.Handle(http.MethodDelete, "/:id", h.Unregister)
+	webhooks.Handle(http.MethodGet, "/:id/deliveries", h.Deliveries)
+	webhooks.Handle(http.MethodGet, "/:id/deliveries/:deliveryId", h.Delivery)
+	webhooks.Handle(http.MethodPost, "/:id/deliveries/:deliveryId/redeliver", h.Redeliver)
+}
+
+// Register godoc
+// @Summary Register a webhook
+// @Description Subscribe a URL to user events (UserCreated, UserUpdated, UserDeleted). Every delivery is a JSON POST
+// @Description of the event signed in the X-Webhook-Signature header: sha256= followed by the hex HMAC-SHA256,
+// @Description keyed with the secret, of the X-Webhook-Timestamp header, a dot and the body.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string false "Admin key, unless the caller has the webhooks:manage permission"
+// @Param Webhook body dto.WebhookInput true "Webhook"
+// @Success 201 {object} model.Webhook
+// @Failure 400 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks [post]
+func (h *WebhookHandler) Register(ctx *gin.Context) {
+	input := dto.WebhookInput{}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeMalformedRequest, "invalid request body", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	webhook, err := h.service.Register(ctx, model.Webhook{URL: input.URL, Events: input.Events, Secret: input.Secret})
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, webhook)
+}
+
+// List godoc
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce json
+// @Param X-Admin-Key header string false "Admin key, unless the caller has the webhooks:manage permission"
+// @Success 200 {object} dto.WebhookListResponse
+// @Failure 403 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks [get]
+func (h *WebhookHandler) List(ctx *gin.Context) {
+	webhooks, err := h.service.Webhooks(ctx)
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.WebhookListResponse{Data: webhooks})
+}
+
+// Find godoc
+// @Summary Find webhook by ID
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "ID"
+// @Param X-Admin-Key header string false "Admin key, unless the caller has the webhooks:manage permission"
+// @Success 200 {object} model.Webhook
+// @Failure 403 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) Find(ctx *gin.Context) {
+	webhook, err := h.service.Webhook(ctx, ctx.Param("id"))
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// Unregister godoc
+// @Summary Delete a webhook
+// @Description Delete the webhook and its deliveries, pending ones are not attempted anymore
+// @Tags webhooks
+// @Param id path string true "ID"
+// @Param X-Admin-Key header string false "Admin key, unless the caller has the webhooks:manage permission"
+// @Success 204
+// @Failure 403 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) Unregister(ctx *gin.Context) {
+	if err := h.service.Unregister(ctx, ctx.Param("id")); err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Deliveries godoc
+// @Summary List webhook deliveries
+// @Description List the latest deliveries of the webhook with their attempts, newest first.
+// @Description status=dead lists the dead letters: deliveries whose retries are exhausted.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "ID"
+// @Param status query string false "Delivery status (pending, succeeded, dead)"
+// @Param limit query int false "Number of deliveries, up to 100"
+// @Param X-Admin-Key header string false "Admin key, unless the caller has the webhooks:manage permission"
+// @Success 200 {object} dto.DeliveryListResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 403 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(ctx *gin.Context) {
+	query := dto.DeliveryQuery{}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		abortWithProblem(ctx, http.StatusBadRequest, CodeInvalidParameters, "invalid delivery parameters", dto.ProblemError{Message: err.Error()})
+		return
+	}
+	deliveries, err := h.service.Deliveries(ctx, ctx.Param("id"), service.DeliveryParams{Status: query.Status, Limit: query.Limit})
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, dto.DeliveryListResponse{Data: deliveries})
+}
+
+// Delivery godoc
+// @Summary Find webhook delivery by ID
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Param X-Admin-Key header string false "Admin key, unless the caller has the webhooks:manage permission"
+// @Success 200 {object} model.Delivery
+// @Failure 403 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries/{deliveryId} [get]
+func (h *WebhookHandler) Delivery(ctx *gin.Context) {
+	delivery, err := h.service.Delivery(ctx, ctx.Param("id"), ctx.Param("deliveryId"))
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description Queue the delivery, typically a dead letter, for an immediate attempt
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Param X-Admin-Key header string false "Admin key, unless the caller has the webhooks:manage permission"
+// @Success 202 {object} model.Delivery
+// @Failure 403 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 401 {object} dto.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *WebhookHandler) Redeliver(ctx *gin.Context) {
+	delivery, err := h.service.Redeliver(ctx, ctx.Param("id"), ctx.Param("deliveryId"))
+	if err != nil {
+		checkErr(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, delivery)
+}
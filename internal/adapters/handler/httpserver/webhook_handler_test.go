@@ -0,0 +1,88 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/handler/dto"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	mockWebhookService := &WebhookMockService{}
+	policy, _ := service.NewPolicy(nil)
+	router := newRouter(&config.HTTP{GinMode: gin.TestMode}, []HttpHandlers{NewWebhookHandler(mockWebhookService)},
+		WithAuthenticator(adminAuthenticator(t)), WithAuthorizer(policy))
+	serve := func(method string, target string, body io.Reader) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, body)
+		request.Header.Set(auth.AdminKeyHeader, "secret")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	webhook := &model.Webhook{ID: "w1", URL: "https://partner.example.com", Events: []string{model.EventUserCreated}, Secret: "0123456789abcdef"}
+	delivery := &model.Delivery{ID: "d1", WebhookID: "w1", Status: model.DeliveryDead, Attempts: []model.DeliveryAttempt{{StatusCode: 500, Error: "webhook answered 500"}}}
+
+	t.Run("Register a webhook without returning its secret", func(t *testing.T) {
+		input := model.Webhook{URL: webhook.URL, Events: webhook.Events, Secret: webhook.Secret}
+		mockWebhookService.On("Register", mock.Anything, input).Return(webhook, nil).Once()
+		recorder := serve(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"https://partner.example.com","events":["UserCreated"],"secret":"0123456789abcdef"}`))
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"id":"w1"`)
+		assert.NotContains(t, recorder.Body.String(), webhook.Secret)
+	})
+	t.Run("Invalid webhook", func(t *testing.T) {
+		invalid := service.ValidationError{Message: "invalid webhook", Details: []string{"url must be an absolute http or https URL"}}
+		mockWebhookService.On("Register", mock.Anything, model.Webhook{}).Return(nil, invalid).Once()
+		recorder := serve(http.MethodPost, "/webhooks", strings.NewReader(`{}`))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), CodeInvalidParameters)
+	})
+	t.Run("List the dead letters", func(t *testing.T) {
+		mockWebhookService.On("Deliveries", mock.Anything, "w1", service.DeliveryParams{Status: model.DeliveryDead, Limit: 5}).
+			Return([]model.Delivery{*delivery}, nil).Once()
+		recorder := serve(http.MethodGet, "/webhooks/w1/deliveries?status=dead&limit=5", nil)
+
+		var response dto.DeliveryListResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, []model.Delivery{*delivery}, response.Data)
+	})
+	t.Run("Redeliver", func(t *testing.T) {
+		mockWebhookService.On("Redeliver", mock.Anything, "w1", "d1").Return(delivery, nil).Once()
+		recorder := serve(http.MethodPost, "/webhooks/w1/deliveries/d1/redeliver", nil)
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+	})
+	t.Run("Unknown webhook and delivery", func(t *testing.T) {
+		mockWebhookService.On("Unregister", mock.Anything, "unknown").Return(service.ErrWebhookNotFound).Once()
+		mockWebhookService.On("Delivery", mock.Anything, "w1", "unknown").Return(nil, service.ErrDeliveryNotFound).Once()
+
+		recorder := serve(http.MethodDelete, "/webhooks/unknown", nil)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), CodeWebhookNotFound)
+		recorder = serve(http.MethodGet, "/webhooks/w1/deliveries/unknown", nil)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), CodeDeliveryNotFound)
+	})
+	t.Run("Webhooks require the webhooks:manage permission", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/webhooks", nil))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), CodeForbidden)
+		assert.Contains(t, recorder.Body.String(), "lacks the webhooks:manage permission")
+	})
+	mockWebhookService.AssertExpectations(t)
+}
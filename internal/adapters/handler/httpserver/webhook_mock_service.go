@@ -0,0 +1,64 @@
+package httpserver
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+)
+
+type WebhookMockService struct {
+	mock.Mock
+}
+
+func (m *WebhookMockService) Register(ctx context.Context, w model.Webhook) (*model.Webhook, error) {
+	called := m.Called(ctx, w)
+	if webhook := called.Get(0); webhook != nil {
+		return webhook.(*model.Webhook), called.Error(1)
+	}
+	return nil, called.Error(1)
+}
+
+func (m *WebhookMockService) Webhooks(ctx context.Context) ([]model.Webhook, error) {
+	called := m.Called(ctx)
+	if webhooks := called.Get(0); webhooks != nil {
+		return webhooks.([]model.Webhook), called.Error(1)
+	}
+	return nil, called.Error(1)
+}
+
+func (m *WebhookMockService) Webhook(ctx context.Context, id string) (*model.Webhook, error) {
+	called := m.Called(ctx, id)
+	if webhook := called.Get(0); webhook != nil {
+		return webhook.(*model.Webhook), called.Error(1)
+	}
+	return nil, called.Error(1)
+}
+
+func (m *WebhookMockService) Unregister(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *WebhookMockService) Deliveries(ctx context.Context, webhookID string, params service.DeliveryParams) ([]model.Delivery, error) {
+	called := m.Called(ctx, webhookID, params)
+	if deliveries := called.Get(0); deliveries != nil {
+		return deliveries.([]model.Delivery), called.Error(1)
+	}
+	return nil, called.Error(1)
+}
+
+func (m *WebhookMockService) Delivery(ctx context.Context, webhookID string, id string) (*model.Delivery, error) {
+	called := m.Called(ctx, webhookID, id)
+	if delivery := called.Get(0); delivery != nil {
+		return delivery.(*model.Delivery), called.Error(1)
+	}
+	return nil, called.Error(1)
+}
+
+func (m *WebhookMockService) Redeliver(ctx context.Context, webhookID string, id string) (*model.Delivery, error) {
+	called := m.Called(ctx, webhookID, id)
+	if delivery := called.Get(0); delivery != nil {
+		return delivery.(*model.Delivery), called.Error(1)
+	}
+	return nil, called.Error(1)
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(config.Log{Level: "warn"}, &out)
+	if err != nil {
+		t.Fatalf("error building logger: %v", err)
+	}
+	ctx := service.ContextWithRequestID(context.Background(), "req-1")
+
+	t.Run("Add the request ID of the context", func(t *testing.T) {
+		out.Reset()
+		logger.With("component", "repository").ErrorContext(ctx, "failed to read user", "error", "timeout")
+
+		var record map[string]any
+		if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+			t.Fatalf("expected a JSON record, result: %q", out.String())
+		}
+		if record[RequestIDKey] != "req-1" || record["component"] != "repository" || record["msg"] != "failed to read user" {
+			t.Errorf("expected a record of req-1, result: %v", record)
+		}
+	})
+	t.Run("Add the span of the context", func(t *testing.T) {
+		out.Reset()
+		traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		ctx := trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+		logger.WarnContext(ctx, "user not found")
+
+		var record map[string]any
+		_ = json.Unmarshal(out.Bytes(), &record)
+		if record[TraceIDKey] != traceID.String() || record[SpanIDKey] != spanID.String() || record[RequestIDKey] != "req-1" {
+			t.Errorf("expected a record of the span, result: %v", record)
+		}
+	})
+	t.Run("Records without request have no request ID", func(t *testing.T) {
+		out.Reset()
+		logger.Error("failed to connect")
+
+		if strings.Contains(out.String(), RequestIDKey) {
+			t.Errorf("expected no request ID, result: %q", out.String())
+		}
+	})
+	t.Run("Drop records below the level", func(t *testing.T) {
+		out.Reset()
+		logger.InfoContext(ctx, "listening")
+
+		if out.Len() != 0 {
+			t.Errorf("expected no record, result: %q", out.String())
+		}
+	})
+	t.Run("Should return error with invalid config", func(t *testing.T) {
+		for _, cfg := range []config.Log{{Level: "verbose"}, {Format: "xml"}} {
+			if _, err := New(cfg, &out); err == nil {
+				t.Errorf("%+v: expected an error", cfg)
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+// Package metrics collects the application metrics and exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics holds the collectors of the application, registered in their own registry with the Go runtime and
+// process collectors.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	outcomes        *prometheus.CounterVec
+	mongoCommands   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve HTTP requests, by method, route template and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		outcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "user_service_operations_total",
+			Help: "User service operations, by operation and outcome.",
+		}, []string{"operation", "outcome"}),
+		mongoCommands: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mongodb_command_duration_seconds",
+			Help:    "Time taken by the MongoDB commands, by command and outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"command", "outcome"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.outcomes, m.mongoCommands,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest counts a served request. Requests matching no route share the empty route.
+func (m *Metrics) ObserveRequest(method string, route string, status int, elapsed time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) CountOutcome(operation string, outcome string) {
+	m.outcomes.WithLabelValues(operation, outcome).Inc()
+}
+
+// CommandMonitor observes the duration of the commands sent by a MongoDB client.
+func (m *Metrics) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			m.mongoCommands.WithLabelValues(e.CommandName, "succeeded").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			m.mongoCommands.WithLabelValues(e.CommandName, "failed").Observe(e.Duration.Seconds())
+		},
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/event"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.ObserveRequest(http.MethodGet, "/users/:id", http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest(http.MethodGet, "/users/:id", http.StatusOK, 30*time.Millisecond)
+	m.CountOutcome("save", "username_taken")
+	monitor := m.CommandMonitor()
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", Duration: time.Millisecond}})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", Duration: time.Millisecond}})
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`http_request_duration_seconds_sum{method="GET",route="/users/:id",status="200"} 0.05`,
+		`user_service_operations_total{operation="save",outcome="username_taken"} 1`,
+		`mongodb_command_duration_seconds_count{command="find",outcome="succeeded"} 1`,
+		`mongodb_command_duration_seconds_count{command="insert",outcome="failed"} 1`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected the metrics to contain %q, result:\n%s", line, body)
+		}
+	}
+}
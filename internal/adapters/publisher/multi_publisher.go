@@ -0,0 +1,20 @@
+package publisher
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+)
+
+// MultiPublisher publishes every event to each of its publishers in turn, stopping at the first failure.
+// Since the event is then published again, publishers preceding the failing one may receive it twice.
+type MultiPublisher []service.Publisher
+
+func (p MultiPublisher) Publish(ctx context.Context, e model.Event) error {
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
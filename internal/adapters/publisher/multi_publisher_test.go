@@ -0,0 +1,22 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"testing"
+)
+
+func TestMultiPublisher(t *testing.T) {
+	first, second := &service.MockPublisher{}, &service.MockPublisher{}
+	publisher := MultiPublisher{first, second}
+
+	if err := publisher.Publish(context.Background(), event); err != nil || len(first.Events) != 1 || len(second.Events) != 1 {
+		t.Fatalf("expected the event in both publishers, result: %v, %v, %v", first.Events, second.Events, err)
+	}
+	errUnavailable := errors.New("unavailable")
+	first.Err = errUnavailable
+	if err := publisher.Publish(context.Background(), event); !errors.Is(err, errUnavailable) || len(second.Events) != 1 {
+		t.Errorf("expected: %v and no event in the second publisher, result: %v, %v", errUnavailable, err, second.Events)
+	}
+}
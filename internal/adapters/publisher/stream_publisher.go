@@ -0,0 +1,45 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StreamPublisher writes every event as a line of JSON, e.g. to a file. It is safe for concurrent use.
+type StreamPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewStreamPublisher(w io.Writer) *StreamPublisher {
+	return &StreamPublisher{w: w}
+}
+
+// NewFilePublisher appends events to the file at path, created with its directory when missing.
+// The file should be closed once the publisher is no longer used.
+func NewFilePublisher(path string) (*StreamPublisher, *os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewStreamPublisher(file), file, nil
+}
+
+func (p *StreamPublisher) Publish(_ context.Context, e model.Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
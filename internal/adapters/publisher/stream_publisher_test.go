@@ -0,0 +1,59 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var event = model.Event{
+	ID:         "1",
+	Type:       model.EventUserCreated,
+	UserID:     "42",
+	OccurredAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	User:       model.User{ID: "42", FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30, Version: 1},
+}
+
+func TestStreamPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewStreamPublisher(&buf)
+
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), event); err != nil {
+			t.Fatalf("error publishing event: %v", err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected: 2 lines, result: %q", buf.String())
+	}
+	var result model.Event
+	if err := json.Unmarshal([]byte(lines[0]), &result); err != nil || result != event {
+		t.Errorf("expected: %v, result: %v, %v", event, result, err)
+	}
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "users.ndjson")
+	for i := 0; i < 2; i++ {
+		publisher, file, err := NewFilePublisher(path)
+		if err != nil {
+			t.Fatalf("error opening file: %v", err)
+		}
+		if err := publisher.Publish(context.Background(), event); err != nil {
+			t.Fatalf("error publishing event: %v", err)
+		}
+		_ = file.Close()
+	}
+
+	content, _ := os.ReadFile(path)
+	if lines := strings.Count(string(content), "\n"); lines != 2 {
+		t.Errorf("expected: events appended to the file, result: %q", content)
+	}
+}
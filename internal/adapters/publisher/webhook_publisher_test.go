@@ -0,0 +1,46 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookPublisher(t *testing.T) {
+	var received []model.Event
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e model.Event
+		if r.Header.Get(EventTypeHeader) == e.Type || json.NewDecoder(r.Body).Decode(&e) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, e)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	publisher := NewWebhookPublisher(server.URL, nil)
+
+	t.Run("Deliver the event", func(t *testing.T) {
+		if err := publisher.Publish(context.Background(), event); err != nil {
+			t.Fatalf("error publishing event: %v", err)
+		}
+		if len(received) != 1 || received[0] != event {
+			t.Errorf("expected: %v, result: %v", event, received)
+		}
+	})
+	t.Run("Fail on error responses", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		if err := publisher.Publish(context.Background(), event); err == nil {
+			t.Errorf("expected an error for a %d response", status)
+		}
+	})
+	t.Run("Fail when the endpoint is unreachable", func(t *testing.T) {
+		if err := NewWebhookPublisher("http://127.0.0.1:1", nil).Publish(context.Background(), event); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
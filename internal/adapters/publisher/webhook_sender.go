@@ -0,0 +1,63 @@
+package publisher
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of signed webhook deliveries.
+const (
+	WebhookIDHeader  = "X-Webhook-ID"
+	DeliveryIDHeader = "X-Delivery-ID"
+	TimestampHeader  = "X-Webhook-Timestamp"
+	SignatureHeader  = "X-Webhook-Signature"
+	signaturePrefix  = "sha256="
+)
+
+// SignedWebhookSender delivers events to webhooks as JSON POST requests signed with the webhook secret.
+// Receivers check the X-Webhook-Signature header against Sign(secret, X-Webhook-Timestamp, body) and
+// reject old timestamps to prevent replays.
+type SignedWebhookSender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSignedWebhookSender sends requests with client, an http.Client with a 10s timeout when nil.
+func NewSignedWebhookSender(client *http.Client) *SignedWebhookSender {
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	return &SignedWebhookSender{client: client, now: time.Now}
+}
+
+func (s *SignedWebhookSender) Send(ctx context.Context, w model.Webhook, d model.Delivery) (int, error) {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	return post(ctx, s.client, w.URL, body, http.Header{
+		EventIDHeader:    {d.Event.ID},
+		EventTypeHeader:  {d.Event.Type},
+		WebhookIDHeader:  {w.ID},
+		DeliveryIDHeader: {d.ID},
+		TimestampHeader:  {timestamp},
+		SignatureHeader:  {Sign(w.Secret, timestamp, body)},
+	})
+}
+
+// Sign returns the signature of a delivery: "sha256=" followed by the hex HMAC-SHA256, keyed with the
+// webhook secret, of the timestamp, a dot and the request body.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
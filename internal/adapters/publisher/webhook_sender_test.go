@@ -0,0 +1,75 @@
+package publisher
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// computed with: printf '1700000000.{}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	expected := "sha256=e4f8e2ecae2295b2ddb2f0b5584c8275e226c0ebe9b3b819e70156bb67122e3e"
+	if result := Sign("0123456789abcdef", "1700000000", []byte("{}")); result != expected {
+		t.Errorf("expected: %s, result: %s", expected, result)
+	}
+}
+
+func TestSignedWebhookSender(t *testing.T) {
+	webhook := model.Webhook{ID: "w1", Events: []string{model.EventUserCreated}, Secret: "0123456789abcdef"}
+	delivery := model.Delivery{ID: "d1", WebhookID: webhook.ID, Event: event}
+	status := http.StatusNoContent
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	webhook.URL = server.URL
+	sender := NewSignedWebhookSender(nil)
+	sender.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	t.Run("Send a signed delivery", func(t *testing.T) {
+		statusCode, err := sender.Send(context.Background(), webhook, delivery)
+		if err != nil || statusCode != status {
+			t.Fatalf("expected: %d, result: %d, %v", status, statusCode, err)
+		}
+		var result model.Event
+		if err := json.Unmarshal(body, &result); err != nil || result != event {
+			t.Errorf("expected: %v, result: %v, %v", event, result, err)
+		}
+		headers := map[string]string{
+			WebhookIDHeader:  webhook.ID,
+			DeliveryIDHeader: delivery.ID,
+			EventIDHeader:    event.ID,
+			EventTypeHeader:  event.Type,
+			TimestampHeader:  "1700000000",
+			"Content-Type":   "application/json",
+		}
+		for header, expected := range headers {
+			if value := received.Header.Get(header); value != expected {
+				t.Errorf("%s: expected: %s, result: %s", header, expected, value)
+			}
+		}
+		signature := received.Header.Get(SignatureHeader)
+		if !hmac.Equal([]byte(signature), []byte(Sign(webhook.Secret, "1700000000", body))) {
+			t.Errorf("invalid signature %s", signature)
+		}
+		if signature == Sign("another secret!!", "1700000000", body) {
+			t.Errorf("signatures should depend on the secret")
+		}
+	})
+	t.Run("Report the status of failed deliveries", func(t *testing.T) {
+		status = http.StatusGone
+		if statusCode, err := sender.Send(context.Background(), webhook, delivery); err == nil || statusCode != http.StatusGone {
+			t.Errorf("expected an error and %d, result: %d, %v", http.StatusGone, statusCode, err)
+		}
+	})
+}
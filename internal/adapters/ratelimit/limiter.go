@@ -0,0 +1,222 @@
+// Package ratelimit limits the requests of each client with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client identification modes of config.RateLimit.KeyBy.
+const (
+	KeyByPrincipal = "principal"
+	KeyByAPIKey    = "api-key"
+	KeyByIP        = "ip"
+)
+
+// defaultRoute is the bucket of the routes without rule.
+const defaultRoute = "*"
+
+// Limit is a token bucket: it holds up to Burst tokens, refilled at Rate tokens per second. Each request takes a token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// wait is how long the bucket takes to earn tokens.
+func (l Limit) wait(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.Rate * float64(time.Second)))
+}
+
+// Decision is the outcome of a request against its bucket.
+type Decision struct {
+	Allowed bool
+	// Limit is the size of the bucket and Remaining the tokens left in it.
+	Limit     int
+	Remaining int
+	// Reset is how long the bucket takes to be full again.
+	Reset time.Duration
+	// RetryAfter is how long a rejected request should wait for a token.
+	RetryAfter time.Duration
+}
+
+// Store holds the buckets. Stores shared by several instances of the API, such as Redis, must take tokens atomically.
+type Store interface {
+	// Take takes a token from the bucket with the given key, created full when missing.
+	Take(ctx context.Context, key string, l Limit) (Decision, error)
+}
+
+// Limiter applies the rate limits of config.RateLimit to the requests of each client.
+type Limiter struct {
+	store  Store
+	keyBy  string
+	ip     *Limit
+	def    *Limit
+	routes map[string]Limit
+	// proxies are the trusted proxies, whose X-Forwarded-For header identifies the client IP.
+	proxies []*net.IPNet
+}
+
+func New(cfg config.RateLimit, store Store) (*Limiter, error) {
+	l := &Limiter{store: store, keyBy: cfg.KeyBy, routes: map[string]Limit{}}
+	switch l.keyBy {
+	case "":
+		l.keyBy = KeyByPrincipal
+	case KeyByPrincipal, KeyByAPIKey, KeyByIP:
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q", cfg.KeyBy)
+	}
+	if cfg.IP != nil {
+		limit, err := newLimit(*cfg.IP)
+		if err != nil {
+			return nil, fmt.Errorf("ip rate limit: %w", err)
+		}
+		l.ip = &limit
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			return nil, err
+		}
+		l.proxies = append(l.proxies, network)
+	}
+	if cfg.Default != nil {
+		limit, err := newLimit(*cfg.Default)
+		if err != nil {
+			return nil, fmt.Errorf("default rate limit: %w", err)
+		}
+		l.def = &limit
+	}
+	for route, rule := range cfg.Routes {
+		limit, err := newLimit(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit of %s: %w", route, err)
+		}
+		l.routes[route] = limit
+	}
+	return l, nil
+}
+
+func newLimit(rule config.RateLimitRule) (Limit, error) {
+	if rule.Requests <= 0 || rule.Per <= 0 || rule.Burst < 0 {
+		return Limit{}, errors.New("requests and per must be positive, burst must not be negative")
+	}
+	burst := rule.Burst
+	if burst == 0 {
+		burst = rule.Requests
+	}
+	return Limit{Rate: float64(rule.Requests) / rule.Per.Seconds(), Burst: burst}, nil
+}
+
+// parseNetwork parses a trusted proxy, given by IP or CIDR.
+func parseNetwork(proxy string) (*net.IPNet, error) {
+	if !strings.Contains(proxy, "/") {
+		ip := net.ParseIP(proxy)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		bits := 8 * len(ip.To16())
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+	}
+	return network, nil
+}
+
+// Enabled reports whether any request is limited.
+func (l *Limiter) Enabled() bool {
+	return l.ip != nil || l.def != nil || len(l.routes) > 0
+}
+
+// AllowIP takes a token from the bucket of the IP of r, shared by every route. It is called before r is
+// authenticated, so that the requests with invalid credentials are limited too. It returns nil without IP limit.
+func (l *Limiter) AllowIP(r *http.Request, _ string) (*Decision, error) {
+	if l.ip == nil {
+		return nil, nil
+	}
+	decision, err := l.store.Take(r.Context(), "ip:"+l.clientIP(r), *l.ip)
+	if err != nil {
+		return nil, err
+	}
+	return &decision, nil
+}
+
+// Allow takes a token from the bucket of the client of r for route, its method and path pattern. It is called
+// once r is authenticated. It returns nil when the route is not limited.
+func (l *Limiter) Allow(r *http.Request, route string) (*Decision, error) {
+	limit, ok := l.routes[route]
+	if !ok {
+		if l.def == nil {
+			return nil, nil
+		}
+		limit, route = *l.def, defaultRoute
+	}
+	decision, err := l.store.Take(r.Context(), l.client(r)+" "+route, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &decision, nil
+}
+
+// client identifies the client of r by keyBy: its principal, or the API key it was authenticated with, hashed to
+// keep it out of shared stores. Anonymous clients, and clients without API key for KeyByAPIKey, are identified by IP.
+func (l *Limiter) client(r *http.Request) string {
+	principal, ok := service.PrincipalFrom(r.Context())
+	switch {
+	case ok && l.keyBy == KeyByPrincipal:
+		return "principal:" + principal.Method + ":" + principal.Subject
+	case ok && l.keyBy == KeyByAPIKey && principal.Method == service.AuthAPIKey:
+		hash := sha256.Sum256([]byte(r.Header.Get(auth.APIKeyHeader)))
+		return "api-key:" + hex.EncodeToString(hash[:])
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// clientIP is the IP the request of r comes from. When r comes from a trusted proxy, it is the last IP of its
+// X-Forwarded-For header not from a trusted proxy: the entries before it could be forged by the client.
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !l.trusted(ip) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !l.trusted(hop) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func (l *Limiter) trusted(ip net.IP) bool {
+	for _, proxy := range l.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/auth"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	// 1 request every 2 seconds, in bursts of 3
+	limit := Limit{Rate: 0.5, Burst: 3}
+
+	t.Run("Allow bursts, then reject until a token is earned", func(t *testing.T) {
+		for remaining := 2; remaining >= 0; remaining-- {
+			decision, err := store.Take(ctx, "a", limit)
+			if err != nil || !decision.Allowed || decision.Remaining != remaining || decision.Limit != 3 {
+				t.Fatalf("expected an allowed request with %d remaining, result: %+v, %v", remaining, decision, err)
+			}
+		}
+		decision, _ := store.Take(ctx, "a", limit)
+		if decision.Allowed || decision.RetryAfter != 2*time.Second || decision.Reset != 6*time.Second {
+			t.Errorf("expected a rejected request to retry in 2s, result: %+v", decision)
+		}
+		if decision, _ := store.Take(ctx, "b", limit); !decision.Allowed {
+			t.Errorf("buckets should be independent, result: %+v", decision)
+		}
+		now = now.Add(time.Second)
+		if decision, _ := store.Take(ctx, "a", limit); decision.Allowed || decision.RetryAfter != time.Second {
+			t.Errorf("expected a rejected request to retry in 1s, result: %+v", decision)
+		}
+		now = now.Add(time.Second)
+		if decision, _ := store.Take(ctx, "a", limit); !decision.Allowed || decision.Remaining != 0 {
+			t.Errorf("expected an allowed request, result: %+v", decision)
+		}
+	})
+	t.Run("Drop the full buckets", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		_, _ = store.Take(ctx, "c", limit)
+		if len(store.buckets) != 1 {
+			t.Errorf("expected only the bucket just used, result: %d buckets", len(store.buckets))
+		}
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	request := func(remoteAddr string, apiKey string, subject string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/users", nil)
+		r.RemoteAddr = remoteAddr
+		principal := service.Principal{Subject: subject, Method: service.AuthJWT}
+		if apiKey != "" {
+			r.Header.Set(auth.APIKeyHeader, apiKey)
+			principal.Method = service.AuthAPIKey
+		}
+		if subject != "" {
+			r = r.WithContext(service.ContextWithPrincipal(r.Context(), principal))
+		}
+		return r
+	}
+	newLimiter := func(keyBy string) *Limiter {
+		limiter, err := New(config.RateLimit{
+			KeyBy:   keyBy,
+			Default: &config.RateLimitRule{Requests: 10, Per: time.Second},
+			Routes:  map[string]config.RateLimitRule{"POST /users": {Requests: 1, Per: time.Minute}},
+		}, NewMemoryStore())
+		if err != nil {
+			t.Fatalf("error building limiter: %v", err)
+		}
+		return limiter
+	}
+	decide := func(allow func(*http.Request, string) (*Decision, error), r *http.Request, route string) bool {
+		t.Helper()
+		decision, err := allow(r, route)
+		if err != nil || decision == nil {
+			t.Fatalf("expected a decision, result: %v", err)
+		}
+		return decision.Allowed
+	}
+	allowed := func(l *Limiter, r *http.Request, route string) bool {
+		t.Helper()
+		return decide(l.Allow, r, route)
+	}
+	allowedIP := func(l *Limiter, r *http.Request, route string) bool {
+		t.Helper()
+		return decide(l.AllowIP, r, route)
+	}
+
+	t.Run("Limit each route of each client", func(t *testing.T) {
+		limiter := newLimiter("")
+		alice, bob := request("10.0.0.1:1234", "", "alice"), request("10.0.0.1:1234", "", "bob")
+		if !allowed(limiter, alice, "POST /users") || allowed(limiter, alice, "POST /users") {
+			t.Errorf("expected a single request of alice to POST /users")
+		}
+		if !allowed(limiter, bob, "POST /users") || !allowed(limiter, alice, "GET /users") {
+			t.Errorf("expected other clients and routes to be allowed")
+		}
+	})
+	t.Run("Identify clients by verified API key or IP", func(t *testing.T) {
+		limiter := newLimiter(KeyByAPIKey)
+		if !allowed(limiter, request("10.0.0.1:1234", "k1", "reporting"), "POST /users") || allowed(limiter, request("10.0.0.2:1234", "k1", "reporting"), "POST /users") {
+			t.Errorf("expected requests with the same API key to share their bucket")
+		}
+		if !allowed(limiter, request("10.0.0.3:1234", "k2", ""), "POST /users") || allowed(limiter, request("10.0.0.3:5678", "", "alice"), "POST /users") {
+			t.Errorf("expected requests without verified API key to be limited by IP")
+		}
+		limiter = newLimiter(KeyByIP)
+		if !allowed(limiter, request("10.0.0.1:1234", "", "alice"), "POST /users") || allowed(limiter, request("10.0.0.1:5678", "", "bob"), "POST /users") {
+			t.Errorf("expected requests from the same IP to share their bucket")
+		}
+	})
+	t.Run("Give API keys from the same IP separate buckets", func(t *testing.T) {
+		limiter, _ := New(config.RateLimit{
+			KeyBy:  KeyByAPIKey,
+			IP:     &config.RateLimitRule{Requests: 100, Per: time.Second},
+			Routes: map[string]config.RateLimitRule{"POST /users": {Requests: 1, Per: time.Minute}},
+		}, NewMemoryStore())
+		for _, key := range []string{"k1", "k2"} {
+			r := request("10.0.0.1:1234", key, "integration-"+key)
+			if !allowedIP(limiter, r, "POST /users") || !allowed(limiter, r, "POST /users") {
+				t.Errorf("expected the first request with %s to be allowed", key)
+			}
+		}
+	})
+	t.Run("Limit anonymous clients by IP", func(t *testing.T) {
+		limiter := newLimiter("")
+		if !allowed(limiter, request("10.0.0.1:1234", "", ""), "POST /users") || allowed(limiter, request("10.0.0.1:5678", "", ""), "POST /users") {
+			t.Errorf("expected a single request from the IP to POST /users")
+		}
+	})
+	t.Run("Limit each IP on every route before authentication", func(t *testing.T) {
+		limiter, _ := New(config.RateLimit{IP: &config.RateLimitRule{Requests: 2, Per: time.Minute}}, NewMemoryStore())
+		r := request("10.0.0.1:1234", "", "")
+		if !allowedIP(limiter, r, "POST /users") || !allowedIP(limiter, r, "GET /users") || allowedIP(limiter, r, "GET /users/:id") {
+			t.Errorf("expected two requests from the IP")
+		}
+		if !allowedIP(limiter, request("10.0.0.2:1234", "", ""), "POST /users") {
+			t.Errorf("expected other IPs to be allowed")
+		}
+		if decision, err := limiter.Allow(r, "POST /users"); decision != nil || err != nil {
+			t.Errorf("expected no limit by route, result: %+v, %v", decision, err)
+		}
+		if decision, err := newLimiter("").AllowIP(r, "POST /users"); decision != nil || err != nil {
+			t.Errorf("expected no limit by IP without rule, result: %+v, %v", decision, err)
+		}
+	})
+	t.Run("Resolve the client IP behind trusted proxies", func(t *testing.T) {
+		limiter, _ := New(config.RateLimit{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}, NewMemoryStore())
+		for remoteAddr, expected := range map[string]map[string]string{
+			"10.0.0.1:1234": {
+				"":                                   "10.0.0.1",
+				"203.0.113.7":                        "203.0.113.7",
+				"198.51.100.1, 203.0.113.7":          "203.0.113.7",
+				"203.0.113.7, 192.168.1.1, 10.0.0.2": "203.0.113.7",
+				"forged, 10.0.0.2":                   "10.0.0.2",
+			},
+			"203.0.113.9:1234": {"203.0.113.7": "203.0.113.9"},
+		} {
+			for forwardedFor, ip := range expected {
+				r := request(remoteAddr, "", "")
+				if forwardedFor != "" {
+					r.Header.Set("X-Forwarded-For", forwardedFor)
+				}
+				if result := limiter.clientIP(r); result != ip {
+					t.Errorf("%s forwarding %q: expected %s, result: %s", remoteAddr, forwardedFor, ip, result)
+				}
+			}
+		}
+	})
+	t.Run("Routes without rule share the default bucket", func(t *testing.T) {
+		limiter, _ := New(config.RateLimit{Default: &config.RateLimitRule{Requests: 1, Per: time.Minute}}, NewMemoryStore())
+		r := request("10.0.0.1:1234", "", "")
+		if !allowed(limiter, r, "GET /users") || allowed(limiter, r, "GET /users/:id") {
+			t.Errorf("expected a single request to the routes without rule")
+		}
+		limiter, _ = New(config.RateLimit{Routes: map[string]config.RateLimitRule{"POST /users": {Requests: 1, Per: time.Minute}}}, NewMemoryStore())
+		if decision, err := limiter.Allow(r, "GET /users"); decision != nil || err != nil {
+			t.Errorf("expected no limit, result: %+v, %v", decision, err)
+		}
+	})
+	t.Run("Should return error with invalid config", func(t *testing.T) {
+		for name, cfg := range map[string]config.RateLimit{
+			"unknown key":  {KeyBy: "cookie"},
+			"no period":    {Default: &config.RateLimitRule{Requests: 1}},
+			"no requests":  {Routes: map[string]config.RateLimitRule{"POST /users": {Per: time.Second}}},
+			"no ip period": {IP: &config.RateLimitRule{Requests: 1}},
+			"bad proxy":    {TrustedProxies: []string{"10.0.0.0/33"}},
+		} {
+			if _, err := New(cfg, NewMemoryStore()); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
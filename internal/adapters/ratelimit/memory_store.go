@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets refilled since their last request are dropped.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	limit  Limit
+}
+
+// refill adds the tokens earned since the last request, up to the burst.
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.at).Seconds()*b.limit.Rate)
+	b.at = now
+}
+
+// MemoryStore keeps the buckets in memory, each instance of the API limiting its own requests.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (m *MemoryStore) Take(_ context.Context, key string, l Limit) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), at: now}
+		m.buckets[key] = b
+	}
+	b.limit = l
+	b.refill(now)
+	decision := Decision{Limit: l.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = l.wait(1 - b.tokens)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = l.wait(float64(l.Burst) - b.tokens)
+	return decision, nil
+}
+
+// sweep drops the full buckets: a new bucket is full too.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < sweepInterval {
+		return
+	}
+	m.sweptAt = now
+	for key, b := range m.buckets {
+		if b.refill(now); b.tokens >= float64(b.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+)
+
+// AuditMemoryRepository keeps audit entries in memory, in recording order. It is safe for concurrent use.
+type AuditMemoryRepository struct {
+	mu      sync.RWMutex
+	entries []model.AuditEntry
+}
+
+func NewAuditMemoryRepo() *AuditMemoryRepository {
+	return &AuditMemoryRepository{}
+}
+
+func (ar *AuditMemoryRepository) Record(ctx context.Context, e model.AuditEntry) error {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	if e.ID == "" {
+		e.ID = primitive.NewObjectID().Hex()
+	}
+	e.Changes = append([]model.FieldChange(nil), e.Changes...)
+	ar.entries = append(ar.entries, e)
+	onRollback(ctx, func() {
+		ar.mu.Lock()
+		defer ar.mu.Unlock()
+		ar.entries = removeByID(ar.entries, e.ID, func(e model.AuditEntry) string { return e.ID })
+	})
+	return nil
+}
+
+func (ar *AuditMemoryRepository) History(_ context.Context, userID string, before string, limit int) ([]model.AuditEntry, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	entries := []model.AuditEntry{}
+	for i := len(ar.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := ar.entries[i]
+		if entry.UserID == userID && (before == "" || entry.ID < before) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// removeByID removes the last item of items whose ID is id.
+func removeByID[T any](items []T, id string, idOf func(T) string) []T {
+	for i := len(items) - 1; i >= 0; i-- {
+		if idOf(items[i]) == id {
+			return append(items[:i:i], items[i+1:]...)
+		}
+	}
+	return items
+}
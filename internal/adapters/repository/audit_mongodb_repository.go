@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+)
+
+const auditCollection = "user_audit"
+
+// AuditMongoRepository stores audit entries in their own collection. Entries are recorded
+// in the transaction of the session context, if any.
+type AuditMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewAuditMongoRepo(db *mongo.Database) *AuditMongoRepository {
+	return &AuditMongoRepository{db: db}
+}
+
+// Migrate creates the index history pages are read from.
+func (ar *AuditMongoRepository) Migrate(ctx context.Context) error {
+	_, err := ar.db.Collection(auditCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create audit index", "error", err)
+	}
+	return err
+}
+
+func (ar *AuditMongoRepository) Record(ctx context.Context, e model.AuditEntry) error {
+	if _, err := ar.db.Collection(auditCollection).InsertOne(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "failed to insert audit entry", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (ar *AuditMongoRepository) History(ctx context.Context, userID string, before string, limit int) ([]model.AuditEntry, error) {
+	filter := bson.M{"userId": userID}
+	if before != "" {
+		oid, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			return []model.AuditEntry{}, nil
+		}
+		filter["_id"] = bson.M{"$lt": oid}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := ar.db.Collection(auditCollection).Find(ctx, filter, opts)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read audit entries", "error", err)
+		return nil, err
+	}
+	entries := []model.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].At = entries[i].At.UTC()
+	}
+	return entries, nil
+}
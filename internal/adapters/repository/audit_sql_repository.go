@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+)
+
+const auditColumns = "id, user_id, operation, actor, request_id, at, changes"
+
+// AuditSQLRepository stores audit entries in the user_audit table, created by the UserSQLRepository migrations.
+// Changes are stored as JSON. Entries are recorded in the transaction of the context, if any.
+type AuditSQLRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+func NewAuditPostgresRepo(db *sql.DB) *AuditSQLRepository {
+	return &AuditSQLRepository{db: db, dialect: postgresDialect}
+}
+
+func NewAuditSQLiteRepo(db *sql.DB) *AuditSQLRepository {
+	return &AuditSQLRepository{db: db, dialect: sqliteDialect}
+}
+
+func (ar *AuditSQLRepository) Record(ctx context.Context, e model.AuditEntry) error {
+	if e.ID == "" {
+		e.ID = primitive.NewObjectID().Hex()
+	}
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	query := ar.dialect.rebind("INSERT INTO user_audit (" + auditColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?)")
+	_, err = sqlConnFrom(ctx, ar.db).ExecContext(ctx, query, e.ID, e.UserID, e.Operation, e.Actor, e.RequestID, e.At.UTC(), string(changes))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to insert audit entry", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (ar *AuditSQLRepository) History(ctx context.Context, userID string, before string, limit int) ([]model.AuditEntry, error) {
+	query := "SELECT " + auditColumns + " FROM user_audit WHERE user_id = ?"
+	args := []any{userID}
+	if before != "" {
+		query += " AND id < ?"
+		args = append(args, before)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	rows, err := sqlConnFrom(ctx, ar.db).QueryContext(ctx, ar.dialect.rebind(query), append(args, limit)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read audit entries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var entry model.AuditEntry
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Operation, &entry.Actor, &entry.RequestID, &entry.At, &changes); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, err
+		}
+		entry.At = entry.At.UTC()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"sync"
+	"time"
+)
+
+// IdempotencyMemoryRepository keeps the idempotency records in memory. It is safe for concurrent use.
+type IdempotencyMemoryRepository struct {
+	mu      sync.Mutex
+	records map[string]model.IdempotencyRecord
+}
+
+func NewIdempotencyMemoryRepo() *IdempotencyMemoryRepository {
+	return &IdempotencyMemoryRepository{records: map[string]model.IdempotencyRecord{}}
+}
+
+// Reserve also drops the expired records.
+func (ir *IdempotencyMemoryRepository) Reserve(_ context.Context, r model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	ir.deleteExpired(r.CreatedAt)
+	if existing, ok := ir.records[r.Key]; ok {
+		return &existing, nil
+	}
+	r.StatusCode, r.Header, r.Body = 0, nil, nil
+	ir.records[r.Key] = r
+	return nil, nil
+}
+
+func (ir *IdempotencyMemoryRepository) deleteExpired(now time.Time) {
+	for key, record := range ir.records {
+		if !record.ExpiresAt.After(now) {
+			delete(ir.records, key)
+		}
+	}
+}
+
+func (ir *IdempotencyMemoryRepository) Complete(_ context.Context, r model.IdempotencyRecord) error {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	if reserved, ok := ir.records[r.Key]; ok {
+		reserved.StatusCode, reserved.Header, reserved.Body = r.StatusCode, r.Header.Clone(), append([]byte(nil), r.Body...)
+		reserved.ExpiresAt = r.ExpiresAt
+		ir.records[r.Key] = reserved
+	}
+	return nil
+}
+
+func (ir *IdempotencyMemoryRepository) Release(_ context.Context, key string) error {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	delete(ir.records, key)
+	return nil
+}
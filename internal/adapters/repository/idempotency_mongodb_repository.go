@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+)
+
+const (
+	idempotencyCollection = "idempotency_keys"
+	// maxReserveAttempts bounds the attempts to reserve a key released each time between the conflicting
+	// insert and the read of the record holding it.
+	maxReserveAttempts = 3
+)
+
+var errReserveConflict = errors.New("idempotency key released and reserved again concurrently")
+
+// IdempotencyMongoRepository stores idempotency records in the idempotency_keys collection, by key.
+type IdempotencyMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewIdempotencyMongoRepo(db *mongo.Database) *IdempotencyMongoRepository {
+	return &IdempotencyMongoRepository{collection: db.Collection(idempotencyCollection)}
+}
+
+// Migrate creates the TTL index deleting the expired records.
+func (ir *IdempotencyMongoRepository) Migrate(ctx context.Context) error {
+	_, err := ir.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create idempotency key index", "error", err)
+	}
+	return err
+}
+
+// Reserve replaces the expired record with the key of r itself: the TTL monitor deletes records up to a minute late.
+func (ir *IdempotencyMongoRepository) Reserve(ctx context.Context, r model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	r.StatusCode, r.Header, r.Body = 0, nil, nil
+	filter := bson.M{"_id": r.Key, "expiresAt": bson.M{"$lte": r.CreatedAt}}
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		_, err := ir.collection.ReplaceOne(ctx, filter, r, options.Replace().SetUpsert(true))
+		if err == nil {
+			return nil, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			slog.ErrorContext(ctx, "failed to insert idempotency key", "error", err)
+			return nil, err
+		}
+		// the key exists and has not expired
+		var existing model.IdempotencyRecord
+		err = ir.collection.FindOne(ctx, bson.M{"_id": r.Key}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			// released in between: reserve the key again
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to read idempotency key", "error", err)
+			return nil, err
+		}
+		existing.CreatedAt, existing.ExpiresAt = existing.CreatedAt.UTC(), existing.ExpiresAt.UTC()
+		return &existing, nil
+	}
+	return nil, errReserveConflict
+}
+
+func (ir *IdempotencyMongoRepository) Complete(ctx context.Context, r model.IdempotencyRecord) error {
+	update := bson.M{"$set": bson.M{"statusCode": r.StatusCode, "header": r.Header, "body": r.Body, "expiresAt": r.ExpiresAt}}
+	if _, err := ir.collection.UpdateByID(ctx, r.Key, update); err != nil {
+		slog.ErrorContext(ctx, "failed to update idempotency key", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (ir *IdempotencyMongoRepository) Release(ctx context.Context, key string) error {
+	if _, err := ir.collection.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		slog.ErrorContext(ctx, "failed to delete idempotency key", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"log/slog"
+)
+
+const idempotencyColumns = "idempotency_key, fingerprint, status_code, header, body, created_at, expires_at"
+
+// IdempotencySQLRepository stores idempotency records in the idempotency_keys table, created by the
+// UserSQLRepository migrations. Response headers are stored as JSON.
+type IdempotencySQLRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+func NewIdempotencyPostgresRepo(db *sql.DB) *IdempotencySQLRepository {
+	return &IdempotencySQLRepository{db: db, dialect: postgresDialect}
+}
+
+func NewIdempotencySQLiteRepo(db *sql.DB) *IdempotencySQLRepository {
+	return &IdempotencySQLRepository{db: db, dialect: sqliteDialect}
+}
+
+// Reserve also deletes the expired records. Timestamps are written in UTC, SQLite compares them as text.
+func (ir *IdempotencySQLRepository) Reserve(ctx context.Context, r model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	if _, err := ir.db.ExecContext(ctx, ir.dialect.rebind("DELETE FROM idempotency_keys WHERE expires_at <= ?"), r.CreatedAt.UTC()); err != nil {
+		slog.ErrorContext(ctx, "failed to delete expired idempotency keys", "error", err)
+		return nil, err
+	}
+	query := ir.dialect.rebind("INSERT INTO idempotency_keys (" + idempotencyColumns + ") VALUES (?, ?, 0, NULL, NULL, ?, ?)" +
+		" ON CONFLICT (idempotency_key) DO NOTHING")
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		result, err := ir.db.ExecContext(ctx, query, r.Key, r.Fingerprint, r.CreatedAt.UTC(), r.ExpiresAt.UTC())
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to insert idempotency key", "error", err)
+			return nil, err
+		}
+		if inserted, err := result.RowsAffected(); err != nil || inserted > 0 {
+			return nil, err
+		}
+		existing, err := ir.find(ctx, r.Key)
+		if existing != nil || err != nil {
+			return existing, err
+		}
+		// released in between: reserve the key again
+	}
+	return nil, errReserveConflict
+}
+
+func (ir *IdempotencySQLRepository) find(ctx context.Context, key string) (*model.IdempotencyRecord, error) {
+	var record model.IdempotencyRecord
+	var header, body []byte
+	err := ir.db.QueryRowContext(ctx, ir.dialect.rebind("SELECT "+idempotencyColumns+" FROM idempotency_keys WHERE idempotency_key = ?"), key).
+		Scan(&record.Key, &record.Fingerprint, &record.StatusCode, &header, &body, &record.CreatedAt, &record.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read idempotency key", "error", err)
+		return nil, err
+	}
+	if header != nil {
+		if err := json.Unmarshal(header, &record.Header); err != nil {
+			return nil, err
+		}
+	}
+	record.Body = body
+	record.CreatedAt, record.ExpiresAt = record.CreatedAt.UTC(), record.ExpiresAt.UTC()
+	return &record, nil
+}
+
+func (ir *IdempotencySQLRepository) Complete(ctx context.Context, r model.IdempotencyRecord) error {
+	header, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+	query := ir.dialect.rebind("UPDATE idempotency_keys SET status_code = ?, header = ?, body = ?, expires_at = ? WHERE idempotency_key = ?")
+	if _, err := ir.db.ExecContext(ctx, query, r.StatusCode, string(header), r.Body, r.ExpiresAt.UTC(), r.Key); err != nil {
+		slog.ErrorContext(ctx, "failed to update idempotency key", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (ir *IdempotencySQLRepository) Release(ctx context.Context, key string) error {
+	if _, err := ir.db.ExecContext(ctx, ir.dialect.rebind("DELETE FROM idempotency_keys WHERE idempotency_key = ?"), key); err != nil {
+		slog.ErrorContext(ctx, "failed to delete idempotency key", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryTxKey struct{}
+
+// memoryTx is the undo log of a transaction: the in-memory repositories register how to revert each
+// of their writes made in the transaction.
+type memoryTx struct {
+	undo []func()
+}
+
+// onRollback registers undo to revert a write if the transaction of ctx, if any, is rolled back.
+func onRollback(ctx context.Context, undo func()) {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		tx.undo = append(tx.undo, undo)
+	}
+}
+
+// MemoryTransactor runs transactions over the in-memory repositories by reverting, newest first,
+// the writes of the transaction function when it fails. Transactions run one at a time.
+type MemoryTransactor struct {
+	mu sync.Mutex
+}
+
+func NewMemoryTransactor() *MemoryTransactor {
+	return &MemoryTransactor{}
+}
+
+func (t *MemoryTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tx := &memoryTx{}
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		return err
+	}
+	return nil
+}
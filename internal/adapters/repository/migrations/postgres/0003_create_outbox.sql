@@ -0,0 +1,12 @@
+CREATE TABLE outbox (
+    id           TEXT PRIMARY KEY,
+    type         TEXT        NOT NULL,
+    user_id      TEXT        NOT NULL,
+    occurred_at  TIMESTAMPTZ NOT NULL,
+    request_id   TEXT        NOT NULL DEFAULT '',
+    payload      JSONB       NOT NULL,
+    published_at TIMESTAMPTZ
+);
+
+-- pending events, oldest first
+CREATE INDEX outbox_pending ON outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,25 @@
+CREATE TABLE webhooks (
+    id         TEXT PRIMARY KEY,
+    url        TEXT        NOT NULL,
+    events     JSONB       NOT NULL,
+    secret     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id              TEXT PRIMARY KEY,
+    webhook_id      TEXT        NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        TEXT        NOT NULL,
+    event           JSONB       NOT NULL,
+    status          TEXT        NOT NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL,
+    attempts        JSONB       NOT NULL,
+    -- an event is delivered once to each webhook
+    UNIQUE (webhook_id, event_id)
+);
+
+-- due deliveries, earliest first
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+-- deliveries of a webhook, newest first
+CREATE INDEX webhook_deliveries_webhook ON webhook_deliveries (webhook_id, id);
@@ -0,0 +1,15 @@
+CREATE TABLE users (
+    id         TEXT PRIMARY KEY,
+    first_name TEXT      NOT NULL,
+    last_name  TEXT      NOT NULL,
+    email      TEXT      NOT NULL,
+    age        INTEGER   NOT NULL,
+    version    INTEGER   NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP
+);
+
+-- listing indexes, id is the tie-breaker of every sort
+CREATE INDEX users_first_name_id ON users (first_name, id);
+CREATE INDEX users_last_name_id ON users (last_name, id);
+CREATE INDEX users_email_id ON users (email, id);
+CREATE INDEX users_age_id ON users (age, id);
@@ -0,0 +1,12 @@
+CREATE TABLE user_audit (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT      NOT NULL,
+    operation  TEXT      NOT NULL,
+    actor      TEXT      NOT NULL,
+    request_id TEXT      NOT NULL DEFAULT '',
+    at         TIMESTAMP NOT NULL,
+    changes    TEXT      NOT NULL
+);
+
+-- history pages, newest first
+CREATE INDEX user_audit_user_id_id ON user_audit (user_id, id);
@@ -0,0 +1,12 @@
+CREATE TABLE outbox (
+    id           TEXT PRIMARY KEY,
+    type         TEXT      NOT NULL,
+    user_id      TEXT      NOT NULL,
+    occurred_at  TIMESTAMP NOT NULL,
+    request_id   TEXT      NOT NULL DEFAULT '',
+    payload      TEXT      NOT NULL,
+    published_at TIMESTAMP
+);
+
+-- pending events, oldest first
+CREATE INDEX outbox_pending ON outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    idempotency_key TEXT PRIMARY KEY,
+    fingerprint     TEXT      NOT NULL,
+    -- 0 until the response is stored
+    status_code     INTEGER   NOT NULL,
+    header          TEXT,
+    body            BLOB,
+    created_at      TIMESTAMP NOT NULL,
+    expires_at      TIMESTAMP NOT NULL
+);
+
+-- expired keys, deleted when keys are reserved
+CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+)
+
+// OutboxMemoryRepository keeps the events in memory, in adding order. It is safe for concurrent use.
+type OutboxMemoryRepository struct {
+	mu        sync.RWMutex
+	events    []model.Event
+	published map[string]time.Time
+}
+
+func NewOutboxMemoryRepo() *OutboxMemoryRepository {
+	return &OutboxMemoryRepository{published: map[string]time.Time{}}
+}
+
+func (or *OutboxMemoryRepository) Add(ctx context.Context, e model.Event) error {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	if e.ID == "" {
+		e.ID = primitive.NewObjectID().Hex()
+	}
+	or.events = append(or.events, e)
+	onRollback(ctx, func() {
+		or.mu.Lock()
+		defer or.mu.Unlock()
+		or.events = removeByID(or.events, e.ID, func(e model.Event) string { return e.ID })
+	})
+	return nil
+}
+
+func (or *OutboxMemoryRepository) Pending(_ context.Context, limit int) ([]model.Event, error) {
+	or.mu.RLock()
+	defer or.mu.RUnlock()
+	events := []model.Event{}
+	for _, event := range or.events {
+		if len(events) == limit {
+			break
+		}
+		if _, published := or.published[event.ID]; !published {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (or *OutboxMemoryRepository) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	previous, published := or.published[id]
+	or.published[id] = at
+	onRollback(ctx, func() {
+		or.mu.Lock()
+		defer or.mu.Unlock()
+		if published {
+			or.published[id] = previous
+		} else {
+			delete(or.published, id)
+		}
+	})
+	return nil
+}
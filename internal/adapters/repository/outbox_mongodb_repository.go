@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+)
+
+const outboxCollection = "outbox"
+
+// OutboxMongoRepository stores events in the outbox collection. Events are added in the transaction
+// of the session context, if any, so they are committed with the user change they describe.
+type OutboxMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewOutboxMongoRepo(db *mongo.Database) *OutboxMongoRepository {
+	return &OutboxMongoRepository{db: db}
+}
+
+// Migrate creates the index pending events are read from.
+func (or *OutboxMongoRepository) Migrate(ctx context.Context) error {
+	_, err := or.db.Collection(outboxCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "publishedAt", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create outbox index", "error", err)
+	}
+	return err
+}
+
+func (or *OutboxMongoRepository) Add(ctx context.Context, e model.Event) error {
+	if _, err := or.db.Collection(outboxCollection).InsertOne(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "failed to insert outbox event", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (or *OutboxMongoRepository) Pending(ctx context.Context, limit int) ([]model.Event, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := or.db.Collection(outboxCollection).Find(ctx, bson.M{"publishedAt": nil}, opts)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read outbox events", "error", err)
+		return nil, err
+	}
+	events := []model.Event{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].OccurredAt = events[i].OccurredAt.UTC()
+	}
+	return events, nil
+}
+
+func (or *OutboxMongoRepository) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = or.db.Collection(outboxCollection).UpdateByID(ctx, oid, bson.M{"$set": bson.M{"publishedAt": at}})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to mark outbox event as published", "error", err)
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"time"
+)
+
+const outboxColumns = "id, type, user_id, occurred_at, request_id, payload"
+
+// OutboxSQLRepository stores events in the outbox table, created by the UserSQLRepository migrations.
+// The user of an event is stored as JSON. Events are added in the transaction of the context, if any.
+type OutboxSQLRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+func NewOutboxPostgresRepo(db *sql.DB) *OutboxSQLRepository {
+	return &OutboxSQLRepository{db: db, dialect: postgresDialect}
+}
+
+func NewOutboxSQLiteRepo(db *sql.DB) *OutboxSQLRepository {
+	return &OutboxSQLRepository{db: db, dialect: sqliteDialect}
+}
+
+func (or *OutboxSQLRepository) Add(ctx context.Context, e model.Event) error {
+	if e.ID == "" {
+		e.ID = primitive.NewObjectID().Hex()
+	}
+	payload, err := json.Marshal(e.User)
+	if err != nil {
+		return err
+	}
+	query := or.dialect.rebind("INSERT INTO outbox (" + outboxColumns + ") VALUES (?, ?, ?, ?, ?, ?)")
+	_, err = sqlConnFrom(ctx, or.db).ExecContext(ctx, query, e.ID, e.Type, e.UserID, e.OccurredAt.UTC(), e.RequestID, string(payload))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to insert outbox event", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (or *OutboxSQLRepository) Pending(ctx context.Context, limit int) ([]model.Event, error) {
+	query := or.dialect.rebind("SELECT " + outboxColumns + " FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT ?")
+	rows, err := sqlConnFrom(ctx, or.db).QueryContext(ctx, query, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read outbox events", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	events := []model.Event{}
+	for rows.Next() {
+		var event model.Event
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.UserID, &event.OccurredAt, &event.RequestID, &payload); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &event.User); err != nil {
+			return nil, err
+		}
+		event.OccurredAt = event.OccurredAt.UTC()
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (or *OutboxSQLRepository) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	query := or.dialect.rebind("UPDATE outbox SET published_at = ? WHERE id = ?")
+	if _, err := sqlConnFrom(ctx, or.db).ExecContext(ctx, query, at.UTC(), id); err != nil {
+		slog.ErrorContext(ctx, "failed to mark outbox event as published", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"testing"
+	"time"
+)
+
+// NewAuditLog returns an empty audit log for a single test, with the transactor its writes can join.
+type NewAuditLog func(t *testing.T) (service.AuditLog, service.Transactor)
+
+// RunAuditLogTests runs the AuditLog contract against the audit logs built by newLog.
+func RunAuditLogTests(t *testing.T, newLog NewAuditLog) {
+	t.Run("Record and History", func(t *testing.T) { testRecordAndHistory(t, newLog) })
+	t.Run("History pages", func(t *testing.T) { testHistoryPages(t, newLog) })
+	t.Run("Rolled back entries", func(t *testing.T) { testAuditRollback(t, newLog) })
+}
+
+func newAuditEntry(userID string, operation string, changes ...model.FieldChange) model.AuditEntry {
+	return model.AuditEntry{
+		UserID:    userID,
+		Operation: operation,
+		Actor:     "admin",
+		RequestID: "req-1",
+		// MongoDB stores milliseconds
+		At:      time.Now().UTC().Truncate(time.Millisecond),
+		Changes: changes,
+	}
+}
+
+func mustRecord(t *testing.T, log service.AuditLog, e model.AuditEntry) {
+	t.Helper()
+	if err := log.Record(context.Background(), e); err != nil {
+		t.Fatalf("error recording audit entry: %v", err)
+	}
+}
+
+func mustReadHistory(t *testing.T, log service.AuditLog, userID string, before string, limit int) []model.AuditEntry {
+	t.Helper()
+	entries, err := log.History(context.Background(), userID, before, limit)
+	if err != nil {
+		t.Fatalf("error reading history: %v", err)
+	}
+	return entries
+}
+
+func testRecordAndHistory(t *testing.T, newLog NewAuditLog) {
+	log, _ := newLog(t)
+	created := newAuditEntry(missingID, model.OperationCreate,
+		model.FieldChange{Field: model.FieldFirstName, After: "John"},
+		model.FieldChange{Field: model.FieldAge, After: 30})
+	updated := newAuditEntry(missingID, model.OperationUpdate, model.FieldChange{Field: model.FieldAge, Before: 30, After: 31})
+	mustRecord(t, log, created)
+	mustRecord(t, log, newAuditEntry("111111111111111111111111", model.OperationCreate))
+	mustRecord(t, log, updated)
+
+	entries := mustReadHistory(t, log, missingID, "", 10)
+	if len(entries) != 2 {
+		t.Fatalf("expected: 2 entries, result: %v", entries)
+	}
+	for i, expected := range []model.AuditEntry{updated, created} {
+		result := entries[i]
+		if result.ID == "" || result.UserID != expected.UserID || result.Operation != expected.Operation ||
+			result.Actor != expected.Actor || result.RequestID != expected.RequestID || !result.At.Equal(expected.At) {
+			t.Errorf("expected: %+v, result: %+v", expected, result)
+		}
+		// values are compared in their JSON form, which is what clients read
+		expectedChanges, _ := json.Marshal(expected.Changes)
+		resultChanges, _ := json.Marshal(result.Changes)
+		if string(expectedChanges) != string(resultChanges) {
+			t.Errorf("expected: %s, result: %s", expectedChanges, resultChanges)
+		}
+	}
+	if entries := mustReadHistory(t, log, "222222222222222222222222", "", 10); entries == nil || len(entries) != 0 {
+		t.Errorf("expected empty history, result: %v", entries)
+	}
+}
+
+func testHistoryPages(t *testing.T, newLog NewAuditLog) {
+	log, _ := newLog(t)
+	for _, operation := range []string{model.OperationCreate, model.OperationUpdate, model.OperationDelete} {
+		mustRecord(t, log, newAuditEntry(missingID, operation))
+	}
+
+	first := mustReadHistory(t, log, missingID, "", 2)
+	if len(first) != 2 || first[0].Operation != model.OperationDelete || first[1].Operation != model.OperationUpdate {
+		t.Fatalf("expected the delete and update entries, result: %v", first)
+	}
+	second := mustReadHistory(t, log, missingID, first[1].ID, 2)
+	if len(second) != 1 || second[0].Operation != model.OperationCreate {
+		t.Errorf("expected the create entry, result: %v", second)
+	}
+}
+
+func testAuditRollback(t *testing.T, newLog NewAuditLog) {
+	log, tx := newLog(t)
+	errAbort := errors.New("abort")
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if err := log.Record(ctx, newAuditEntry(missingID, model.OperationCreate)); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected: %v, result: %v", errAbort, err)
+	}
+	if entries := mustReadHistory(t, log, missingID, "", 10); len(entries) != 0 {
+		t.Errorf("rolled back entries should not be recorded, result: %v", entries)
+	}
+}
@@ -0,0 +1,100 @@
+package repositorytest
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// RunIdempotencyStoreTests runs the IdempotencyStore contract against the empty stores built by newStore.
+func RunIdempotencyStoreTests(t *testing.T, newStore func(t *testing.T) service.IdempotencyStore) {
+	ctx := context.Background()
+	// MongoDB stores milliseconds
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newRecord := func(key string, fingerprint string, at time.Time) model.IdempotencyRecord {
+		return model.IdempotencyRecord{Key: key, Fingerprint: fingerprint, CreatedAt: at, ExpiresAt: at.Add(time.Hour)}
+	}
+	mustReserve := func(t *testing.T, store service.IdempotencyStore, r model.IdempotencyRecord) *model.IdempotencyRecord {
+		t.Helper()
+		existing, err := store.Reserve(ctx, r)
+		if err != nil {
+			t.Fatalf("error reserving key: %v", err)
+		}
+		return existing
+	}
+
+	t.Run("Reserve and Complete", func(t *testing.T) {
+		store := newStore(t)
+		record := newRecord("alice k1", "f1", now)
+		if existing := mustReserve(t, store, record); existing != nil {
+			t.Fatalf("expected the key to be reserved, result: %+v", existing)
+		}
+		existing := mustReserve(t, store, newRecord("alice k1", "f2", now.Add(time.Minute)))
+		if existing == nil || existing.Completed() || existing.Fingerprint != "f1" || !existing.CreatedAt.Equal(now) {
+			t.Fatalf("expected the reserved record, result: %+v", existing)
+		}
+		if other := mustReserve(t, store, newRecord("bob k1", "f1", now)); other != nil {
+			t.Errorf("expected keys to be independent, result: %+v", other)
+		}
+
+		record.StatusCode, record.Header, record.Body = http.StatusCreated, http.Header{"Location": {"/users/1"}}, []byte(`{"id":"1"}`)
+		if err := store.Complete(ctx, record); err != nil {
+			t.Fatalf("error completing key: %v", err)
+		}
+		existing = mustReserve(t, store, newRecord("alice k1", "f1", now.Add(time.Minute)))
+		if existing == nil || existing.StatusCode != http.StatusCreated || !reflect.DeepEqual(existing.Header, record.Header) ||
+			string(existing.Body) != `{"id":"1"}` || !existing.ExpiresAt.Equal(record.ExpiresAt) {
+			t.Errorf("expected: %+v, result: %+v", record, existing)
+		}
+	})
+	t.Run("Release", func(t *testing.T) {
+		store := newStore(t)
+		mustReserve(t, store, newRecord("alice k1", "f1", now))
+		if err := store.Release(ctx, "alice k1"); err != nil {
+			t.Fatalf("error releasing key: %v", err)
+		}
+		if existing := mustReserve(t, store, newRecord("alice k1", "f2", now)); existing != nil {
+			t.Errorf("expected a released key to be reserved again, result: %+v", existing)
+		}
+	})
+	t.Run("Expired keys are reserved again", func(t *testing.T) {
+		store := newStore(t)
+		record := newRecord("alice k1", "f1", now)
+		mustReserve(t, store, record)
+		record.StatusCode = http.StatusCreated
+		_ = store.Complete(ctx, record)
+
+		if existing := mustReserve(t, store, newRecord("alice k1", "f2", now.Add(time.Hour))); existing != nil {
+			t.Fatalf("expected the expired key to be reserved, result: %+v", existing)
+		}
+		existing := mustReserve(t, store, newRecord("alice k1", "f3", now.Add(time.Hour)))
+		if existing == nil || existing.Fingerprint != "f2" || existing.Completed() {
+			t.Errorf("expected the new reservation, result: %+v", existing)
+		}
+	})
+	t.Run("Reservations not completed within their lease are reserved again", func(t *testing.T) {
+		store := newStore(t)
+		reservation := newRecord("alice k1", "f1", now)
+		reservation.ExpiresAt = now.Add(time.Minute)
+		mustReserve(t, store, reservation)
+		if existing := mustReserve(t, store, newRecord("alice k1", "f2", now.Add(2*time.Minute))); existing != nil {
+			t.Fatalf("expected the abandoned key to be reserved, result: %+v", existing)
+		}
+
+		completed := newRecord("bob k1", "f1", now)
+		completed.ExpiresAt = now.Add(time.Minute)
+		mustReserve(t, store, completed)
+		completed.StatusCode, completed.ExpiresAt = http.StatusCreated, now.Add(time.Hour)
+		if err := store.Complete(ctx, completed); err != nil {
+			t.Fatalf("error completing key: %v", err)
+		}
+		existing := mustReserve(t, store, newRecord("bob k1", "f1", now.Add(2*time.Minute)))
+		if existing == nil || !existing.Completed() || !existing.ExpiresAt.Equal(completed.ExpiresAt) {
+			t.Errorf("expected the completed record to be kept until its new expiration, result: %+v", existing)
+		}
+	})
+}
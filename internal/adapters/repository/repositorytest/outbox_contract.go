@@ -0,0 +1,105 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"testing"
+	"time"
+)
+
+// NewOutbox returns an empty outbox for a single test, with the transactor its writes can join.
+type NewOutbox func(t *testing.T) (service.Outbox, service.Transactor)
+
+// RunOutboxTests runs the Outbox contract against the outboxes built by newOutbox.
+func RunOutboxTests(t *testing.T, newOutbox NewOutbox) {
+	t.Run("Add and Pending", func(t *testing.T) { testAddAndPending(t, newOutbox) })
+	t.Run("MarkPublished", func(t *testing.T) { testMarkPublished(t, newOutbox) })
+	t.Run("Rolled back events", func(t *testing.T) { testOutboxRollback(t, newOutbox) })
+}
+
+func newEvent(eventType string, u model.User) model.Event {
+	return model.Event{
+		Type:   eventType,
+		UserID: u.ID,
+		// MongoDB stores milliseconds
+		OccurredAt: time.Now().UTC().Truncate(time.Millisecond),
+		RequestID:  "req-1",
+		User:       u,
+	}
+}
+
+func mustAdd(t *testing.T, outbox service.Outbox, e model.Event) {
+	t.Helper()
+	if err := outbox.Add(context.Background(), e); err != nil {
+		t.Fatalf("error adding event: %v", err)
+	}
+}
+
+func mustReadPending(t *testing.T, outbox service.Outbox, limit int) []model.Event {
+	t.Helper()
+	events, err := outbox.Pending(context.Background(), limit)
+	if err != nil {
+		t.Fatalf("error reading pending events: %v", err)
+	}
+	return events
+}
+
+func testAddAndPending(t *testing.T, newOutbox NewOutbox) {
+	outbox, _ := newOutbox(t)
+	user := newUser("John", "Doe", 30)
+	user.ID = missingID
+	user.Version = 1
+	expected := []model.Event{newEvent(model.EventUserCreated, user), newEvent(model.EventUserUpdated, user), newEvent(model.EventUserDeleted, user)}
+	for _, event := range expected {
+		mustAdd(t, outbox, event)
+	}
+
+	events := mustReadPending(t, outbox, 2)
+	if len(events) != 2 {
+		t.Fatalf("expected: 2 events, result: %v", events)
+	}
+	for i, result := range events {
+		if result.ID == "" || result.Type != expected[i].Type || result.UserID != expected[i].UserID ||
+			!result.OccurredAt.Equal(expected[i].OccurredAt) || result.RequestID != expected[i].RequestID || result.User != user {
+			t.Errorf("expected: %+v, result: %+v", expected[i], result)
+		}
+	}
+	if events := mustReadPending(t, outbox, 10); len(events) != 3 || events[2].Type != model.EventUserDeleted {
+		t.Errorf("expected: 3 events, result: %v", events)
+	}
+}
+
+func testMarkPublished(t *testing.T, newOutbox NewOutbox) {
+	outbox, _ := newOutbox(t)
+	user := newUser("John", "Doe", 30)
+	user.ID = missingID
+	mustAdd(t, outbox, newEvent(model.EventUserCreated, user))
+	mustAdd(t, outbox, newEvent(model.EventUserUpdated, user))
+
+	first := mustReadPending(t, outbox, 1)[0]
+	if err := outbox.MarkPublished(context.Background(), first.ID, time.Now()); err != nil {
+		t.Fatalf("error marking event as published: %v", err)
+	}
+	if events := mustReadPending(t, outbox, 10); len(events) != 1 || events[0].Type != model.EventUserUpdated {
+		t.Errorf("expected the update event only, result: %v", events)
+	}
+}
+
+func testOutboxRollback(t *testing.T, newOutbox NewOutbox) {
+	outbox, tx := newOutbox(t)
+	errAbort := errors.New("abort")
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if err := outbox.Add(ctx, newEvent(model.EventUserCreated, newUser("John", "Doe", 30))); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected: %v, result: %v", errAbort, err)
+	}
+	if events := mustReadPending(t, outbox, 10); len(events) != 0 {
+		t.Errorf("rolled back events should not be added, result: %v", events)
+	}
+}
@@ -0,0 +1,93 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"testing"
+)
+
+// NewTransactionalRepository returns an empty repository for a single test, with the transactor its writes can join.
+type NewTransactionalRepository func(t *testing.T) (service.UserRepository, service.Transactor)
+
+// RunTransactorTests runs the Transactor contract against the repositories and transactors built by newRepo.
+func RunTransactorTests(t *testing.T, newRepo NewTransactionalRepository) {
+	t.Run("Commit", func(t *testing.T) { testCommit(t, newRepo) })
+	t.Run("Rollback", func(t *testing.T) { testRollback(t, newRepo) })
+	t.Run("Nested transaction joins the outer one", func(t *testing.T) { testNestedTransaction(t, newRepo) })
+}
+
+func testCommit(t *testing.T, newRepo NewTransactionalRepository) {
+	repo, tx := newRepo(t)
+	var saved [2]string
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		for i, u := range []string{"John", "Jane"} {
+			user, err := repo.Save(ctx, newUser(u, "Doe", 30))
+			if err != nil {
+				return err
+			}
+			saved[i] = user.ID
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error committing transaction: %v", err)
+	}
+	for _, id := range saved {
+		if found, _ := repo.FindById(context.Background(), id); found == nil {
+			t.Errorf("user %s should be committed", id)
+		}
+	}
+}
+
+func testRollback(t *testing.T, newRepo NewTransactionalRepository) {
+	repo, tx := newRepo(t)
+	existing := mustSave(t, repo, newUser("John", "Doe", 30))
+	errAbort := errors.New("abort")
+	var saved string
+	err := tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		user, err := repo.Save(ctx, newUser("Jane", "Doe", 31))
+		if err != nil {
+			return err
+		}
+		saved = user.ID
+		existing.Age = 40
+		if _, err := repo.Update(ctx, existing); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected: %v, result: %v", errAbort, err)
+	}
+	if found, _ := repo.FindById(context.Background(), saved); found != nil {
+		t.Errorf("saved user should be rolled back, result: %v", found)
+	}
+	found, _ := repo.FindById(context.Background(), existing.ID)
+	if found == nil || found.Age != 30 || found.Version != 1 {
+		t.Errorf("update should be rolled back, result: %v", found)
+	}
+}
+
+func testNestedTransaction(t *testing.T, newRepo NewTransactionalRepository) {
+	repo, tx := newRepo(t)
+	errAbort := errors.New("abort")
+	var saved string
+	_ = tx.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		err := tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			user, err := repo.Save(ctx, newUser("John", "Doe", 30))
+			if err != nil {
+				return err
+			}
+			saved = user.ID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if found, _ := repo.FindById(context.Background(), saved); found != nil {
+		t.Errorf("inner transaction should be rolled back with the outer one, result: %v", found)
+	}
+}
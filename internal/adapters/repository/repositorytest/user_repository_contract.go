@@ -0,0 +1,449 @@
+// Package repositorytest holds the conformance tests every service.UserRepository, service.AuditLog,
+// service.Outbox, service.WebhookRepository and service.IdempotencyStore implementation must pass.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"sync"
+	"testing"
+	"time"
+)
+
+// NewRepository returns an empty repository for a single test. Cleanup should be registered on t.
+type NewRepository func(t *testing.T) service.UserRepository
+
+// missingID is a well-formed ObjectID hex that no test ever saves.
+const missingID = "000000000000000000000000"
+
+// RunUserRepositoryTests runs the UserRepository contract against the repositories built by newRepo.
+// These are the semantics Service relies on.
+func RunUserRepositoryTests(t *testing.T, newRepo NewRepository) {
+	t.Run("Save and FindById", func(t *testing.T) { testSaveAndFind(t, newRepo(t)) })
+	t.Run("FindById unknown and invalid IDs", func(t *testing.T) { testFindMissing(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("UpdateFields", func(t *testing.T) { testUpdateFields(t, newRepo(t)) })
+	t.Run("Update versions", func(t *testing.T) { testUpdateVersions(t, newRepo(t)) })
+	t.Run("Unique names", func(t *testing.T) { testUniqueNames(t, newRepo(t)) })
+	t.Run("ExistsByFirstNameAndLastName", func(t *testing.T) { testExists(t, newRepo(t)) })
+	t.Run("Soft delete and restore", func(t *testing.T) { testSoftDelete(t, newRepo(t)) })
+	t.Run("Hard delete", func(t *testing.T) { testHardDelete(t, newRepo(t)) })
+	t.Run("List and Count", func(t *testing.T) { testList(t, newRepo(t)) })
+	t.Run("Stream", func(t *testing.T) { testStream(t, newRepo(t)) })
+	t.Run("Concurrent saves", func(t *testing.T) { testConcurrentSaves(t, newRepo(t)) })
+	t.Run("Concurrent updates", func(t *testing.T) { testConcurrentUpdates(t, newRepo(t)) })
+}
+
+func newUser(firstName, lastName string, age int) model.User {
+	return model.User{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     fmt.Sprintf("%s.%s@doe.com", firstName, lastName),
+		Age:       age,
+		Version:   1,
+	}
+}
+
+func mustSave(t *testing.T, repo service.UserRepository, u model.User) model.User {
+	t.Helper()
+	saved, err := repo.Save(context.Background(), u)
+	if err != nil {
+		t.Fatalf("error saving user %v: %v", u, err)
+	}
+	return *saved
+}
+
+func testSaveAndFind(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	saved := mustSave(t, repo, newUser("John", "Doe", 30))
+	if saved.ID == "" {
+		t.Fatalf("saved user should have an ID")
+	}
+	other := mustSave(t, repo, newUser("Jane", "Doe", 31))
+	if other.ID == saved.ID {
+		t.Errorf("saved users should have distinct IDs")
+	}
+
+	found, err := repo.FindById(ctx, saved.ID)
+	if err != nil {
+		t.Fatalf("error finding user: %v", err)
+	}
+	if found == nil || *found != saved {
+		t.Errorf("expected: %v, result: %v", saved, found)
+	}
+}
+
+func testFindMissing(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	mustSave(t, repo, newUser("John", "Doe", 30))
+	for _, id := range []string{missingID, "not-an-id", ""} {
+		found, err := repo.FindById(ctx, id)
+		if found != nil || err != nil {
+			t.Errorf("FindById(%q) should return nil, nil, result: %v, %v", id, found, err)
+		}
+		deleted, err := repo.FindDeletedById(ctx, id)
+		if deleted != nil || err != nil {
+			t.Errorf("FindDeletedById(%q) should return nil, nil, result: %v, %v", id, deleted, err)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	saved := mustSave(t, repo, newUser("John", "Doe", 30))
+	changed := newUser("Johnny", "Doe", 31)
+	changed.ID = saved.ID
+	changed.Version = 0
+
+	updated, err := repo.Update(ctx, changed)
+	if err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	changed.Version = saved.Version + 1
+	if updated == nil || *updated != changed {
+		t.Errorf("expected: %v, result: %v", changed, updated)
+	}
+	found, _ := repo.FindById(ctx, saved.ID)
+	if found == nil || *found != changed {
+		t.Errorf("update not persisted, expected: %v, result: %v", changed, found)
+	}
+
+	changed.FirstName = "Unknown"
+	for _, id := range []string{missingID, "not-an-id"} {
+		changed.ID = id
+		if updated, err := repo.Update(ctx, changed); updated != nil || err != nil {
+			t.Errorf("updating missing user %q should return nil, nil, result: %v, %v", id, updated, err)
+		}
+	}
+}
+
+func testUpdateFields(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	saved := mustSave(t, repo, newUser("John", "Doe", 30))
+	patch := model.User{ID: saved.ID, FirstName: "ignored", Email: "patched@doe.com"}
+
+	updated, err := repo.UpdateFields(ctx, patch, []string{model.FieldEmail})
+	if err != nil {
+		t.Fatalf("error updating user fields: %v", err)
+	}
+	expected := saved
+	expected.Email = patch.Email
+	expected.Version++
+	if updated == nil || *updated != expected {
+		t.Errorf("expected: %v, result: %v", expected, updated)
+	}
+}
+
+func testUpdateVersions(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	saved := mustSave(t, repo, newUser("John", "Doe", 30))
+	changed := saved
+	changed.Age = 31
+
+	updated, err := repo.Update(ctx, changed)
+	if err != nil || updated == nil || updated.Version != saved.Version+1 {
+		t.Fatalf("update with the current version should succeed, result: %v, %v", updated, err)
+	}
+	changed.Age = 32
+	stale, err := repo.Update(ctx, changed)
+	if stale != nil || err != nil {
+		t.Errorf("update with an outdated version should return nil, nil, result: %v, %v", stale, err)
+	}
+	stale, err = repo.UpdateFields(ctx, changed, []string{model.FieldAge})
+	if stale != nil || err != nil {
+		t.Errorf("update fields with an outdated version should return nil, nil, result: %v, %v", stale, err)
+	}
+	found, _ := repo.FindById(ctx, saved.ID)
+	if found == nil || found.Age != 31 {
+		t.Errorf("outdated updates should not be persisted, result: %v", found)
+	}
+}
+
+func testUniqueNames(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	john := mustSave(t, repo, newUser("John", "Doe", 30))
+	jane := mustSave(t, repo, newUser("Jane", "Doe", 30))
+
+	if _, err := repo.Save(ctx, newUser("John", "Doe", 40)); !errors.Is(err, service.ErrUsernameTaken) {
+		t.Errorf("saving a taken name: expected %v, result: %v", service.ErrUsernameTaken, err)
+	}
+	rename := jane
+	rename.Version = 0
+	rename.FirstName = "John"
+	if _, err := repo.Update(ctx, rename); !errors.Is(err, service.ErrUsernameTaken) {
+		t.Errorf("updating to a taken name: expected %v, result: %v", service.ErrUsernameTaken, err)
+	}
+	if _, err := repo.UpdateFields(ctx, rename, []string{model.FieldFirstName}); !errors.Is(err, service.ErrUsernameTaken) {
+		t.Errorf("updating fields to a taken name: expected %v, result: %v", service.ErrUsernameTaken, err)
+	}
+	same := john
+	same.Version = 0
+	same.Age = 50
+	if _, err := repo.Update(ctx, same); err != nil {
+		t.Errorf("a user keeping its own name should be updated, result: %v", err)
+	}
+	if _, err := repo.Save(ctx, newUser("John", "Smith", 30)); err != nil {
+		t.Errorf("only the first and last name combination should be unique, result: %v", err)
+	}
+}
+
+func testExists(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	john := mustSave(t, repo, newUser("John", "Doe", 30))
+
+	for _, tc := range []struct {
+		user     model.User
+		expected bool
+	}{
+		{newUser("John", "Doe", 20), true},
+		{john, false},
+		{newUser("John", "Smith", 20), false},
+		{newUser("Jane", "Doe", 20), false},
+	} {
+		exists, err := repo.ExistsByFirstNameAndLastName(ctx, tc.user)
+		if err != nil || exists != tc.expected {
+			t.Errorf("ExistsByFirstNameAndLastName(%v): expected %v, result: %v, %v", tc.user, tc.expected, exists, err)
+		}
+	}
+}
+
+func testSoftDelete(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	john := mustSave(t, repo, newUser("John", "Doe", 30))
+	at := time.Now().UTC().Truncate(time.Millisecond)
+
+	deleted, err := repo.SoftDelete(ctx, john.ID, at)
+	if err != nil || !deleted {
+		t.Fatalf("soft delete should succeed, result: %v, %v", deleted, err)
+	}
+	if deleted, _ := repo.SoftDelete(ctx, john.ID, at); deleted {
+		t.Errorf("deleting an already soft-deleted user should return false")
+	}
+	if deleted, _ := repo.SoftDelete(ctx, missingID, at); deleted {
+		t.Errorf("deleting a missing user should return false")
+	}
+	if found, _ := repo.FindById(ctx, john.ID); found != nil {
+		t.Errorf("soft-deleted users should not be found, result: %v", found)
+	}
+	found, err := repo.FindDeletedById(ctx, john.ID)
+	if err != nil || found == nil || found.DeletedAt == nil || !found.DeletedAt.Equal(at) {
+		t.Errorf("soft-deleted user should be found with its deletion time, result: %v, %v", found, err)
+	}
+	if exists, _ := repo.ExistsByFirstNameAndLastName(ctx, newUser("John", "Doe", 30)); exists {
+		t.Errorf("soft-deleted users should not hold their name")
+	}
+	if updated, _ := repo.Update(ctx, john); updated != nil {
+		t.Errorf("soft-deleted users should not be updated")
+	}
+	if count, _ := repo.Count(ctx, service.UserFilter{}); count != 0 {
+		t.Errorf("soft-deleted users should not be counted, result: %d", count)
+	}
+
+	replacement := mustSave(t, repo, newUser("John", "Doe", 40))
+	if _, err := repo.Restore(ctx, john.ID); !errors.Is(err, service.ErrUsernameTaken) {
+		t.Errorf("restoring a user whose name was taken: expected %v, result: %v", service.ErrUsernameTaken, err)
+	}
+	if _, err := repo.Delete(ctx, replacement.ID); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	restored, err := repo.Restore(ctx, john.ID)
+	if err != nil || restored == nil || restored.DeletedAt != nil || restored.ID != john.ID {
+		t.Fatalf("restore should succeed, result: %v, %v", restored, err)
+	}
+	if again, err := repo.Restore(ctx, john.ID); again != nil || err != nil {
+		t.Errorf("restoring a live user should return nil, nil, result: %v, %v", again, err)
+	}
+	if found, _ := repo.FindById(ctx, john.ID); found == nil {
+		t.Errorf("restored user should be found")
+	}
+}
+
+func testHardDelete(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	john := mustSave(t, repo, newUser("John", "Doe", 30))
+	jane := mustSave(t, repo, newUser("Jane", "Doe", 30))
+	if _, err := repo.SoftDelete(ctx, jane.ID, time.Now()); err != nil {
+		t.Fatalf("error soft deleting user: %v", err)
+	}
+
+	for _, id := range []string{john.ID, jane.ID} {
+		deleted, err := repo.Delete(ctx, id)
+		if err != nil || !deleted {
+			t.Errorf("hard delete of %s should succeed, result: %v, %v", id, deleted, err)
+		}
+		if deleted, _ := repo.Delete(ctx, id); deleted {
+			t.Errorf("deleting %s twice should return false", id)
+		}
+	}
+	if found, _ := repo.FindDeletedById(ctx, jane.ID); found != nil {
+		t.Errorf("hard-deleted users should not be found")
+	}
+	if deleted, err := repo.Delete(ctx, "not-an-id"); deleted || err != nil {
+		t.Errorf("deleting an invalid ID should return false, nil, result: %v, %v", deleted, err)
+	}
+}
+
+func testList(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	byName := map[string]model.User{}
+	for i, name := range []string{"Carl", "Anna", "Bob", "Dave", "Eve"} {
+		byName[name] = mustSave(t, repo, newUser(name, "Doe", 20+i%3))
+	}
+	mustSave(t, repo, newUser("Zed", "Smith", 60))
+
+	count, err := repo.Count(ctx, service.UserFilter{LastName: "Doe"})
+	if err != nil || count != 5 {
+		t.Errorf("expected 5 users named Doe, result: %d, %v", count, err)
+	}
+	count, _ = repo.Count(ctx, service.UserFilter{MinAge: 21, MaxAge: 22})
+	if count != 3 {
+		t.Errorf("expected 3 users between 21 and 22, result: %d", count)
+	}
+
+	sort := service.UserSort{Field: service.SortByFirstName}
+	query := service.UserQuery{Filter: service.UserFilter{LastName: "Doe"}, Sort: sort, Limit: 2}
+	var names []string
+	for page := 0; page < 5; page++ {
+		users, err := repo.List(ctx, query)
+		if err != nil {
+			t.Fatalf("error listing users: %v", err)
+		}
+		if len(users) == 0 {
+			break
+		}
+		for _, u := range users {
+			names = append(names, u.FirstName)
+		}
+		last := users[len(users)-1]
+		query.After = &service.Cursor{Sort: sort.Field, ID: last.ID, Value: last.FirstName}
+	}
+	if fmt.Sprint(names) != fmt.Sprint([]string{"Anna", "Bob", "Carl", "Dave", "Eve"}) {
+		t.Errorf("expected users in first name order, result: %v", names)
+	}
+
+	// ages are 22, 20, 21, 20, 21: ties are broken by ID in the sort direction
+	users, err := repo.List(ctx, service.UserQuery{
+		Filter: service.UserFilter{LastName: "Doe"},
+		Sort:   service.UserSort{Field: service.SortByAge, Desc: true},
+		Limit:  10,
+	})
+	if err != nil || len(users) != 5 {
+		t.Fatalf("expected 5 users, result: %v, %v", users, err)
+	}
+	for i := 1; i < len(users); i++ {
+		prev, cur := users[i-1], users[i]
+		if prev.Age < cur.Age || (prev.Age == cur.Age && prev.ID < cur.ID) {
+			t.Errorf("users not sorted by descending age and ID: %v", users)
+		}
+	}
+	after, _ := repo.List(ctx, service.UserQuery{
+		Filter: service.UserFilter{LastName: "Doe"},
+		Sort:   service.UserSort{Field: service.SortByAge, Desc: true},
+		After:  &service.Cursor{Sort: service.SortByAge, Desc: true, ID: users[1].ID, Value: fmt.Sprint(users[1].Age)},
+		Limit:  10,
+	})
+	if len(after) != 3 || after[0].ID != users[2].ID {
+		t.Errorf("expected the last 3 users after the cursor, result: %v", after)
+	}
+
+	empty, err := repo.List(ctx, service.UserQuery{Filter: service.UserFilter{FirstName: "Nobody"}, Limit: 10})
+	if err != nil || len(empty) != 0 {
+		t.Errorf("expected no users, result: %v, %v", empty, err)
+	}
+}
+
+func testStream(t *testing.T, repo service.UserRepository) {
+	ctx := context.Background()
+	var saved []string
+	for i, name := range []string{"Carl", "Anna", "Bob"} {
+		saved = append(saved, mustSave(t, repo, newUser(name, "Doe", 20+i)).ID)
+	}
+	deleted := mustSave(t, repo, newUser("Dave", "Doe", 30))
+	if _, err := repo.SoftDelete(ctx, deleted.ID, time.Now()); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+
+	var ids []string
+	err := repo.Stream(ctx, service.UserFilter{LastName: "Doe"}, func(u model.User) error {
+		ids = append(ids, u.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error streaming users: %v", err)
+	}
+	if fmt.Sprint(ids) != fmt.Sprint(saved) {
+		t.Errorf("expected live users in ID order: %v, result: %v", saved, ids)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = repo.Stream(ctx, service.UserFilter{MinAge: 21}, func(u model.User) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("expected stream to stop at the first error, result: %v after %d calls", err, calls)
+	}
+}
+
+func testConcurrentSaves(t *testing.T, repo service.UserRepository) {
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := repo.Save(context.Background(), newUser("John", "Doe", 20+i))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	saved := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			saved++
+		case !errors.Is(err, service.ErrUsernameTaken):
+			t.Errorf("concurrent saves should fail with %v, result: %v", service.ErrUsernameTaken, err)
+		}
+	}
+	if saved != 1 {
+		t.Errorf("exactly one concurrent save should succeed, result: %d", saved)
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, repo service.UserRepository) {
+	const writers = 10
+	saved := mustSave(t, repo, newUser("John", "Doe", 30))
+	var wg sync.WaitGroup
+	updated := make(chan bool, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			changed := saved
+			changed.Age = 40 + i
+			result, err := repo.Update(context.Background(), changed)
+			if err != nil {
+				t.Errorf("error updating user: %v", err)
+			}
+			updated <- result != nil
+		}(i)
+	}
+	wg.Wait()
+	close(updated)
+	succeeded := 0
+	for ok := range updated {
+		if ok {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("exactly one update of the same version should succeed, result: %d", succeeded)
+	}
+}
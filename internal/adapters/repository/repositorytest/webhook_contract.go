@@ -0,0 +1,180 @@
+package repositorytest
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// NewWebhookRepository returns an empty webhook repository for a single test.
+type NewWebhookRepository func(t *testing.T) service.WebhookRepository
+
+// RunWebhookRepositoryTests runs the WebhookRepository contract against the repositories built by newRepo.
+func RunWebhookRepositoryTests(t *testing.T, newRepo NewWebhookRepository) {
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newRepo) })
+	t.Run("Deliveries", func(t *testing.T) { testDeliveries(t, newRepo) })
+	t.Run("Due deliveries", func(t *testing.T) { testDueDeliveries(t, newRepo) })
+	t.Run("Deleted webhooks", func(t *testing.T) { testDeleteWebhook(t, newRepo) })
+}
+
+// MongoDB stores milliseconds
+var webhookTime = time.Now().UTC().Truncate(time.Millisecond)
+
+func mustCreateWebhook(t *testing.T, repo service.WebhookRepository, url string) *model.Webhook {
+	t.Helper()
+	webhook, err := repo.CreateWebhook(context.Background(), model.Webhook{
+		URL:       url,
+		Events:    []string{model.EventUserCreated, model.EventUserDeleted},
+		Secret:    "0123456789abcdef",
+		CreatedAt: webhookTime,
+	})
+	if err != nil || webhook.ID == "" {
+		t.Fatalf("error creating webhook: %v, %v", webhook, err)
+	}
+	return webhook
+}
+
+func newDelivery(webhookID string, eventID string, nextAttemptAt time.Time) model.Delivery {
+	user := newUser("John", "Doe", 30)
+	user.ID = missingID
+	event := newEvent(model.EventUserCreated, user)
+	event.ID = eventID
+	return model.Delivery{WebhookID: webhookID, Event: event, Status: model.DeliveryPending, NextAttemptAt: nextAttemptAt, CreatedAt: webhookTime}
+}
+
+func mustAddDelivery(t *testing.T, repo service.WebhookRepository, d model.Delivery) {
+	t.Helper()
+	if err := repo.AddDelivery(context.Background(), d); err != nil {
+		t.Fatalf("error adding delivery: %v", err)
+	}
+}
+
+func mustReadDeliveries(t *testing.T, repo service.WebhookRepository, webhookID string, status string, limit int) []model.Delivery {
+	t.Helper()
+	deliveries, err := repo.Deliveries(context.Background(), webhookID, status, limit)
+	if err != nil {
+		t.Fatalf("error reading deliveries: %v", err)
+	}
+	return deliveries
+}
+
+func testWebhooks(t *testing.T, newRepo NewWebhookRepository) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	first := mustCreateWebhook(t, repo, "https://first.example.com")
+	second := mustCreateWebhook(t, repo, "https://second.example.com")
+
+	result, err := repo.FindWebhook(ctx, first.ID)
+	if err != nil || !reflect.DeepEqual(result, first) {
+		t.Errorf("expected: %+v, result: %+v, %v", first, result, err)
+	}
+	webhooks, err := repo.ListWebhooks(ctx)
+	if err != nil || len(webhooks) != 2 || webhooks[0].ID != first.ID || webhooks[1].ID != second.ID {
+		t.Errorf("expected: the two webhooks, oldest first, result: %+v, %v", webhooks, err)
+	}
+	for _, id := range []string{missingID, "invalid"} {
+		if result, err := repo.FindWebhook(ctx, id); err != nil || result != nil {
+			t.Errorf("expected: nil, result: %v, %v", result, err)
+		}
+	}
+}
+
+func testDeliveries(t *testing.T, newRepo NewWebhookRepository) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	webhook := mustCreateWebhook(t, repo, "https://partner.example.com")
+	first := newDelivery(webhook.ID, "1", webhookTime)
+	mustAddDelivery(t, repo, first)
+	mustAddDelivery(t, repo, first)
+	mustAddDelivery(t, repo, newDelivery(webhook.ID, "2", webhookTime))
+
+	deliveries := mustReadDeliveries(t, repo, webhook.ID, "", 10)
+	if len(deliveries) != 2 || deliveries[0].Event.ID != "2" || deliveries[1].Event.ID != "1" {
+		t.Fatalf("expected: 2 deliveries, newest first, result: %+v", deliveries)
+	}
+	stored := deliveries[1]
+	if stored.ID == "" || stored.WebhookID != webhook.ID || stored.Status != model.DeliveryPending || !stored.NextAttemptAt.Equal(first.NextAttemptAt) ||
+		!stored.CreatedAt.Equal(first.CreatedAt) || !stored.Event.OccurredAt.Equal(first.Event.OccurredAt) || stored.Event.User != first.Event.User || len(stored.Attempts) != 0 {
+		t.Errorf("expected: %+v, result: %+v", first, stored)
+	}
+
+	stored.Status = model.DeliveryDead
+	stored.Attempts = []model.DeliveryAttempt{{At: webhookTime, StatusCode: 500, Error: "webhook answered 500", DurationMs: 12}, {At: webhookTime, DurationMs: 3}}
+	if err := repo.SaveDelivery(ctx, stored); err != nil {
+		t.Fatalf("error saving delivery: %v", err)
+	}
+	result, err := repo.FindDelivery(ctx, webhook.ID, stored.ID)
+	if err != nil || result == nil || !reflect.DeepEqual(result.Attempts, stored.Attempts) || result.Status != model.DeliveryDead {
+		t.Errorf("expected: %+v, result: %+v, %v", stored, result, err)
+	}
+	if dead := mustReadDeliveries(t, repo, webhook.ID, model.DeliveryDead, 10); len(dead) != 1 || dead[0].ID != stored.ID {
+		t.Errorf("expected the dead delivery only, result: %+v", dead)
+	}
+	if deliveries := mustReadDeliveries(t, repo, webhook.ID, "", 1); len(deliveries) != 1 {
+		t.Errorf("expected: 1 delivery, result: %+v", deliveries)
+	}
+	for _, ids := range [][2]string{{missingID, stored.ID}, {webhook.ID, missingID}, {webhook.ID, "invalid"}} {
+		if result, err := repo.FindDelivery(ctx, ids[0], ids[1]); err != nil || result != nil {
+			t.Errorf("expected: nil, result: %v, %v", result, err)
+		}
+	}
+}
+
+func testDueDeliveries(t *testing.T, newRepo NewWebhookRepository) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	webhook := mustCreateWebhook(t, repo, "https://partner.example.com")
+	mustAddDelivery(t, repo, newDelivery(webhook.ID, "later", webhookTime.Add(time.Minute)))
+	mustAddDelivery(t, repo, newDelivery(webhook.ID, "second", webhookTime.Add(-time.Second)))
+	mustAddDelivery(t, repo, newDelivery(webhook.ID, "first", webhookTime.Add(-time.Minute)))
+	mustAddDelivery(t, repo, newDelivery(webhook.ID, "now", webhookTime))
+	mustAddDelivery(t, repo, newDelivery(webhook.ID, "done", webhookTime.Add(-time.Hour)))
+	done := mustReadDeliveries(t, repo, webhook.ID, "", 1)[0]
+	done.Status = model.DeliverySucceeded
+	if err := repo.SaveDelivery(ctx, done); err != nil {
+		t.Fatalf("error saving delivery: %v", err)
+	}
+
+	due, err := repo.DueDeliveries(ctx, webhookTime, 10)
+	if err != nil {
+		t.Fatalf("error reading due deliveries: %v", err)
+	}
+	var ids []string
+	for _, delivery := range due {
+		ids = append(ids, delivery.Event.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"first", "second", "now"}) {
+		t.Errorf("expected: the due pending deliveries, earliest first, result: %v", ids)
+	}
+	if due, _ := repo.DueDeliveries(ctx, webhookTime, 1); len(due) != 1 || due[0].Event.ID != "first" {
+		t.Errorf("expected: the earliest delivery, result: %+v", due)
+	}
+}
+
+func testDeleteWebhook(t *testing.T, newRepo NewWebhookRepository) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	webhook := mustCreateWebhook(t, repo, "https://partner.example.com")
+	other := mustCreateWebhook(t, repo, "https://other.example.com")
+	mustAddDelivery(t, repo, newDelivery(webhook.ID, "1", webhookTime))
+	mustAddDelivery(t, repo, newDelivery(other.ID, "1", webhookTime))
+
+	if deleted, err := repo.DeleteWebhook(ctx, webhook.ID); err != nil || !deleted {
+		t.Fatalf("expected: deleted webhook, result: %v, %v", deleted, err)
+	}
+	if result, _ := repo.FindWebhook(ctx, webhook.ID); result != nil {
+		t.Errorf("expected: nil, result: %+v", result)
+	}
+	if deliveries := mustReadDeliveries(t, repo, webhook.ID, "", 10); len(deliveries) != 0 {
+		t.Errorf("deliveries should be deleted with their webhook, result: %+v", deliveries)
+	}
+	if due, _ := repo.DueDeliveries(ctx, webhookTime, 10); len(due) != 1 || due[0].WebhookID != other.ID {
+		t.Errorf("expected the delivery of the other webhook only, result: %+v", due)
+	}
+	if deleted, err := repo.DeleteWebhook(ctx, webhook.ID); err != nil || deleted {
+		t.Errorf("expected: not deleted, result: %v, %v", deleted, err)
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"embed"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"io/fs"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// sqlDialect holds what differs between the SQL databases backing the SQL repositories.
+// Queries are written with ? placeholders and rebound to the dialect.
+type sqlDialect struct {
+	name              string
+	migrations        fs.FS
+	numberedParams    bool
+	isUniqueViolation func(err error) bool
+}
+
+var postgresDialect = sqlDialect{
+	name:           "postgres",
+	migrations:     mustSub(migrationFiles, "migrations/postgres"),
+	numberedParams: true,
+	isUniqueViolation: func(err error) bool {
+		var pgErr *pgconn.PgError
+		return errors.As(err, &pgErr) && pgErr.Code == "23505"
+	},
+}
+
+var sqliteDialect = sqlDialect{
+	name:       "sqlite",
+	migrations: mustSub(migrationFiles, "migrations/sqlite"),
+	isUniqueViolation: func(err error) bool {
+		var sqliteErr *sqlite.Error
+		return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	},
+}
+
+// rebind replaces the ? placeholders of query with $1, $2... when the dialect uses numbered parameters.
+func (d sqlDialect) rebind(query string) string {
+	if !d.numberedParams {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// migrateSQL applies, in file name order, the dialect migrations not yet recorded in schema_migrations.
+// Each migration runs in its own transaction.
+func migrateSQL(ctx context.Context, db *sql.DB, d sqlDialect) error {
+	createTable := `CREATE TABLE IF NOT EXISTS schema_migrations (version TEXT PRIMARY KEY, applied_at TIMESTAMP NOT NULL)`
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	names, err := fs.Glob(d.migrations, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if applied[name] {
+			continue
+		}
+		slog.InfoContext(ctx, "applying sql migration", "dialect", d.name, "migration", name)
+		if err := applySQLMigration(ctx, db, d, name); err != nil {
+			slog.ErrorContext(ctx, "failed to apply sql migration", "dialect", d.name, "migration", name, "error", err)
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func applySQLMigration(ctx context.Context, db *sql.DB, d sqlDialect, name string) error {
+	script, err := fs.ReadFile(d.migrations, name)
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+		return err
+	}
+	insert := d.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`)
+	if _, err := tx.ExecContext(ctx, insert, name, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type sqlTxKey struct{}
+
+// sqlConn is implemented by both *sql.DB and *sql.Tx.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQLTransactor runs transactions on a SQL database. The transaction travels in the context,
+// SQL repositories use it when they find one.
+type SQLTransactor struct {
+	db *sql.DB
+}
+
+func NewSQLTransactor(db *sql.DB) *SQLTransactor {
+	return &SQLTransactor{db: db}
+}
+
+func (t *SQLTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(sqlTxKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, sqlTxKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqlConnFrom returns the transaction of ctx, or db outside transactions.
+func sqlConnFrom(ctx context.Context, db *sql.DB) sqlConn {
+	if tx, ok := ctx.Value(sqlTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of the repository operations from the global tracer provider.
+var tracer = otel.Tracer("github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/repository")
+
+// startMongoSpan starts the span of a repository method sending the operation command to collection.
+func startMongoSpan(ctx context.Context, name string, operation string, collection string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemMongoDB, semconv.DBOperation(operation), semconv.DBMongoDBCollection(collection),
+	))
+}
+
+// endSpan ends span, given the address of the method error result so that it can be deferred.
+// Taken usernames are expected outcomes, they do not mark the span as failed.
+func endSpan(span trace.Span, err *error) {
+	if *err != nil && !errors.Is(*err, service.ErrUsernameTaken) {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
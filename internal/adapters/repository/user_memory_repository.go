@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errDuplicateID = errors.New("user id already exists")
+
+// UserMemoryRepository keeps users in memory, with the same semantics as UserMongoRepository:
+// ObjectID hex identifiers, unique live user names and nil results for unknown or invalid IDs.
+// It is safe for concurrent use.
+type UserMemoryRepository struct {
+	mu                   sync.RWMutex
+	users                map[string]model.User
+	caseInsensitiveNames bool
+}
+
+func NewUserMemoryRepo(caseInsensitiveNames bool) *UserMemoryRepository {
+	return &UserMemoryRepository{users: map[string]model.User{}, caseInsensitiveNames: caseInsensitiveNames}
+}
+
+func (ur *UserMemoryRepository) FindById(_ context.Context, id string) (*model.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	user, ok := ur.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (ur *UserMemoryRepository) Save(ctx context.Context, u model.User) (*model.User, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	if u.ID == "" {
+		u.ID = primitive.NewObjectID().Hex()
+	}
+	if _, exists := ur.users[u.ID]; exists {
+		return nil, errDuplicateID
+	}
+	if ur.nameTaken(u) {
+		return nil, service.ErrUsernameTaken
+	}
+	ur.remember(ctx, u.ID)
+	ur.users[u.ID] = u
+	return &u, nil
+}
+
+func (ur *UserMemoryRepository) Update(ctx context.Context, u model.User) (*model.User, error) {
+	return ur.UpdateFields(ctx, u, []string{model.FieldFirstName, model.FieldLastName, model.FieldEmail, model.FieldAge})
+}
+
+func (ur *UserMemoryRepository) UpdateFields(ctx context.Context, u model.User, fields []string) (*model.User, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	user, ok := ur.users[u.ID]
+	if !ok || user.DeletedAt != nil || (u.Version != 0 && u.Version != user.Version) {
+		return nil, nil
+	}
+	for _, field := range fields {
+		switch field {
+		case model.FieldFirstName:
+			user.FirstName = u.FirstName
+		case model.FieldLastName:
+			user.LastName = u.LastName
+		case model.FieldEmail:
+			user.Email = u.Email
+		case model.FieldAge:
+			user.Age = u.Age
+		}
+	}
+	if ur.nameTaken(user) {
+		return nil, service.ErrUsernameTaken
+	}
+	ur.remember(ctx, user.ID)
+	user.Version++
+	ur.users[user.ID] = user
+	return &user, nil
+}
+
+func (ur *UserMemoryRepository) ExistsByFirstNameAndLastName(_ context.Context, u model.User) (bool, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	return ur.nameTaken(u), nil
+}
+
+func (ur *UserMemoryRepository) List(_ context.Context, q service.UserQuery) ([]model.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	users := []model.User{}
+	for _, user := range ur.users {
+		if matchesUserFilter(user, q.Filter) && (q.After == nil || isAfterCursor(user, q.Sort, *q.After)) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return compareBySort(users[i], q.Sort, sortKey(users[j], q.Sort.Field), users[j].ID) < 0
+	})
+	if len(users) > q.Limit {
+		users = users[:q.Limit]
+	}
+	return users, nil
+}
+
+func (ur *UserMemoryRepository) Count(_ context.Context, f service.UserFilter) (int64, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	var count int64
+	for _, user := range ur.users {
+		if matchesUserFilter(user, f) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Stream calls fn with a copy of the matching users taken when it starts, so fn may use the repository.
+func (ur *UserMemoryRepository) Stream(ctx context.Context, f service.UserFilter, fn func(u model.User) error) error {
+	users, err := ur.List(ctx, service.UserQuery{Filter: f, Limit: math.MaxInt})
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ur *UserMemoryRepository) FindDeletedById(_ context.Context, id string) (*model.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	user, ok := ur.users[id]
+	if !ok || user.DeletedAt == nil {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (ur *UserMemoryRepository) SoftDelete(ctx context.Context, id string, at time.Time) (bool, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	user, ok := ur.users[id]
+	if !ok || user.DeletedAt != nil {
+		return false, nil
+	}
+	ur.remember(ctx, id)
+	user.DeletedAt = &at
+	ur.users[id] = user
+	return true, nil
+}
+
+func (ur *UserMemoryRepository) Delete(ctx context.Context, id string) (bool, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	if _, ok := ur.users[id]; !ok {
+		return false, nil
+	}
+	ur.remember(ctx, id)
+	delete(ur.users, id)
+	return true, nil
+}
+
+func (ur *UserMemoryRepository) Restore(ctx context.Context, id string) (*model.User, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	user, ok := ur.users[id]
+	if !ok || user.DeletedAt == nil {
+		return nil, nil
+	}
+	if ur.nameTaken(user) {
+		return nil, service.ErrUsernameTaken
+	}
+	ur.remember(ctx, id)
+	user.DeletedAt = nil
+	ur.users[id] = user
+	return &user, nil
+}
+
+// remember registers the restoration of the user id, as it is before a write, in case the transaction of ctx
+// is rolled back. Callers must hold the lock.
+func (ur *UserMemoryRepository) remember(ctx context.Context, id string) {
+	user, existed := ur.users[id]
+	onRollback(ctx, func() {
+		ur.mu.Lock()
+		defer ur.mu.Unlock()
+		if existed {
+			ur.users[id] = user
+		} else {
+			delete(ur.users, id)
+		}
+	})
+}
+
+// nameTaken reports whether another live user has the same name as u. Callers must hold the lock.
+func (ur *UserMemoryRepository) nameTaken(u model.User) bool {
+	for _, user := range ur.users {
+		if user.ID != u.ID && user.DeletedAt == nil && ur.sameName(user.FirstName, u.FirstName) && ur.sameName(user.LastName, u.LastName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ur *UserMemoryRepository) sameName(a, b string) bool {
+	if ur.caseInsensitiveNames {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func matchesUserFilter(u model.User, f service.UserFilter) bool {
+	return u.DeletedAt == nil &&
+		(f.FirstName == "" || u.FirstName == f.FirstName) &&
+		(f.LastName == "" || u.LastName == f.LastName) &&
+		(f.Email == "" || u.Email == f.Email) &&
+		(f.MinAge == 0 || u.Age >= f.MinAge) &&
+		(f.MaxAge == 0 || u.Age <= f.MaxAge)
+}
+
+func isAfterCursor(u model.User, s service.UserSort, c service.Cursor) bool {
+	if s.Field == service.SortByAge {
+		age, err := strconv.Atoi(c.Value)
+		if err != nil {
+			return false
+		}
+		return compareBySort(u, s, age, c.ID) > 0
+	}
+	return compareBySort(u, s, c.Value, c.ID) > 0
+}
+
+// compareBySort compares u with the user whose sort field value is key and whose ID is id, in the order of s.
+func compareBySort(u model.User, s service.UserSort, key any, id string) int {
+	result := 0
+	switch k := sortKey(u, s.Field).(type) {
+	case int:
+		other, _ := key.(int)
+		result = k - other
+	case string:
+		other, _ := key.(string)
+		result = strings.Compare(k, other)
+	}
+	if result == 0 {
+		result = strings.Compare(u.ID, id)
+	}
+	if s.Desc {
+		return -result
+	}
+	return result
+}
+
+func sortKey(u model.User, field string) any {
+	switch field {
+	case service.SortByFirstName:
+		return u.FirstName
+	case service.SortByLastName:
+		return u.LastName
+	case service.SortByEmail:
+		return u.Email
+	case service.SortByAge:
+		return u.Age
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/repository/repositorytest"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"testing"
+)
+
+func TestUserMemoryRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, func(t *testing.T) service.UserRepository {
+		return NewUserMemoryRepo(false)
+	})
+}
+
+func TestMemoryTransactor(t *testing.T) {
+	repositorytest.RunTransactorTests(t, func(t *testing.T) (service.UserRepository, service.Transactor) {
+		return NewUserMemoryRepo(false), NewMemoryTransactor()
+	})
+}
+
+func TestAuditMemoryRepository(t *testing.T) {
+	repositorytest.RunAuditLogTests(t, func(t *testing.T) (service.AuditLog, service.Transactor) {
+		return NewAuditMemoryRepo(), NewMemoryTransactor()
+	})
+}
+
+func TestOutboxMemoryRepository(t *testing.T) {
+	repositorytest.RunOutboxTests(t, func(t *testing.T) (service.Outbox, service.Transactor) {
+		return NewOutboxMemoryRepo(), NewMemoryTransactor()
+	})
+}
+
+func TestWebhookMemoryRepository(t *testing.T) {
+	repositorytest.RunWebhookRepositoryTests(t, func(t *testing.T) service.WebhookRepository {
+		return NewWebhookMemoryRepo()
+	})
+}
+
+func TestIdempotencyMemoryRepository(t *testing.T) {
+	repositorytest.RunIdempotencyStoreTests(t, func(t *testing.T) service.IdempotencyStore {
+		return NewIdempotencyMemoryRepo()
+	})
+}
+
+func TestUserMemoryRepository_CaseInsensitiveNames(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserMemoryRepo(true)
+	_, _ = repo.Save(ctx, model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30})
+
+	_, err := repo.Save(ctx, model.User{FirstName: "JOHN", LastName: "doe", Email: "john@doe.com", Age: 30})
+	if !errors.Is(err, service.ErrUsernameTaken) {
+		t.Errorf("expected: %v, result: %v", service.ErrUsernameTaken, err)
+	}
+}
+
+func TestMemoryTransactor_RollbackKeepsOtherWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserMemoryRepo(false)
+	kept, _ := repo.Save(ctx, model.User{FirstName: "Jane", LastName: "Doe", Email: "jane@doe.com", Age: 30})
+	rollback := errors.New("rollback")
+
+	err := NewMemoryTransactor().WithinTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := repo.Save(txCtx, model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30}); err != nil {
+			return err
+		}
+		if _, err := repo.Save(ctx, model.User{FirstName: "Mary", LastName: "Doe", Email: "mary@doe.com", Age: 30}); err != nil {
+			return err
+		}
+		return rollback
+	})
+
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected: %v, result: %v", rollback, err)
+	}
+	count, _ := repo.Count(ctx, service.UserFilter{})
+	if count != 2 {
+		t.Errorf("only the transaction writes should be rolled back, users: %d", count)
+	}
+	if found, _ := repo.FindById(ctx, kept.ID); found == nil {
+		t.Errorf("user saved before the transaction should be kept")
+	}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+)
+
+const uniqueNameIndex = "unique_first_last_name"
+
+// mongoMigration is a schema change of the users collection. Migrations are idempotent,
+// they run in order on every startup and only change what is not already in place.
+type mongoMigration struct {
+	name string
+	up   func(ctx context.Context, ur *UserMongoRepository) error
+}
+
+var userMongoMigrations = []mongoMigration{
+	{name: "create listing indexes", up: createListingIndexes},
+	{name: "backfill user versions", up: backfillVersions},
+	{name: "enforce unique user names", up: ensureUniqueNameIndex},
+}
+
+// Migrate applies the users collection migrations. It fails when the data does not allow them,
+// e.g. when two users already share the same first and last name.
+func (ur *UserMongoRepository) Migrate(ctx context.Context) error {
+	for _, migration := range userMongoMigrations {
+		slog.InfoContext(ctx, "applying mongodb migration", "migration", migration.name)
+		if err := migration.up(ctx, ur); err != nil {
+			slog.ErrorContext(ctx, "failed to apply mongodb migration", "migration", migration.name, "error", err)
+			return fmt.Errorf("migration %q: %w", migration.name, err)
+		}
+	}
+	return nil
+}
+
+// createListingIndexes creates an index for every sortable listing field, with _id as tie-breaker.
+func createListingIndexes(ctx context.Context, ur *UserMongoRepository) error {
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "firstName", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "lastName", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "age", Value: 1}, {Key: "_id", Value: 1}}},
+	}
+	_, err := ur.db.Collection(userCollection).Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// backfillVersions gives users created before optimistic concurrency control their first version, so that
+// their ETag can be sent back in If-Match: version 0 stands for any version. $in with nil also matches users
+// without version.
+func backfillVersions(ctx context.Context, ur *UserMongoRepository) error {
+	filter := bson.M{"version": bson.M{"$in": bson.A{nil, 0}}}
+	_, err := ur.db.Collection(userCollection).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"version": 1}})
+	return err
+}
+
+// ensureUniqueNameIndex makes the database reject two live users with the same first and last name.
+// deletedAt is part of the key so soft-deleted users never conflict with live ones.
+// The index is recreated when the configured name collation changed.
+func ensureUniqueNameIndex(ctx context.Context, ur *UserMongoRepository) error {
+	indexes := ur.db.Collection(userCollection).Indexes()
+	cursor, err := indexes.List(ctx)
+	if err != nil {
+		return err
+	}
+	var existing []struct {
+		Name      string             `bson:"name"`
+		Collation *options.Collation `bson:"collation"`
+	}
+	if err := cursor.All(ctx, &existing); err != nil {
+		return err
+	}
+	for _, index := range existing {
+		// superseded by the unique index
+		if index.Name == "firstName_1_lastName_1" {
+			if _, err := indexes.DropOne(ctx, index.Name); err != nil {
+				return err
+			}
+		}
+		if index.Name == uniqueNameIndex {
+			if sameCollation(index.Collation, ur.nameCollation) {
+				return nil
+			}
+			if _, err := indexes.DropOne(ctx, index.Name); err != nil {
+				return err
+			}
+		}
+	}
+	opts := options.Index().SetName(uniqueNameIndex).SetUnique(true)
+	if ur.nameCollation != nil {
+		opts.SetCollation(ur.nameCollation)
+	}
+	_, err = indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "firstName", Value: 1}, {Key: "lastName", Value: 1}, {Key: "deletedAt", Value: 1}},
+		Options: opts,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return errors.New("users with the same first and last name must be renamed or deleted first")
+	}
+	return err
+}
+
+func sameCollation(a, b *options.Collation) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Locale == b.Locale && a.Strength == b.Strength
+}
@@ -4,45 +4,61 @@ import (
 	"context"
 	"errors"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log/slog"
+	"strconv"
+	"time"
 )
 
 const userCollection = "users"
 
 type UserMongoRepository struct {
 	db *mongo.Database
+	// nameCollation is used by the unique name index and the name lookups, nil means case-sensitive names.
+	nameCollation *options.Collation
 }
 
-func NewUserRepo(db *mongo.Database) *UserMongoRepository {
-	return &UserMongoRepository{db: db}
+func NewUserRepo(db *mongo.Database, caseInsensitiveNames bool) *UserMongoRepository {
+	ur := &UserMongoRepository{db: db}
+	if caseInsensitiveNames {
+		ur.nameCollation = &options.Collation{Locale: "en", Strength: 2}
+	}
+	return ur
 }
 
-func (ur *UserMongoRepository) FindById(ctx context.Context, id string) (*model.User, error) {
+func (ur *UserMongoRepository) FindById(ctx context.Context, id string) (_ *model.User, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.FindById", "findOne", userCollection)
+	defer endSpan(span, &err)
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		slog.Error("converting user id from request to object id.", "error", err)
+		slog.ErrorContext(ctx, "converting user id from request to object id.", "error", err)
 		return nil, nil
 	}
-	filter := bson.D{{"_id", oid}}
+	filter := bson.D{{Key: "_id", Value: oid}, {Key: "deletedAt", Value: nil}}
 	var user *model.User
 	err = ur.db.Collection(userCollection).FindOne(ctx, filter).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
 		}
-		slog.Error("failed to decode FindOne result", "error", err)
+		slog.ErrorContext(ctx, "failed to decode FindOne result", "error", err)
 		return nil, err
 	}
 	return user, nil
 }
-func (ur *UserMongoRepository) Save(ctx context.Context, u model.User) (*model.User, error) {
+func (ur *UserMongoRepository) Save(ctx context.Context, u model.User) (_ *model.User, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.Save", "insertOne", userCollection)
+	defer endSpan(span, &err)
 	result, err := ur.db.Collection(userCollection).InsertOne(ctx, u)
 	if err != nil {
-		slog.Error("failed to insert user", "error", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, service.ErrUsernameTaken
+		}
+		slog.ErrorContext(ctx, "failed to insert user", "error", err)
 		return nil, err
 	}
 	u.ID = result.InsertedID.(primitive.ObjectID).Hex()
@@ -50,28 +66,55 @@ func (ur *UserMongoRepository) Save(ctx context.Context, u model.User) (*model.U
 }
 
 func (ur *UserMongoRepository) Update(ctx context.Context, u model.User) (*model.User, error) {
+	return ur.UpdateFields(ctx, u, []string{model.FieldFirstName, model.FieldLastName, model.FieldEmail, model.FieldAge})
+}
+
+// UpdateFields sets only the given fields of the stored user to the values they have in u.
+func (ur *UserMongoRepository) UpdateFields(ctx context.Context, u model.User, fields []string) (_ *model.User, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.UpdateFields", "findOneAndUpdate", userCollection)
+	defer endSpan(span, &err)
 	oid, err := primitive.ObjectIDFromHex(u.ID)
 	if err != nil {
-		return nil, err
+		return nil, nil
+	}
+	set := bson.D{}
+	for _, field := range fields {
+		switch field {
+		case model.FieldFirstName:
+			set = append(set, bson.E{Key: field, Value: u.FirstName})
+		case model.FieldLastName:
+			set = append(set, bson.E{Key: field, Value: u.LastName})
+		case model.FieldEmail:
+			set = append(set, bson.E{Key: field, Value: u.Email})
+		case model.FieldAge:
+			set = append(set, bson.E{Key: field, Value: u.Age})
+		}
 	}
 	updatedUser := &model.User{}
-	filter := bson.D{{"_id", oid}}
+	filter := bson.D{{Key: "_id", Value: oid}, {Key: "deletedAt", Value: nil}}
+	if u.Version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: u.Version})
+	}
 	ret := options.ReturnDocument(1)
 	opts := options.FindOneAndUpdateOptions{ReturnDocument: &ret}
-	update := bson.D{{"$set", bson.D{{"firstName", u.FirstName}, {"lastName", u.LastName}, {"email", u.Email}, {"age", u.Age}}}}
+	update := bson.D{{Key: "$set", Value: set}, {Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}}
 	err = ur.db.Collection(userCollection).FindOneAndUpdate(ctx, filter, update, &opts).Decode(updatedUser)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
 		}
-		slog.Error("failed to decode FindOneAndUpdate result", "error", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, service.ErrUsernameTaken
+		}
+		slog.ErrorContext(ctx, "failed to decode FindOneAndUpdate result", "error", err)
 		return nil, err
 	}
 	return updatedUser, nil
 }
-func (ur *UserMongoRepository) ExistsByFirstNameAndLastName(ctx context.Context, u model.User) (bool, error) {
+func (ur *UserMongoRepository) ExistsByFirstNameAndLastName(ctx context.Context, u model.User) (_ bool, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.ExistsByFirstNameAndLastName", "findOne", userCollection)
+	defer endSpan(span, &err)
 	var oid primitive.ObjectID
-	var err error
 	if len(u.ID) != 0 {
 		oid, err = primitive.ObjectIDFromHex(u.ID)
 		if err != nil {
@@ -79,17 +122,19 @@ func (ur *UserMongoRepository) ExistsByFirstNameAndLastName(ctx context.Context,
 		}
 	}
 	filter := bson.D{
-		{"firstName", u.FirstName},
-		{"lastName", u.LastName},
-		{"_id", bson.D{{"$ne", oid}}},
+		{Key: "firstName", Value: u.FirstName},
+		{Key: "lastName", Value: u.LastName},
+		{Key: "_id", Value: bson.D{{Key: "$ne", Value: oid}}},
+		{Key: "deletedAt", Value: nil},
 	}
 	var user *model.User
-	err = ur.db.Collection(userCollection).FindOne(ctx, filter).Decode(&user)
+	opts := options.FindOne().SetCollation(ur.nameCollation)
+	err = ur.db.Collection(userCollection).FindOne(ctx, filter, opts).Decode(&user)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return false, nil
 		}
-		slog.Error("failed to decode FindOne result", "error", err)
+		slog.ErrorContext(ctx, "failed to decode FindOne result", "error", err)
 		return false, err
 	}
 	if user != nil {
@@ -97,3 +142,204 @@ func (ur *UserMongoRepository) ExistsByFirstNameAndLastName(ctx context.Context,
 	}
 	return false, nil
 }
+
+func (ur *UserMongoRepository) FindDeletedById(ctx context.Context, id string) (_ *model.User, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.FindDeletedById", "findOne", userCollection)
+	defer endSpan(span, &err)
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	filter := bson.D{{Key: "_id", Value: oid}, {Key: "deletedAt", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	var user *model.User
+	err = ur.db.Collection(userCollection).FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		slog.ErrorContext(ctx, "failed to decode FindOne result", "error", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (ur *UserMongoRepository) SoftDelete(ctx context.Context, id string, at time.Time) (_ bool, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.SoftDelete", "updateOne", userCollection)
+	defer endSpan(span, &err)
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	filter := bson.D{{Key: "_id", Value: oid}, {Key: "deletedAt", Value: nil}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "deletedAt", Value: at}}}}
+	result, err := ur.db.Collection(userCollection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to soft delete user", "error", err)
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (ur *UserMongoRepository) Delete(ctx context.Context, id string) (_ bool, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.Delete", "deleteOne", userCollection)
+	defer endSpan(span, &err)
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	result, err := ur.db.Collection(userCollection).DeleteOne(ctx, bson.D{{Key: "_id", Value: oid}})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to delete user", "error", err)
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (ur *UserMongoRepository) Restore(ctx context.Context, id string) (_ *model.User, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.Restore", "findOneAndUpdate", userCollection)
+	defer endSpan(span, &err)
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	restoredUser := &model.User{}
+	filter := bson.D{{Key: "_id", Value: oid}, {Key: "deletedAt", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "deletedAt", Value: ""}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = ur.db.Collection(userCollection).FindOneAndUpdate(ctx, filter, update, opts).Decode(restoredUser)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, service.ErrUsernameTaken
+		}
+		slog.ErrorContext(ctx, "failed to decode FindOneAndUpdate result", "error", err)
+		return nil, err
+	}
+	return restoredUser, nil
+}
+
+func (ur *UserMongoRepository) List(ctx context.Context, q service.UserQuery) (_ []model.User, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.List", "find", userCollection)
+	defer endSpan(span, &err)
+	filter := userFilter(q.Filter)
+	if q.After != nil {
+		after, err := afterCursor(q.Sort, *q.After)
+		if err != nil {
+			return nil, err
+		}
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, after}}}
+	}
+	direction := 1
+	if q.Sort.Desc {
+		direction = -1
+	}
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if field := sortField(q.Sort.Field); field != "_id" {
+		sort = append(bson.D{{Key: field, Value: direction}}, sort...)
+	}
+	opts := options.Find().SetSort(sort).SetLimit(int64(q.Limit))
+	cursor, err := ur.db.Collection(userCollection).Find(ctx, filter, opts)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to find users", "error", err)
+		return nil, err
+	}
+	users := []model.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		slog.ErrorContext(ctx, "failed to decode Find result", "error", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+func (ur *UserMongoRepository) Count(ctx context.Context, f service.UserFilter) (_ int64, err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.Count", "countDocuments", userCollection)
+	defer endSpan(span, &err)
+	count, err := ur.db.Collection(userCollection).CountDocuments(ctx, userFilter(f))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to count users", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (ur *UserMongoRepository) Stream(ctx context.Context, f service.UserFilter, fn func(u model.User) error) (err error) {
+	ctx, span := startMongoSpan(ctx, "UserMongoRepository.Stream", "find", userCollection)
+	defer endSpan(span, &err)
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := ur.db.Collection(userCollection).Find(ctx, userFilter(f), opts)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to find users", "error", err)
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil {
+			slog.ErrorContext(ctx, "failed to decode Find result", "error", err)
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func userFilter(f service.UserFilter) bson.D {
+	filter := bson.D{{Key: "deletedAt", Value: nil}}
+	if f.FirstName != "" {
+		filter = append(filter, bson.E{Key: "firstName", Value: f.FirstName})
+	}
+	if f.LastName != "" {
+		filter = append(filter, bson.E{Key: "lastName", Value: f.LastName})
+	}
+	if f.Email != "" {
+		filter = append(filter, bson.E{Key: "email", Value: f.Email})
+	}
+	age := bson.D{}
+	if f.MinAge > 0 {
+		age = append(age, bson.E{Key: "$gte", Value: f.MinAge})
+	}
+	if f.MaxAge > 0 {
+		age = append(age, bson.E{Key: "$lte", Value: f.MaxAge})
+	}
+	if len(age) > 0 {
+		filter = append(filter, bson.E{Key: "age", Value: age})
+	}
+	return filter
+}
+
+// afterCursor matches the users that come after the cursor in the given sort order, using _id as tie-breaker.
+func afterCursor(sort service.UserSort, c service.Cursor) (bson.D, error) {
+	oid, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return nil, service.ValidationError{Message: "invalid cursor"}
+	}
+	op := "$gt"
+	if sort.Desc {
+		op = "$lt"
+	}
+	field := sortField(sort.Field)
+	if field == "_id" {
+		return bson.D{{Key: "_id", Value: bson.D{{Key: op, Value: oid}}}}, nil
+	}
+	var value any = c.Value
+	if field == "age" {
+		if value, err = strconv.Atoi(c.Value); err != nil {
+			return nil, service.ValidationError{Message: "invalid cursor"}
+		}
+	}
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: field, Value: bson.D{{Key: op, Value: value}}}},
+		bson.D{{Key: field, Value: value}, {Key: "_id", Value: bson.D{{Key: op, Value: oid}}}},
+	}}}, nil
+}
+
+func sortField(field string) string {
+	if field == service.SortByID || field == "" {
+		return "_id"
+	}
+	return field
+}
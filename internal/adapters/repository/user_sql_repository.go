@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const userColumns = "id, first_name, last_name, email, age, version, deleted_at"
+
+var userSQLColumns = map[string]string{
+	model.FieldFirstName: "first_name",
+	model.FieldLastName:  "last_name",
+	model.FieldEmail:     "email",
+	model.FieldAge:       "age",
+}
+
+// UserSQLRepository stores users in a SQL database. IDs are ObjectID hex strings,
+// so users keep the same identifiers and ordering as in UserMongoRepository.
+type UserSQLRepository struct {
+	db                   *sql.DB
+	dialect              sqlDialect
+	caseInsensitiveNames bool
+}
+
+func NewUserPostgresRepo(db *sql.DB, caseInsensitiveNames bool) *UserSQLRepository {
+	return &UserSQLRepository{db: db, dialect: postgresDialect, caseInsensitiveNames: caseInsensitiveNames}
+}
+
+// NewUserSQLiteRepo stores users in the SQLite database db, which should be opened with config.ConnectSQLite.
+func NewUserSQLiteRepo(db *sql.DB, caseInsensitiveNames bool) *UserSQLRepository {
+	return &UserSQLRepository{db: db, dialect: sqliteDialect, caseInsensitiveNames: caseInsensitiveNames}
+}
+
+// Migrate applies the schema migrations and makes the unique name index match the name case sensitivity.
+func (ur *UserSQLRepository) Migrate(ctx context.Context) error {
+	if err := migrateSQL(ctx, ur.db, ur.dialect); err != nil {
+		return err
+	}
+	index, staleIndex, columns := "users_unique_name", "users_unique_name_ci", "first_name, last_name"
+	if ur.caseInsensitiveNames {
+		index, staleIndex, columns = staleIndex, index, "lower(first_name), lower(last_name)"
+	}
+	if _, err := ur.db.ExecContext(ctx, "DROP INDEX IF EXISTS "+staleIndex); err != nil {
+		return err
+	}
+	createIndex := "CREATE UNIQUE INDEX IF NOT EXISTS " + index + " ON users (" + columns + ") WHERE deleted_at IS NULL"
+	if _, err := ur.db.ExecContext(ctx, createIndex); err != nil {
+		slog.ErrorContext(ctx, "failed to create unique name index", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (ur *UserSQLRepository) FindById(ctx context.Context, id string) (*model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ? AND deleted_at IS NULL"
+	return ur.queryUser(ctx, query, id)
+}
+
+func (ur *UserSQLRepository) Save(ctx context.Context, u model.User) (*model.User, error) {
+	if u.ID == "" {
+		u.ID = primitive.NewObjectID().Hex()
+	}
+	query := ur.dialect.rebind("INSERT INTO users (" + userColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?)")
+	_, err := ur.conn(ctx).ExecContext(ctx, query, u.ID, u.FirstName, u.LastName, u.Email, u.Age, u.Version, nullTime(u.DeletedAt))
+	if err != nil {
+		if ur.dialect.isUniqueViolation(err) {
+			return nil, service.ErrUsernameTaken
+		}
+		slog.ErrorContext(ctx, "failed to insert user", "error", err)
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (ur *UserSQLRepository) Update(ctx context.Context, u model.User) (*model.User, error) {
+	return ur.UpdateFields(ctx, u, []string{model.FieldFirstName, model.FieldLastName, model.FieldEmail, model.FieldAge})
+}
+
+func (ur *UserSQLRepository) UpdateFields(ctx context.Context, u model.User, fields []string) (*model.User, error) {
+	var set []string
+	var args []any
+	for _, field := range fields {
+		column, ok := userSQLColumns[field]
+		if !ok {
+			continue
+		}
+		set = append(set, column+" = ?")
+		switch field {
+		case model.FieldFirstName:
+			args = append(args, u.FirstName)
+		case model.FieldLastName:
+			args = append(args, u.LastName)
+		case model.FieldEmail:
+			args = append(args, u.Email)
+		case model.FieldAge:
+			args = append(args, u.Age)
+		}
+	}
+	set = append(set, "version = version + 1")
+	query := "UPDATE users SET " + strings.Join(set, ", ") + " WHERE id = ? AND deleted_at IS NULL"
+	args = append(args, u.ID)
+	if u.Version != 0 {
+		query += " AND version = ?"
+		args = append(args, u.Version)
+	}
+	return ur.queryUser(ctx, query+" RETURNING "+userColumns, args...)
+}
+
+func (ur *UserSQLRepository) ExistsByFirstNameAndLastName(ctx context.Context, u model.User) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM users WHERE first_name = ? AND last_name = ? AND id <> ? AND deleted_at IS NULL)"
+	if ur.caseInsensitiveNames {
+		query = "SELECT EXISTS (SELECT 1 FROM users WHERE lower(first_name) = lower(?) AND lower(last_name) = lower(?) AND id <> ? AND deleted_at IS NULL)"
+	}
+	var exists bool
+	if err := ur.conn(ctx).QueryRowContext(ctx, ur.dialect.rebind(query), u.FirstName, u.LastName, u.ID).Scan(&exists); err != nil {
+		slog.ErrorContext(ctx, "failed to check user name", "error", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+func (ur *UserSQLRepository) List(ctx context.Context, q service.UserQuery) ([]model.User, error) {
+	where, args := userWhere(q.Filter)
+	column, direction, op := "id", "ASC", ">"
+	if q.Sort.Field != service.SortByID && q.Sort.Field != "" {
+		column = userSQLColumns[q.Sort.Field]
+	}
+	if q.Sort.Desc {
+		direction, op = "DESC", "<"
+	}
+	if q.After != nil {
+		if column == "id" {
+			where = append(where, "id "+op+" ?")
+			args = append(args, q.After.ID)
+		} else {
+			var value any = q.After.Value
+			if q.Sort.Field == service.SortByAge {
+				age, err := strconv.Atoi(q.After.Value)
+				if err != nil {
+					return nil, service.ValidationError{Message: "invalid cursor"}
+				}
+				value = age
+			}
+			where = append(where, "("+column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?))")
+			args = append(args, value, value, q.After.ID)
+		}
+	}
+	order := column + " " + direction
+	if column != "id" {
+		order += ", id " + direction
+	}
+	query := "SELECT " + userColumns + " FROM users WHERE " + strings.Join(where, " AND ") + " ORDER BY " + order + " LIMIT ?"
+	rows, err := ur.conn(ctx).QueryContext(ctx, ur.dialect.rebind(query), append(args, q.Limit)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list users", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	users := []model.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (ur *UserSQLRepository) Count(ctx context.Context, f service.UserFilter) (int64, error) {
+	where, args := userWhere(f)
+	var count int64
+	query := ur.dialect.rebind("SELECT COUNT(*) FROM users WHERE " + strings.Join(where, " AND "))
+	if err := ur.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		slog.ErrorContext(ctx, "failed to count users", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (ur *UserSQLRepository) Stream(ctx context.Context, f service.UserFilter, fn func(u model.User) error) error {
+	where, args := userWhere(f)
+	query := ur.dialect.rebind("SELECT " + userColumns + " FROM users WHERE " + strings.Join(where, " AND ") + " ORDER BY id")
+	rows, err := ur.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to stream users", "error", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(*user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (ur *UserSQLRepository) FindDeletedById(ctx context.Context, id string) (*model.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = ? AND deleted_at IS NOT NULL"
+	return ur.queryUser(ctx, query, id)
+}
+
+func (ur *UserSQLRepository) SoftDelete(ctx context.Context, id string, at time.Time) (bool, error) {
+	query := ur.dialect.rebind("UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL")
+	return ur.execAffectingOne(ctx, query, at.UTC(), id)
+}
+
+func (ur *UserSQLRepository) Delete(ctx context.Context, id string) (bool, error) {
+	return ur.execAffectingOne(ctx, ur.dialect.rebind("DELETE FROM users WHERE id = ?"), id)
+}
+
+func (ur *UserSQLRepository) Restore(ctx context.Context, id string) (*model.User, error) {
+	query := "UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL RETURNING " + userColumns
+	return ur.queryUser(ctx, query, id)
+}
+
+// conn returns the transaction started by SQLTransactor on ctx, if any, or the database.
+func (ur *UserSQLRepository) conn(ctx context.Context) sqlConn {
+	return sqlConnFrom(ctx, ur.db)
+}
+
+// queryUser runs a query returning at most one user row. No row means nil, nil.
+func (ur *UserSQLRepository) queryUser(ctx context.Context, query string, args ...any) (*model.User, error) {
+	user, err := scanUser(ur.conn(ctx).QueryRowContext(ctx, ur.dialect.rebind(query), args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if ur.dialect.isUniqueViolation(err) {
+			return nil, service.ErrUsernameTaken
+		}
+		slog.ErrorContext(ctx, "failed to query user", "error", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+func (ur *UserSQLRepository) execAffectingOne(ctx context.Context, query string, args ...any) (bool, error) {
+	result, err := ur.conn(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to execute user statement", "error", err)
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func userWhere(f service.UserFilter) ([]string, []any) {
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+	if f.FirstName != "" {
+		where = append(where, "first_name = ?")
+		args = append(args, f.FirstName)
+	}
+	if f.LastName != "" {
+		where = append(where, "last_name = ?")
+		args = append(args, f.LastName)
+	}
+	if f.Email != "" {
+		where = append(where, "email = ?")
+		args = append(args, f.Email)
+	}
+	if f.MinAge > 0 {
+		where = append(where, "age >= ?")
+		args = append(args, f.MinAge)
+	}
+	if f.MaxAge > 0 {
+		where = append(where, "age <= ?")
+		args = append(args, f.MaxAge)
+	}
+	return where, args
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*model.User, error) {
+	var user model.User
+	var deletedAt sql.NullTime
+	if err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Age, &user.Version, &deletedAt); err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		at := deletedAt.Time.UTC()
+		user.DeletedAt = &at
+	}
+	return &user, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
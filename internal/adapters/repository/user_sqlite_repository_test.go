@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/repository/repositorytest"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newSQLiteRepo(t *testing.T, path string, caseInsensitiveNames bool) *UserSQLRepository {
+	t.Helper()
+	db, err := config.ConnectSQLite(context.Background(), config.SQLite{Path: path})
+	if err != nil {
+		t.Fatalf("error opening sqlite database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := NewUserSQLiteRepo(db, caseInsensitiveNames)
+	if err := repo.Migrate(context.Background()); err != nil {
+		t.Fatalf("error migrating database: %v", err)
+	}
+	return repo
+}
+
+func TestUserSQLiteRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, func(t *testing.T) service.UserRepository {
+		return newSQLiteRepo(t, filepath.Join(t.TempDir(), "users.db"), false)
+	})
+}
+
+func TestSQLiteTransactor(t *testing.T) {
+	repositorytest.RunTransactorTests(t, func(t *testing.T) (service.UserRepository, service.Transactor) {
+		repo := newSQLiteRepo(t, filepath.Join(t.TempDir(), "users.db"), false)
+		return repo, NewSQLTransactor(repo.db)
+	})
+}
+
+func TestAuditSQLiteRepository(t *testing.T) {
+	repositorytest.RunAuditLogTests(t, func(t *testing.T) (service.AuditLog, service.Transactor) {
+		repo := newSQLiteRepo(t, filepath.Join(t.TempDir(), "users.db"), false)
+		return NewAuditSQLiteRepo(repo.db), NewSQLTransactor(repo.db)
+	})
+}
+
+func TestOutboxSQLiteRepository(t *testing.T) {
+	repositorytest.RunOutboxTests(t, func(t *testing.T) (service.Outbox, service.Transactor) {
+		repo := newSQLiteRepo(t, filepath.Join(t.TempDir(), "users.db"), false)
+		return NewOutboxSQLiteRepo(repo.db), NewSQLTransactor(repo.db)
+	})
+}
+
+func TestWebhookSQLiteRepository(t *testing.T) {
+	repositorytest.RunWebhookRepositoryTests(t, func(t *testing.T) service.WebhookRepository {
+		return NewWebhookSQLiteRepo(newSQLiteRepo(t, filepath.Join(t.TempDir(), "users.db"), false).db)
+	})
+}
+
+func TestIdempotencySQLiteRepository(t *testing.T) {
+	repositorytest.RunIdempotencyStoreTests(t, func(t *testing.T) service.IdempotencyStore {
+		return NewIdempotencySQLiteRepo(newSQLiteRepo(t, filepath.Join(t.TempDir(), "users.db"), false).db)
+	})
+}
+
+func TestUserSQLiteRepository_Durability(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data", "users.db")
+	saved, err := newSQLiteRepo(t, path, false).Save(ctx, model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30, Version: 1})
+	if err != nil {
+		t.Fatalf("error saving user: %v", err)
+	}
+
+	result, err := newSQLiteRepo(t, path, false).FindById(ctx, saved.ID)
+	if err != nil || result == nil || *result != *saved {
+		t.Errorf("expected: %v, result: %v, %v", saved, result, err)
+	}
+}
+
+func TestUserSQLiteRepository_CaseInsensitiveNames(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.db")
+	repo := newSQLiteRepo(t, path, true)
+	_, _ = repo.Save(ctx, model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30, Version: 1})
+
+	_, err := repo.Save(ctx, model.User{FirstName: "JOHN", LastName: "doe", Email: "john@doe.com", Age: 30, Version: 1})
+	if !errors.Is(err, service.ErrUsernameTaken) {
+		t.Errorf("expected: %v, result: %v", service.ErrUsernameTaken, err)
+	}
+
+	t.Run("switching back to case sensitive names replaces the index", func(t *testing.T) {
+		repo := newSQLiteRepo(t, path, false)
+		if _, err := repo.Save(ctx, model.User{FirstName: "JOHN", LastName: "doe", Email: "john@doe.com", Age: 30, Version: 1}); err != nil {
+			t.Errorf("expected: %v, result: %v", nil, err)
+		}
+	})
+}
+
+func TestSQLite_ConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteRepo(t, filepath.Join(t.TempDir(), "users.db"), false)
+	auditLog, tx := NewAuditSQLiteRepo(repo.db), NewSQLTransactor(repo.db)
+
+	t.Run("Concurrent transactions wait for each other", func(t *testing.T) {
+		const writers = 20
+		errs := make(chan error, writers)
+		for i := 0; i < writers; i++ {
+			go func(i int) {
+				errs <- tx.WithinTransaction(ctx, func(ctx context.Context) error {
+					// reading first, as the service does, makes deferred transactions fail upgrading their read lock
+					u := model.User{FirstName: "John", LastName: strconv.Itoa(i), Email: "john@doe.com", Age: 30}
+					if _, err := repo.ExistsByFirstNameAndLastName(ctx, u); err != nil {
+						return err
+					}
+					user, err := repo.Save(ctx, u)
+					if err != nil {
+						return err
+					}
+					return auditLog.Record(ctx, model.AuditEntry{UserID: user.ID, Operation: model.OperationCreate, At: time.Now()})
+				})
+			}(i)
+		}
+		for i := 0; i < writers; i++ {
+			if err := <-errs; err != nil {
+				t.Errorf("error writing concurrently: %v", err)
+			}
+		}
+		if count, _ := repo.Count(ctx, service.UserFilter{}); count != writers {
+			t.Errorf("expected %d users, result: %d", writers, count)
+		}
+	})
+	t.Run("Streams do not block writes", func(t *testing.T) {
+		err := repo.Stream(ctx, service.UserFilter{}, func(model.User) error {
+			saveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			_, err := repo.Save(saveCtx, model.User{FirstName: "Jane", LastName: primitive.NewObjectID().Hex(), Email: "jane@doe.com", Age: 30})
+			return err
+		})
+		if err != nil {
+			t.Errorf("error writing while streaming: %v", err)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WebhookMemoryRepository keeps webhooks and deliveries in memory, in creation order. It is safe for concurrent use.
+type WebhookMemoryRepository struct {
+	mu         sync.RWMutex
+	webhooks   []model.Webhook
+	deliveries []model.Delivery
+}
+
+func NewWebhookMemoryRepo() *WebhookMemoryRepository {
+	return &WebhookMemoryRepository{}
+}
+
+func (wr *WebhookMemoryRepository) CreateWebhook(_ context.Context, w model.Webhook) (*model.Webhook, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	w.ID = primitive.NewObjectID().Hex()
+	w.Events = append([]string(nil), w.Events...)
+	wr.webhooks = append(wr.webhooks, w)
+	return &w, nil
+}
+
+func (wr *WebhookMemoryRepository) FindWebhook(_ context.Context, id string) (*model.Webhook, error) {
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+	for _, webhook := range wr.webhooks {
+		if webhook.ID == id {
+			return &webhook, nil
+		}
+	}
+	return nil, nil
+}
+
+func (wr *WebhookMemoryRepository) ListWebhooks(_ context.Context) ([]model.Webhook, error) {
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+	return append([]model.Webhook{}, wr.webhooks...), nil
+}
+
+func (wr *WebhookMemoryRepository) DeleteWebhook(_ context.Context, id string) (bool, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	for i, webhook := range wr.webhooks {
+		if webhook.ID != id {
+			continue
+		}
+		wr.webhooks = append(wr.webhooks[:i:i], wr.webhooks[i+1:]...)
+		var deliveries []model.Delivery
+		for _, delivery := range wr.deliveries {
+			if delivery.WebhookID != id {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+		wr.deliveries = deliveries
+		return true, nil
+	}
+	return false, nil
+}
+
+func (wr *WebhookMemoryRepository) AddDelivery(_ context.Context, d model.Delivery) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	for _, delivery := range wr.deliveries {
+		if delivery.WebhookID == d.WebhookID && delivery.Event.ID == d.Event.ID {
+			return nil
+		}
+	}
+	d.ID = primitive.NewObjectID().Hex()
+	d.Attempts = append([]model.DeliveryAttempt(nil), d.Attempts...)
+	wr.deliveries = append(wr.deliveries, d)
+	return nil
+}
+
+func (wr *WebhookMemoryRepository) DueDeliveries(_ context.Context, now time.Time, limit int) ([]model.Delivery, error) {
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+	deliveries := []model.Delivery{}
+	for _, delivery := range wr.deliveries {
+		if delivery.Status == model.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (wr *WebhookMemoryRepository) SaveDelivery(_ context.Context, d model.Delivery) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	for i, delivery := range wr.deliveries {
+		if delivery.ID == d.ID {
+			delivery.Status, delivery.NextAttemptAt = d.Status, d.NextAttemptAt
+			delivery.Attempts = append([]model.DeliveryAttempt(nil), d.Attempts...)
+			wr.deliveries[i] = delivery
+			return nil
+		}
+	}
+	return nil
+}
+
+func (wr *WebhookMemoryRepository) FindDelivery(_ context.Context, webhookID string, id string) (*model.Delivery, error) {
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+	for _, delivery := range wr.deliveries {
+		if delivery.WebhookID == webhookID && delivery.ID == id {
+			return &delivery, nil
+		}
+	}
+	return nil, nil
+}
+
+func (wr *WebhookMemoryRepository) Deliveries(_ context.Context, webhookID string, status string, limit int) ([]model.Delivery, error) {
+	wr.mu.RLock()
+	defer wr.mu.RUnlock()
+	deliveries := []model.Delivery{}
+	for i := len(wr.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := wr.deliveries[i]
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+)
+
+const (
+	webhookCollection  = "webhooks"
+	deliveryCollection = "webhook_deliveries"
+)
+
+// WebhookMongoRepository stores webhooks and their deliveries in two collections.
+type WebhookMongoRepository struct {
+	db *mongo.Database
+}
+
+func NewWebhookMongoRepo(db *mongo.Database) *WebhookMongoRepository {
+	return &WebhookMongoRepository{db: db}
+}
+
+// Migrate creates the delivery indexes: the unique index delivering an event once to each webhook,
+// and the indexes due deliveries and the deliveries of a webhook are read from.
+func (wr *WebhookMongoRepository) Migrate(ctx context.Context) error {
+	_, err := wr.db.Collection(deliveryCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "webhookId", Value: 1}, {Key: "event._id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create webhook delivery indexes", "error", err)
+	}
+	return err
+}
+
+func (wr *WebhookMongoRepository) CreateWebhook(ctx context.Context, w model.Webhook) (*model.Webhook, error) {
+	w.ID = ""
+	result, err := wr.db.Collection(webhookCollection).InsertOne(ctx, w)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to insert webhook", "error", err)
+		return nil, err
+	}
+	w.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return &w, nil
+}
+
+func (wr *WebhookMongoRepository) FindWebhook(ctx context.Context, id string) (*model.Webhook, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	var webhook model.Webhook
+	err = wr.db.Collection(webhookCollection).FindOne(ctx, bson.M{"_id": oid}).Decode(&webhook)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read webhook", "error", err)
+		return nil, err
+	}
+	webhook.CreatedAt = webhook.CreatedAt.UTC()
+	return &webhook, nil
+}
+
+func (wr *WebhookMongoRepository) ListWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := wr.db.Collection(webhookCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read webhooks", "error", err)
+		return nil, err
+	}
+	webhooks := []model.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].CreatedAt = webhooks[i].CreatedAt.UTC()
+	}
+	return webhooks, nil
+}
+
+func (wr *WebhookMongoRepository) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	result, err := wr.db.Collection(webhookCollection).DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to delete webhook", "error", err)
+		return false, err
+	}
+	if result.DeletedCount == 0 {
+		return false, nil
+	}
+	if _, err := wr.db.Collection(deliveryCollection).DeleteMany(ctx, bson.M{"webhookId": id}); err != nil {
+		slog.ErrorContext(ctx, "failed to delete webhook deliveries", "error", err)
+		return true, err
+	}
+	return true, nil
+}
+
+func (wr *WebhookMongoRepository) AddDelivery(ctx context.Context, d model.Delivery) error {
+	d.ID = ""
+	if d.Attempts == nil {
+		d.Attempts = []model.DeliveryAttempt{}
+	}
+	_, err := wr.db.Collection(deliveryCollection).InsertOne(ctx, d)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to insert webhook delivery", "error", err)
+	}
+	return err
+}
+
+func (wr *WebhookMongoRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]model.Delivery, error) {
+	filter := bson.M{"status": model.DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	return wr.findDeliveries(ctx, filter, opts)
+}
+
+func (wr *WebhookMongoRepository) SaveDelivery(ctx context.Context, d model.Delivery) error {
+	oid, err := primitive.ObjectIDFromHex(d.ID)
+	if err != nil {
+		return err
+	}
+	attempts := d.Attempts
+	if attempts == nil {
+		attempts = []model.DeliveryAttempt{}
+	}
+	set := bson.M{"status": d.Status, "nextAttemptAt": d.NextAttemptAt, "attempts": attempts}
+	if _, err := wr.db.Collection(deliveryCollection).UpdateByID(ctx, oid, bson.M{"$set": set}); err != nil {
+		slog.ErrorContext(ctx, "failed to update webhook delivery", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (wr *WebhookMongoRepository) FindDelivery(ctx context.Context, webhookID string, id string) (*model.Delivery, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+	deliveries, err := wr.findDeliveries(ctx, bson.M{"_id": oid, "webhookId": webhookID}, options.Find())
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+func (wr *WebhookMongoRepository) Deliveries(ctx context.Context, webhookID string, status string, limit int) ([]model.Delivery, error) {
+	filter := bson.M{"webhookId": webhookID}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	return wr.findDeliveries(ctx, filter, opts)
+}
+
+func (wr *WebhookMongoRepository) findDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]model.Delivery, error) {
+	cursor, err := wr.db.Collection(deliveryCollection).Find(ctx, filter, opts)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read webhook deliveries", "error", err)
+		return nil, err
+	}
+	deliveries := []model.Delivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		delivery.NextAttemptAt, delivery.CreatedAt = delivery.NextAttemptAt.UTC(), delivery.CreatedAt.UTC()
+		delivery.Event.OccurredAt = delivery.Event.OccurredAt.UTC()
+		for j := range delivery.Attempts {
+			delivery.Attempts[j].At = delivery.Attempts[j].At.UTC()
+		}
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log/slog"
+	"time"
+)
+
+const (
+	webhookColumns  = "id, url, events, secret, created_at"
+	deliveryColumns = "id, webhook_id, event, status, next_attempt_at, created_at, attempts"
+)
+
+// WebhookSQLRepository stores webhooks and deliveries in the webhooks and webhook_deliveries tables, created
+// by the UserSQLRepository migrations. Webhook events, delivery events and attempts are stored as JSON.
+type WebhookSQLRepository struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+func NewWebhookPostgresRepo(db *sql.DB) *WebhookSQLRepository {
+	return &WebhookSQLRepository{db: db, dialect: postgresDialect}
+}
+
+func NewWebhookSQLiteRepo(db *sql.DB) *WebhookSQLRepository {
+	return &WebhookSQLRepository{db: db, dialect: sqliteDialect}
+}
+
+func (wr *WebhookSQLRepository) CreateWebhook(ctx context.Context, w model.Webhook) (*model.Webhook, error) {
+	w.ID = primitive.NewObjectID().Hex()
+	events, err := json.Marshal(w.Events)
+	if err != nil {
+		return nil, err
+	}
+	query := wr.dialect.rebind("INSERT INTO webhooks (" + webhookColumns + ") VALUES (?, ?, ?, ?, ?)")
+	if _, err := wr.db.ExecContext(ctx, query, w.ID, w.URL, string(events), w.Secret, w.CreatedAt.UTC()); err != nil {
+		slog.ErrorContext(ctx, "failed to insert webhook", "error", err)
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (wr *WebhookSQLRepository) FindWebhook(ctx context.Context, id string) (*model.Webhook, error) {
+	webhooks, err := wr.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id)
+	if err != nil || len(webhooks) == 0 {
+		return nil, err
+	}
+	return &webhooks[0], nil
+}
+
+func (wr *WebhookSQLRepository) ListWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	return wr.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+}
+
+func (wr *WebhookSQLRepository) queryWebhooks(ctx context.Context, query string, args ...any) ([]model.Webhook, error) {
+	rows, err := wr.db.QueryContext(ctx, wr.dialect.rebind(query), args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read webhooks", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		var webhook model.Webhook
+		var events []byte
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(events, &webhook.Events); err != nil {
+			return nil, err
+		}
+		webhook.CreatedAt = webhook.CreatedAt.UTC()
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook deletes the deliveries of the webhook explicitly: SQLite does not enforce foreign keys by default.
+func (wr *WebhookSQLRepository) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	var deleted int64
+	err := NewSQLTransactor(wr.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		conn := sqlConnFrom(ctx, wr.db)
+		if _, err := conn.ExecContext(ctx, wr.dialect.rebind("DELETE FROM webhook_deliveries WHERE webhook_id = ?"), id); err != nil {
+			return err
+		}
+		result, err := conn.ExecContext(ctx, wr.dialect.rebind("DELETE FROM webhooks WHERE id = ?"), id)
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to delete webhook", "error", err)
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func (wr *WebhookSQLRepository) AddDelivery(ctx context.Context, d model.Delivery) error {
+	d.ID = primitive.NewObjectID().Hex()
+	event, err := json.Marshal(d.Event)
+	if err != nil {
+		return err
+	}
+	attempts, err := json.Marshal(append([]model.DeliveryAttempt{}, d.Attempts...))
+	if err != nil {
+		return err
+	}
+	query := wr.dialect.rebind("INSERT INTO webhook_deliveries (" + deliveryColumns + ", event_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)" +
+		" ON CONFLICT (webhook_id, event_id) DO NOTHING")
+	_, err = wr.db.ExecContext(ctx, query, d.ID, d.WebhookID, string(event), d.Status, d.NextAttemptAt.UTC(), d.CreatedAt.UTC(), string(attempts), d.Event.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to insert webhook delivery", "error", err)
+		return err
+	}
+	return nil
+}
+
+// DueDeliveries compares timestamps, which SQLite stores as text: they are always written in UTC to keep their order.
+func (wr *WebhookSQLRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]model.Delivery, error) {
+	return wr.queryDeliveries(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries"+
+		" WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?", model.DeliveryPending, now.UTC(), limit)
+}
+
+func (wr *WebhookSQLRepository) SaveDelivery(ctx context.Context, d model.Delivery) error {
+	attempts, err := json.Marshal(append([]model.DeliveryAttempt{}, d.Attempts...))
+	if err != nil {
+		return err
+	}
+	query := wr.dialect.rebind("UPDATE webhook_deliveries SET status = ?, next_attempt_at = ?, attempts = ? WHERE id = ?")
+	if _, err := wr.db.ExecContext(ctx, query, d.Status, d.NextAttemptAt.UTC(), string(attempts), d.ID); err != nil {
+		slog.ErrorContext(ctx, "failed to update webhook delivery", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (wr *WebhookSQLRepository) FindDelivery(ctx context.Context, webhookID string, id string) (*model.Delivery, error) {
+	deliveries, err := wr.queryDeliveries(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? AND id = ?", webhookID, id)
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+func (wr *WebhookSQLRepository) Deliveries(ctx context.Context, webhookID string, status string, limit int) ([]model.Delivery, error) {
+	query, args := "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ?", []any{webhookID}
+	if status != "" {
+		query, args = query+" AND status = ?", append(args, status)
+	}
+	return wr.queryDeliveries(ctx, query+" ORDER BY id DESC LIMIT ?", append(args, limit)...)
+}
+
+func (wr *WebhookSQLRepository) queryDeliveries(ctx context.Context, query string, args ...any) ([]model.Delivery, error) {
+	rows, err := wr.db.QueryContext(ctx, wr.dialect.rebind(query), args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to read webhook deliveries", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []model.Delivery{}
+	for rows.Next() {
+		var delivery model.Delivery
+		var event, attempts []byte
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &event, &delivery.Status, &delivery.NextAttemptAt, &delivery.CreatedAt, &attempts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(event, &delivery.Event); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(attempts, &delivery.Attempts); err != nil {
+			return nil, err
+		}
+		delivery.NextAttemptAt, delivery.CreatedAt = delivery.NextAttemptAt.UTC(), delivery.CreatedAt.UTC()
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
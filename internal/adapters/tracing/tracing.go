@@ -0,0 +1,82 @@
+// Package tracing installs the OpenTelemetry tracer provider of the application.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Setup installs the W3C trace context propagator and, unless tracing is disabled, the global tracer provider
+// exporting the spans of serviceName as configured by cfg. It returns the function flushing the pending spans
+// and stopping the provider.
+func Setup(ctx context.Context, cfg config.Tracing, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	exporter, closeExporter, err := newExporter(ctx, cfg)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeExporter(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// newExporter returns the exporter of cfg, nil when tracing is disabled, and the function closing its output.
+func newExporter(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, func() error, error) {
+	noop := func() error { return nil }
+	switch cfg.Exporter {
+	case config.ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, noop, err
+	case config.ExporterStdout:
+		var w io.Writer = os.Stdout
+		closeFile := noop
+		if cfg.File != "" {
+			if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+				return nil, nil, err
+			}
+			file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				return nil, nil, err
+			}
+			w, closeFile = file, file.Close
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		return exporter, closeFile, err
+	case "", config.ExporterNone:
+		return nil, noop, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+}
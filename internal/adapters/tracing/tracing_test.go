@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/adapters/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Export the spans to a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces", "spans.ndjson")
+		shutdown, err := Setup(ctx, config.Tracing{Exporter: config.ExporterStdout, File: path, SampleRatio: 1}, "onboarding-test")
+		if err != nil {
+			t.Fatalf("error setting up tracing: %v", err)
+		}
+		_, span := otel.Tracer("test").Start(ctx, "UserService.Save")
+		span.End()
+		if err := shutdown(ctx); err != nil {
+			t.Fatalf("error shutting down tracing: %v", err)
+		}
+
+		spans, _ := os.ReadFile(path)
+		if !strings.Contains(string(spans), `"Name":"UserService.Save"`) || !strings.Contains(string(spans), "onboarding-test") {
+			t.Errorf("expected the span of onboarding-test, result: %s", spans)
+		}
+	})
+	t.Run("Propagate the W3C trace context", func(t *testing.T) {
+		if _, err := Setup(ctx, config.Tracing{}, "onboarding-test"); err != nil {
+			t.Fatalf("error setting up tracing: %v", err)
+		}
+		carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+		ctx := otel.GetTextMapPropagator().Extract(ctx, carrier)
+		injected := propagation.MapCarrier{}
+		otel.GetTextMapPropagator().Inject(ctx, injected)
+
+		if injected["traceparent"] != carrier["traceparent"] {
+			t.Errorf("expected: %s, result: %s", carrier["traceparent"], injected["traceparent"])
+		}
+	})
+	t.Run("Should return error with an unknown exporter", func(t *testing.T) {
+		if _, err := Setup(ctx, config.Tracing{Exporter: "zipkin"}, "onboarding-test"); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,205 @@
+// Package app runs the application: its HTTP server and background workers, until it is asked to stop.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// Shutdown phases, in order.
+const (
+	PhaseDrain   = "drain"
+	PhaseServer  = "server"
+	PhaseWorkers = "workers"
+	PhaseClose   = "close"
+)
+
+// Drainer fails the readiness probes of the application, so that load balancers stop sending it requests.
+type Drainer interface {
+	Drain()
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Runner serves HTTP requests and runs background workers until its context is done, then shuts down in phases:
+// it fails the readiness probes for the drain delay, then stops accepting connections and waits for the in-flight
+// requests, stops the workers and waits for them, both within the shutdown timeout, and finally releases the
+// resources the requests and workers used, such as the storage, in the order they were given.
+type Runner struct {
+	server          *http.Server
+	drainer         Drainer
+	drainDelay      time.Duration
+	shutdownTimeout time.Duration
+	workers         []worker
+	closers         []closer
+}
+
+type Option func(r *Runner)
+
+// WithDrain fails the readiness probes of d for delay before the server stops accepting connections.
+func WithDrain(d Drainer, delay time.Duration) Option {
+	return func(r *Runner) {
+		r.drainer, r.drainDelay = d, delay
+	}
+}
+
+// WithShutdownTimeout bounds the time taken to stop the server and the workers once drained, 10s by default.
+// The resources are then released within the same time.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(r *Runner) {
+		r.shutdownTimeout = timeout
+	}
+}
+
+// WithWorker runs run in the background until its context is canceled on shutdown.
+func WithWorker(name string, run func(ctx context.Context)) Option {
+	return func(r *Runner) {
+		r.workers = append(r.workers, worker{name: name, run: run})
+	}
+}
+
+// WithCloser releases a resource once the server and the workers are stopped. Resources are released in order.
+func WithCloser(name string, close func(ctx context.Context) error) Option {
+	return func(r *Runner) {
+		r.closers = append(r.closers, closer{name: name, close: close})
+	}
+}
+
+func NewRunner(server *http.Server, opts ...Option) *Runner {
+	r := &Runner{server: server, shutdownTimeout: defaultShutdownTimeout}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run listens on the address of the server and runs the application until ctx is done.
+func (r *Runner) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", r.server.Addr)
+	if err != nil {
+		return errors.Join(fmt.Errorf("listening on %s: %w", r.server.Addr, err), r.close(ctx))
+	}
+	return r.Serve(ctx, listener)
+}
+
+// Serve runs the application, serving the connections accepted by listener, until ctx is done or the server fails.
+// It returns once the application is shut down, with the errors met on the way.
+func (r *Runner) Serve(ctx context.Context, listener net.Listener) error {
+	workersCtx, stopWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	for _, w := range r.workers {
+		workers.Add(1)
+		go func(w worker) {
+			defer workers.Done()
+			w.run(workersCtx)
+			slog.InfoContext(ctx, "Worker stopped", "worker", w.name)
+		}(w)
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.InfoContext(ctx, "Server listening on "+listener.Addr().String())
+		serveErr <- r.server.Serve(listener)
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.InfoContext(ctx, "Shutting down...", "cause", context.Cause(ctx).Error())
+	case err = <-serveErr:
+		slog.ErrorContext(ctx, "Server failed, shutting down...", "error", err)
+		err = fmt.Errorf("serving: %w", err)
+	}
+	// the shutdown goes on once ctx is done, and its records keep the values of ctx
+	logCtx := context.WithoutCancel(ctx)
+	errs := []error{err}
+	// a failed server gets no more requests to drain
+	if r.drainer != nil && err == nil {
+		errs = append(errs, r.phase(logCtx, PhaseDrain, func() error {
+			r.drainer.Drain()
+			time.Sleep(r.drainDelay)
+			return nil
+		}))
+	}
+	// the requests received while draining get the whole shutdown timeout to finish
+	shutdownCtx, cancel := context.WithTimeout(logCtx, r.shutdownTimeout)
+	defer cancel()
+	errs = append(errs, r.phase(logCtx, PhaseServer, func() error {
+		if err := r.server.Shutdown(shutdownCtx); err != nil {
+			// the remaining requests are cut short: the resources they use are about to be released
+			_ = r.server.Close()
+			return fmt.Errorf("waiting for the in-flight requests: %w", err)
+		}
+		return nil
+	}))
+	errs = append(errs, r.phase(logCtx, PhaseWorkers, func() error {
+		stopWorkers()
+		done := make(chan struct{})
+		go func() {
+			workers.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-shutdownCtx.Done():
+			select {
+			case <-done:
+				// the workers stopped as the deadline passed, e.g. after a timed out server shutdown
+				return nil
+			default:
+				return fmt.Errorf("waiting for the workers: %w", shutdownCtx.Err())
+			}
+		}
+	}))
+	errs = append(errs, r.close(logCtx))
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	slog.InfoContext(logCtx, "Shutdown complete")
+	return nil
+}
+
+// close releases the resources in order, within the shutdown timeout.
+func (r *Runner) close(ctx context.Context) error {
+	closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.shutdownTimeout)
+	defer cancel()
+	return r.phase(ctx, PhaseClose, func() error {
+		var errs []error
+		for _, c := range r.closers {
+			if err := c.close(closeCtx); err != nil {
+				errs = append(errs, fmt.Errorf("closing %s: %w", c.name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// phase runs a shutdown phase and reports its outcome and duration.
+func (r *Runner) phase(ctx context.Context, name string, run func() error) error {
+	start := time.Now()
+	err := run()
+	elapsed := time.Since(start).String()
+	if err != nil {
+		slog.ErrorContext(ctx, "Shutdown phase failed", "phase", name, "elapsed", elapsed, "error", err)
+		return err
+	}
+	slog.InfoContext(ctx, "Shutdown phase completed", "phase", name, "elapsed", elapsed)
+	return nil
+}
@@ -0,0 +1,237 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the events of a shutdown, in order.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type drainer struct {
+	*recorder
+	drained chan struct{}
+}
+
+func (d drainer) Drain() {
+	d.record("drained")
+	close(d.drained)
+}
+
+var client = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+func get(url string) (string, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	return string(body), err
+}
+
+// serve runs a Runner serving handler on a random port, returning its URL, the function stopping it
+// and the channel receiving the result of the run.
+func serve(t *testing.T, handler http.HandlerFunc, opts ...Option) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	t.Cleanup(stop)
+	done := make(chan error, 1)
+	runner := NewRunner(&http.Server{Handler: handler}, opts...)
+	go func() { done <- runner.Serve(ctx, listener) }()
+	return "http://" + listener.Addr().String(), stop, done
+}
+
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("the runner did not stop")
+		return nil
+	}
+}
+
+func assertEvents(t *testing.T, r *recorder, expected ...string) {
+	t.Helper()
+	events := r.recorded()
+	if len(events) != len(expected) {
+		t.Fatalf("expected: %v, result: %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("expected: %v, result: %v", expected, events)
+		}
+	}
+}
+
+func TestRunner(t *testing.T) {
+	t.Run("Finish the in-flight requests, then stop the workers, then close the storage", func(t *testing.T) {
+		events := &recorder{}
+		started := make(chan struct{})
+		url, stop, done := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			events.record("request served")
+			_, _ = io.WriteString(w, "ok")
+		},
+			WithWorker("relay", func(ctx context.Context) {
+				<-ctx.Done()
+				events.record("worker stopped")
+			}),
+			WithCloser("publisher", func(context.Context) error {
+				events.record("publisher closed")
+				return nil
+			}),
+			WithCloser("storage", func(context.Context) error {
+				events.record("storage closed")
+				return nil
+			}),
+		)
+		response := make(chan string, 1)
+		go func() {
+			body, err := get(url)
+			if err != nil {
+				body = err.Error()
+			}
+			response <- body
+		}()
+		<-started
+		stop()
+
+		if body := <-response; body != "ok" {
+			t.Errorf("expected the in-flight request to be served, result: %s", body)
+		}
+		if err := wait(t, done); err != nil {
+			t.Errorf("expected a clean shutdown, result: %v", err)
+		}
+		assertEvents(t, events, "request served", "worker stopped", "publisher closed", "storage closed")
+	})
+	t.Run("Serve new requests while draining, refuse them after", func(t *testing.T) {
+		events := &recorder{}
+		d := drainer{recorder: events, drained: make(chan struct{})}
+		url, stop, done := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "ok")
+		}, WithDrain(d, 300*time.Millisecond), WithCloser("storage", func(context.Context) error {
+			events.record("storage closed")
+			return nil
+		}))
+		stop()
+		<-d.drained
+
+		if body, err := get(url); body != "ok" {
+			t.Errorf("expected requests to be served while draining, result: %q, %v", body, err)
+		}
+		if err := wait(t, done); err != nil {
+			t.Errorf("expected a clean shutdown, result: %v", err)
+		}
+		if _, err := get(url); err == nil {
+			t.Errorf("expected the connections to be refused once shut down")
+		}
+		assertEvents(t, events, "drained", "storage closed")
+	})
+	t.Run("Finish the requests received while draining longer than the shutdown timeout", func(t *testing.T) {
+		events := &recorder{}
+		d := drainer{recorder: events, drained: make(chan struct{})}
+		url, stop, done := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(300 * time.Millisecond)
+			events.record("request served")
+			_, _ = io.WriteString(w, "ok")
+		}, WithDrain(d, 200*time.Millisecond), WithShutdownTimeout(200*time.Millisecond))
+		stop()
+		<-d.drained
+
+		if body, err := get(url); body != "ok" {
+			t.Errorf("expected the request to be served, result: %q, %v", body, err)
+		}
+		if err := wait(t, done); err != nil {
+			t.Errorf("expected a clean shutdown, result: %v", err)
+		}
+		assertEvents(t, events, "drained", "request served")
+	})
+	t.Run("Give up on the requests exceeding the shutdown timeout", func(t *testing.T) {
+		events := &recorder{}
+		started, release := make(chan struct{}), make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		url, stop, done := serve(t, func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}, WithShutdownTimeout(100*time.Millisecond), WithCloser("storage", func(ctx context.Context) error {
+			events.record("storage closed")
+			return ctx.Err()
+		}))
+		response := make(chan error, 1)
+		go func() {
+			_, err := get(url)
+			response <- err
+		}()
+		<-started
+		start := time.Now()
+		stop()
+
+		err := wait(t, done)
+		if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+			t.Errorf("expected the shutdown to time out, result: %v after %s", err, time.Since(start))
+		}
+		if err := <-response; err == nil {
+			t.Errorf("expected the request to be cut short")
+		}
+		assertEvents(t, events, "storage closed")
+	})
+	t.Run("Shut down when the server fails", func(t *testing.T) {
+		events := &recorder{}
+		d := drainer{recorder: events, drained: make(chan struct{})}
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		_ = listener.Close()
+		runner := NewRunner(&http.Server{}, WithDrain(d, time.Hour), WithCloser("storage", func(context.Context) error {
+			events.record("storage closed")
+			return errors.New("connection reset")
+		}))
+
+		err := runner.Serve(context.Background(), listener)
+		if err == nil || err.Error() == "" {
+			t.Fatalf("expected an error")
+		}
+		assertEvents(t, events, "storage closed")
+	})
+	t.Run("Close the storage when the address is taken", func(t *testing.T) {
+		events := &recorder{}
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		defer listener.Close()
+		runner := NewRunner(&http.Server{Addr: listener.Addr().String()}, WithCloser("storage", func(context.Context) error {
+			events.record("storage closed")
+			return nil
+		}))
+
+		if err := runner.Run(context.Background()); err == nil {
+			t.Errorf("expected an error")
+		}
+		assertEvents(t, events, "storage closed")
+	})
+}
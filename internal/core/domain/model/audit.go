@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Operations recorded in the audit log.
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+)
+
+// FieldDeletedAt names the soft-delete timestamp in audit diffs.
+const FieldDeletedAt = "deletedAt"
+
+// AuditEntry records a change made to a user: who made it, when, in which request and what changed.
+type AuditEntry struct {
+	ID        string        `bson:"_id,omitempty" json:"id"`
+	UserID    string        `bson:"userId" json:"userId"`
+	Operation string        `bson:"operation" json:"operation"`
+	Actor     string        `bson:"actor" json:"actor"`
+	RequestID string        `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	At        time.Time     `bson:"at" json:"at"`
+	Changes   []FieldChange `bson:"changes" json:"changes"`
+}
+
+// FieldChange is the value of a user field before and after a change. A nil value means the field was not set.
+type FieldChange struct {
+	Field  string `bson:"field" json:"field"`
+	Before any    `bson:"before" json:"before"`
+	After  any    `bson:"after" json:"after"`
+}
+
+// Diff lists the user fields that differ between before and after. A nil before is a creation,
+// every field is then reported as set; a nil after is a removal, every field is reported as unset.
+// Timestamps are reported in RFC 3339 form.
+func Diff(before, after *User) []FieldChange {
+	var changes []FieldChange
+	beforeFields, afterFields := auditedFields(before), auditedFields(after)
+	for i, field := range []string{FieldFirstName, FieldLastName, FieldEmail, FieldAge, FieldDeletedAt} {
+		if beforeFields[i] != afterFields[i] {
+			changes = append(changes, FieldChange{Field: field, Before: beforeFields[i], After: afterFields[i]})
+		}
+	}
+	return changes
+}
+
+func auditedFields(u *User) []any {
+	if u == nil {
+		return make([]any, 5)
+	}
+	var deletedAt any
+	if u.DeletedAt != nil {
+		deletedAt = u.DeletedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return []any{u.FirstName, u.LastName, u.Email, u.Age, deletedAt}
+}
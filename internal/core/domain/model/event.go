@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Types of the user lifecycle events.
+const (
+	EventUserCreated = "UserCreated"
+	// EventUserUpdated is also emitted when a soft-deleted user is restored.
+	EventUserUpdated = "UserUpdated"
+	EventUserDeleted = "UserDeleted"
+)
+
+// Event is a domain event about a user. User is the user after the change, or before it for UserDeleted.
+type Event struct {
+	ID         string    `bson:"_id,omitempty" json:"id"`
+	Type       string    `bson:"type" json:"type"`
+	UserID     string    `bson:"userId" json:"userId"`
+	OccurredAt time.Time `bson:"occurredAt" json:"occurredAt"`
+	RequestID  string    `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	User       User      `bson:"user" json:"user"`
+}
+
+// EventTypes lists every event type, e.g. the events webhooks can subscribe to.
+var EventTypes = []string{EventUserCreated, EventUserUpdated, EventUserDeleted}
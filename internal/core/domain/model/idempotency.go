@@ -0,0 +1,24 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord is the request made with an idempotency key and, once completed, its response.
+// A record without response is a request still being processed, whose reservation expires after a short lease.
+type IdempotencyRecord struct {
+	Key string `bson:"_id"`
+	// Fingerprint identifies the request: a retry must send the same request with the key.
+	Fingerprint string      `bson:"fingerprint"`
+	StatusCode  int         `bson:"statusCode"`
+	Header      http.Header `bson:"header"`
+	Body        []byte      `bson:"body"`
+	CreatedAt   time.Time   `bson:"createdAt"`
+	ExpiresAt   time.Time   `bson:"expiresAt"`
+}
+
+// Completed reports whether the response of the request is stored.
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
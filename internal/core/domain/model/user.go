@@ -1,16 +1,57 @@
 package model
 
 import (
-	"errors"
 	"regexp"
+	"strings"
+	"time"
 )
 
+// Names of the user fields clients can change.
+const (
+	FieldFirstName = "firstName"
+	FieldLastName  = "lastName"
+	FieldEmail     = "email"
+	FieldAge       = "age"
+)
+
+// Codes of the validation rules a user must satisfy.
+const (
+	RuleRequired = "required"
+	RuleEmail    = "email"
+	RuleMinAge   = "min_age"
+)
+
+const MinAge = 18
+
+var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// FieldError is a validation rule a user field does not satisfy.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors holds every validation rule a user does not satisfy.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, ", ")
+}
+
 type User struct {
-	ID        string `bson:"_id,omitempty" json:"id,omitempty"`
-	FirstName string `bson:"firstName" json:"firstName"`
-	LastName  string `bson:"lastName" json:"lastName"`
-	Email     string `bson:"email" json:"email"`
-	Age       int    `bson:"age" json:"age"`
+	ID        string     `bson:"_id,omitempty" json:"id,omitempty"`
+	FirstName string     `bson:"firstName" json:"firstName"`
+	LastName  string     `bson:"lastName" json:"lastName"`
+	Email     string     `bson:"email" json:"email"`
+	Age       int        `bson:"age" json:"age"`
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	// Version is incremented on every update and used for optimistic concurrency control.
+	Version int64 `bson:"version" json:"version"`
 }
 
 func NewUser(id string, firstName string, lastName string, email string, age int) (*User, error) {
@@ -21,30 +62,53 @@ func NewUser(id string, firstName string, lastName string, email string, age int
 		Email:     email,
 		Age:       age,
 	}
-	err := validateUser(user)
-	if err != nil {
+	if err := user.Validate(); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func validateUser(u *User) error {
-	if len(u.FirstName) == 0 {
-		return errors.New("first name is required")
+// Validate checks every user field and returns ValidationErrors listing all violations.
+func (u User) Validate() error {
+	if errs := validateUser(&u); len(errs) > 0 {
+		return errs
 	}
+	return nil
+}
+
+// ChangedFields lists the editable fields whose value differs between u and other.
+func (u User) ChangedFields(other User) []string {
+	var fields []string
+	if u.FirstName != other.FirstName {
+		fields = append(fields, FieldFirstName)
+	}
+	if u.LastName != other.LastName {
+		fields = append(fields, FieldLastName)
+	}
+	if u.Email != other.Email {
+		fields = append(fields, FieldEmail)
+	}
+	if u.Age != other.Age {
+		fields = append(fields, FieldAge)
+	}
+	return fields
+}
 
+func validateUser(u *User) ValidationErrors {
+	var errs ValidationErrors
+	if len(u.FirstName) == 0 {
+		errs = append(errs, FieldError{Field: FieldFirstName, Code: RuleRequired, Message: "first name is required"})
+	}
 	if len(u.LastName) == 0 {
-		return errors.New("last name is required")
+		errs = append(errs, FieldError{Field: FieldLastName, Code: RuleRequired, Message: "last name is required"})
 	}
 	if len(u.Email) == 0 {
-		return errors.New("email is required")
-	}
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	if !emailRegex.MatchString(u.Email) {
-		return errors.New("invalid email")
+		errs = append(errs, FieldError{Field: FieldEmail, Code: RuleRequired, Message: "email is required"})
+	} else if !emailRegex.MatchString(u.Email) {
+		errs = append(errs, FieldError{Field: FieldEmail, Code: RuleEmail, Message: "invalid email"})
 	}
-	if u.Age < 18 {
-		return errors.New("user must be at least 18 years old")
+	if u.Age < MinAge {
+		errs = append(errs, FieldError{Field: FieldAge, Code: RuleMinAge, Message: "user must be at least 18 years old"})
 	}
-	return nil
+	return errs
 }
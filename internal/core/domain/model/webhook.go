@@ -0,0 +1,47 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Statuses of a webhook delivery. A failed delivery stays pending until its retries are exhausted,
+// it is then dead: kept, with its attempts, for inspection and manual redelivery.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// Webhook is a subscription of a partner URL to user events. Secret signs the deliveries and is never returned to clients.
+type Webhook struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	URL       string    `bson:"url" json:"url"`
+	Events    []string  `bson:"events" json:"events"`
+	Secret    string    `bson:"secret" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// Subscribed reports whether the webhook receives events of type eventType.
+func (w Webhook) Subscribed(eventType string) bool {
+	return slices.Contains(w.Events, eventType)
+}
+
+// Delivery is the delivery of an event to a webhook, with every attempt made so far.
+type Delivery struct {
+	ID            string            `bson:"_id,omitempty" json:"id"`
+	WebhookID     string            `bson:"webhookId" json:"webhookId"`
+	Event         Event             `bson:"event" json:"event"`
+	Status        string            `bson:"status" json:"status"`
+	NextAttemptAt time.Time         `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	CreatedAt     time.Time         `bson:"createdAt" json:"createdAt"`
+	Attempts      []DeliveryAttempt `bson:"attempts" json:"attempts"`
+}
+
+// DeliveryAttempt is a request made to deliver an event. StatusCode is zero when no response was received.
+type DeliveryAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs int64     `bson:"durationMs" json:"durationMs"`
+}
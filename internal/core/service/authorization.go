@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Permissions on users and webhooks. A role or scope granting a permission with the OwnSuffix only grants it on
+// the user whose ID is the subject of the caller.
+const (
+	PermUsersRead   = "users:read"
+	PermUsersWrite  = "users:write"
+	PermUsersDelete = "users:delete"
+	// PermUsersDeleteHard removes users for good, on top of PermUsersDelete.
+	PermUsersDeleteHard = "users:delete:hard"
+	PermWebhooksManage  = "webhooks:manage"
+	OwnSuffix           = ":own"
+	// PermAll grants every permission.
+	PermAll = "*"
+)
+
+var Permissions = []string{PermUsersRead, PermUsersWrite, PermUsersDelete, PermUsersDeleteHard, PermWebhooksManage}
+
+// restrictedPermissions are not granted to every caller by a Policy without roles, only to the privileged callers
+// and to the scopes naming them.
+var restrictedPermissions = []string{PermUsersDeleteHard, PermWebhooksManage}
+
+// Access is how much of a permission a caller has.
+type Access int
+
+const (
+	AccessNone Access = iota
+	AccessOwn
+	AccessAll
+)
+
+// ForbiddenError rejects a caller lacking a permission. OwnOnly is set when the caller has it on its own user only.
+type ForbiddenError struct {
+	Subject    string
+	Permission string
+	OwnOnly    bool
+}
+
+func (e ForbiddenError) Error() string {
+	if e.OwnOnly {
+		return fmt.Sprintf("%s has the %s permission on its own user only", e.Subject, e.Permission)
+	}
+	return fmt.Sprintf("%s lacks the %s permission", e.Subject, e.Permission)
+}
+
+// Policy grants permissions to the roles and scopes of callers. Scopes are permission names. Privileged callers,
+// authenticated by the admin key, have every permission. A Policy without roles grants every permission but the
+// restricted ones to every caller. A nil Policy grants every permission to everyone.
+type Policy struct {
+	roles map[string][]string
+}
+
+// NewPolicy grants each role the listed permissions.
+func NewPolicy(roles map[string][]string) (*Policy, error) {
+	for role, permissions := range roles {
+		for _, permission := range permissions {
+			if permission != PermAll && !slices.Contains(Permissions, strings.TrimSuffix(permission, OwnSuffix)) {
+				return nil, fmt.Errorf("role %s: unknown permission %q", role, permission)
+			}
+		}
+	}
+	return &Policy{roles: roles}, nil
+}
+
+// WithPolicy restricts the service operations to the callers p grants the permissions they require.
+func WithPolicy(p *Policy) Option {
+	return func(s *Service) {
+		s.policy = p
+	}
+}
+
+// Access returns the access of principal to permission.
+func (p *Policy) Access(principal Principal, permission string) Access {
+	if p == nil || principal.Method == AuthAdminKey || (len(p.roles) == 0 && !slices.Contains(restrictedPermissions, permission)) {
+		return AccessAll
+	}
+	granted := slices.Clone(principal.Scopes)
+	for _, role := range principal.Roles {
+		granted = append(granted, p.roles[role]...)
+	}
+	access := AccessNone
+	for _, g := range granted {
+		switch g {
+		case PermAll, permission:
+			return AccessAll
+		case permission + OwnSuffix:
+			access = AccessOwn
+		}
+	}
+	return access
+}
+
+// Require returns a ForbiddenError unless the caller of ctx has permission, at least on its own user.
+func (p *Policy) Require(ctx context.Context, permission string) error {
+	return p.authorize(ctx, permission, func(Principal) bool { return true })
+}
+
+// Authorize returns a ForbiddenError unless the caller of ctx has permission on the user with ID userID.
+// An empty userID stands for every user, as when listing or creating users, and requires the full permission.
+func (p *Policy) Authorize(ctx context.Context, permission string, userID string) error {
+	return p.authorize(ctx, permission, func(principal Principal) bool { return userID != "" && userID == principal.Subject })
+}
+
+func (p *Policy) authorize(ctx context.Context, permission string, owns func(Principal) bool) error {
+	if p == nil {
+		return nil
+	}
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		principal.Subject = AnonymousActor
+	}
+	switch p.Access(principal, permission) {
+	case AccessAll:
+		return nil
+	case AccessOwn:
+		if owns(principal) {
+			return nil
+		}
+		return ForbiddenError{Subject: principal.Subject, Permission: permission, OwnOnly: true}
+	}
+	return ForbiddenError{Subject: principal.Subject, Permission: permission}
+}
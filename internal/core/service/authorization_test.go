@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	policy, err := NewPolicy(map[string][]string{
+		"reader":       {PermUsersRead},
+		"self-service": {PermUsersRead + OwnSuffix, PermUsersWrite + OwnSuffix},
+		"admin":        {PermAll},
+	})
+	if err != nil {
+		t.Fatalf("error building policy: %v", err)
+	}
+	other := validUser
+	other.ID, other.FirstName = "other", "Jane"
+	service := NewUserService(&MockUserRepository{Users: []model.User{validUser, other}}, WithPolicy(policy))
+	as := func(p Principal) context.Context {
+		return ContextWithPrincipal(context.Background(), p)
+	}
+	forbidden := func(t *testing.T, err error, ownOnly bool) {
+		t.Helper()
+		var forbiddenErr ForbiddenError
+		if !errors.As(err, &forbiddenErr) || forbiddenErr.OwnOnly != ownOnly {
+			t.Errorf("expected forbidden error with own only %t, result: %v", ownOnly, err)
+		}
+	}
+
+	t.Run("Readers only read", func(t *testing.T) {
+		reader := as(Principal{Subject: "reporting", Roles: []string{"reader"}})
+		if _, err := service.FindById(reader, other.ID); err != nil {
+			t.Errorf("error finding user: %v", err)
+		}
+		if _, err := service.List(reader, ListParams{}); err != nil {
+			t.Errorf("error listing users: %v", err)
+		}
+		_, err := service.Update(reader, other)
+		forbidden(t, err, false)
+		forbidden(t, service.Delete(reader, other.ID, false), false)
+	})
+	t.Run("Self-service users only access their own user", func(t *testing.T) {
+		self := as(Principal{Subject: validUser.ID, Roles: []string{"self-service"}})
+		changed := validUser
+		changed.Email = "johnny@doe.com"
+		if _, err := service.Update(self, changed); err != nil {
+			t.Errorf("error updating own user: %v", err)
+		}
+		_, err := service.FindById(self, other.ID)
+		forbidden(t, err, true)
+		_, err = service.Patch(self, other.ID, 0, func(u model.User) (model.User, error) { return u, nil })
+		forbidden(t, err, true)
+		_, err = service.List(self, ListParams{})
+		forbidden(t, err, true)
+		forbidden(t, service.Delete(self, validUser.ID, false), false)
+	})
+	t.Run("Scopes grant permissions", func(t *testing.T) {
+		if _, err := service.FindById(as(Principal{Subject: "client", Scopes: []string{PermUsersRead}}), other.ID); err != nil {
+			t.Errorf("error finding user: %v", err)
+		}
+	})
+	t.Run("Admins and privileged callers have every permission", func(t *testing.T) {
+		for _, p := range []Principal{{Subject: "root", Roles: []string{"admin"}}, {Subject: "admin", Method: AuthAdminKey}} {
+			if _, err := service.Restore(as(p), "unknown"); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("%s: expected: %v, result: %v", p.Subject, ErrUserNotFound, err)
+			}
+		}
+	})
+	t.Run("Anonymous callers and unknown roles have no permission", func(t *testing.T) {
+		_, err := service.FindById(context.Background(), other.ID)
+		forbidden(t, err, false)
+		_, err = service.FindById(as(Principal{Subject: "bob", Roles: []string{"unknown"}}), other.ID)
+		forbidden(t, err, false)
+	})
+	t.Run("Hard delete requires its own permission", func(t *testing.T) {
+		editor, err := NewPolicy(map[string][]string{"editor": {PermUsersDelete}, "owner": {PermUsersDelete, PermUsersDeleteHard}})
+		if err != nil {
+			t.Fatalf("error building policy: %v", err)
+		}
+		service := NewUserService(&MockUserRepository{Users: []model.User{validUser, other}}, WithPolicy(editor))
+		forbidden(t, service.Delete(as(Principal{Subject: "bob", Roles: []string{"editor"}}), other.ID, true), false)
+		if err := service.Delete(as(Principal{Subject: "alice", Roles: []string{"owner"}}), other.ID, true); err != nil {
+			t.Errorf("error hard deleting user: %v", err)
+		}
+	})
+	t.Run("Without roles, restricted permissions are only granted to privileged callers and scopes", func(t *testing.T) {
+		open, _ := NewPolicy(nil)
+		bob := Principal{Subject: "bob"}
+		if open.Access(bob, PermUsersDelete) != AccessAll {
+			t.Errorf("expected every caller to delete users")
+		}
+		for _, permission := range []string{PermUsersDeleteHard, PermWebhooksManage} {
+			if open.Access(bob, permission) != AccessNone {
+				t.Errorf("expected no access to %s", permission)
+			}
+			if open.Access(Principal{Subject: "admin", Method: AuthAdminKey}, permission) != AccessAll {
+				t.Errorf("expected the admin to have %s", permission)
+			}
+			if open.Access(Principal{Subject: "ops", Scopes: []string{permission}}, permission) != AccessAll {
+				t.Errorf("expected the %s scope to grant it", permission)
+			}
+		}
+	})
+	t.Run("Should return error with unknown permissions", func(t *testing.T) {
+		if _, err := NewPolicy(map[string][]string{"editor": {"users:edit"}}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+)
+
+// IdempotencyStore stores the requests made with an idempotency key, and their responses, until they expire.
+type IdempotencyStore interface {
+	// Reserve stores r, without response, unless a record with its key has not expired at r.CreatedAt.
+	// It returns that record, nil when r was stored.
+	Reserve(ctx context.Context, r model.IdempotencyRecord) (*model.IdempotencyRecord, error)
+	// Complete stores the response, and the expiration time, of the reserved record with the key of r.
+	Complete(ctx context.Context, r model.IdempotencyRecord) error
+	// Release deletes the record with the given key, so that the request can be made again.
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+)
+
+// Outcomes of the service operations.
+const (
+	OutcomeOK               = "ok"
+	OutcomeNotFound         = "not_found"
+	OutcomeUsernameTaken    = "username_taken"
+	OutcomeVersionConflict  = "version_conflict"
+	OutcomeValidationFailed = "validation_failed"
+	OutcomeForbidden        = "forbidden"
+	OutcomeError            = "error"
+)
+
+// Metrics counts the outcomes of the service operations.
+type Metrics interface {
+	CountOutcome(operation string, outcome string)
+}
+
+// WithMetrics counts the outcome of every service operation in m.
+func WithMetrics(m Metrics) Option {
+	return func(s *Service) {
+		s.metrics = m
+	}
+}
+
+// Outcome classifies the error returned by an operation.
+func Outcome(err error) string {
+	var fieldErrs model.ValidationErrors
+	var validationErr ValidationError
+	var forbiddenErr ForbiddenError
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case errors.Is(err, ErrUserNotFound):
+		return OutcomeNotFound
+	case errors.Is(err, ErrUsernameTaken):
+		return OutcomeUsernameTaken
+	case errors.Is(err, ErrVersionConflict):
+		return OutcomeVersionConflict
+	case errors.As(err, &fieldErrs), errors.As(err, &validationErr):
+		return OutcomeValidationFailed
+	case errors.As(err, &forbiddenErr):
+		return OutcomeForbidden
+	default:
+		return OutcomeError
+	}
+}
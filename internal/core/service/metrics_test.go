@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"testing"
+)
+
+// countingMetrics counts the outcomes by operation.
+type countingMetrics map[string]int
+
+func (m countingMetrics) CountOutcome(operation string, outcome string) {
+	m[operation+" "+outcome]++
+}
+
+func TestServiceMetrics(t *testing.T) {
+	ctx := context.Background()
+	metrics := countingMetrics{}
+	service := NewUserService(&MockUserRepository{}, WithMetrics(metrics))
+
+	namesake := validUser
+	namesake.ID = "2"
+	_, _ = service.Save(ctx, validUser)
+	_, _ = service.Save(ctx, namesake)
+	_, _ = service.Save(ctx, model.User{FirstName: "John"})
+	_, _ = service.FindById(ctx, "missing")
+	_, _ = service.List(ctx, ListParams{Limit: -1})
+
+	expected := countingMetrics{
+		"save ok": 1, "save username_taken": 1, "save validation_failed": 1, "find not_found": 1, "list validation_failed": 1,
+	}
+	if len(metrics) != len(expected) {
+		t.Fatalf("expected: %v, result: %v", expected, metrics)
+	}
+	for key, count := range expected {
+		if metrics[key] != count {
+			t.Errorf("expected: %v, result: %v", expected, metrics)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	for _, test := range []struct {
+		err     error
+		outcome string
+	}{
+		{nil, OutcomeOK},
+		{ErrUserNotFound, OutcomeNotFound},
+		{ErrVersionConflict, OutcomeVersionConflict},
+		{ForbiddenError{Subject: "alice"}, OutcomeForbidden},
+		{ValidationError{Message: "bad cursor"}, OutcomeValidationFailed},
+		{ErrImportAborted, OutcomeError},
+	} {
+		if result := Outcome(test.err); result != test.outcome {
+			t.Errorf("%v: expected: %s, result: %s", test.err, test.outcome, result)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import "context"
+
+// Authentication methods of a Principal.
+const (
+	AuthAPIKey   = "api-key"
+	AuthJWT      = "jwt"
+	AuthAdminKey = "admin-key"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	Roles   []string
+	Scopes  []string
+	// Method is how the caller was authenticated, e.g. AuthJWT.
+	Method string
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the authenticated caller.
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the authenticated caller of ctx, false when the caller is anonymous.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of the service operations from the global tracer provider, a no-op until one is installed.
+var tracer = otel.Tracer("github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/service")
+
+// start starts the span of operation, defaulting a nil ctx to context.Background as the span context needs a parent.
+func start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, operation)
+}
+
+// end ends the span of operation and counts its outcome, given the address of the operation error result so that
+// it can be deferred. Only unexpected errors mark the span as failed: rejected requests are expected outcomes.
+func (s *Service) end(span trace.Span, operation string, err *error) {
+	outcome := Outcome(*err)
+	span.SetAttributes(attribute.String("outcome", outcome))
+	if outcome == OutcomeError {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+	if s.metrics != nil {
+		s.metrics.CountOutcome(operation, outcome)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestServiceTracing(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	defaultProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(defaultProvider) })
+	service := NewUserService(&MockUserRepository{})
+
+	_, _ = service.FindById(context.Background(), "missing")
+	// atomic imports need a transactor
+	_, _ = service.Import(context.Background(), []ImportRow{{User: validUser}}, true)
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("expected 2 spans, result: %d", len(ended))
+	}
+	notFound, failed := ended[0], ended[1]
+	if notFound.Name() != "UserService.FindById" || notFound.Status().Code == codes.Error ||
+		!hasAttribute(notFound.Attributes(), attribute.String("outcome", OutcomeNotFound)) {
+		t.Errorf("expected a FindById span with the not_found outcome, result: %s %v %v", notFound.Name(), notFound.Status(), notFound.Attributes())
+	}
+	if failed.Name() != "UserService.Import" || failed.Status().Code != codes.Error ||
+		!hasAttribute(failed.Attributes(), attribute.String("outcome", OutcomeError)) {
+		t.Errorf("expected a failed Import span, result: %s %v %v", failed.Name(), failed.Status(), failed.Attributes())
+	}
+}
+
+func hasAttribute(attributes []attribute.KeyValue, expected attribute.KeyValue) bool {
+	for _, a := range attributes {
+		if a == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrTransactionsUnsupported = errors.New("the storage backend does not support transactions")
+
+// Transactor runs fn in a transaction. The repository calls fn makes with the context it receives
+// are committed together when fn returns nil and rolled back when it returns an error.
+// Calls made within a transaction join it instead of starting a new one.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Option configures optional Service dependencies.
+type Option func(s *Service)
+
+// WithTransactor lets the service group repository writes in transactions,
+// which all-or-nothing imports require.
+func WithTransactor(t Transactor) Option {
+	return func(s *Service) {
+		s.tx = t
+	}
+}
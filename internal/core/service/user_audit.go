@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"time"
+)
+
+// AnonymousActor is the actor recorded for changes made by unidentified callers.
+const AnonymousActor = "anonymous"
+
+var ErrAuditDisabled = errors.New("the audit log is disabled")
+
+// AuditLog stores the audit entries of user changes.
+type AuditLog interface {
+	// Record stores e, giving it an ID when it has none. IDs increase with the recording order.
+	Record(ctx context.Context, e model.AuditEntry) error
+	// History returns up to limit entries of the user, newest first. When before is not empty,
+	// only the entries older than the entry with that ID are returned.
+	History(ctx context.Context, userID string, before string, limit int) ([]model.AuditEntry, error)
+}
+
+// WithAuditLog records every user change in a. When the service also has a Transactor,
+// entries are recorded in the transaction of the change they describe.
+func WithAuditLog(a AuditLog) Option {
+	return func(s *Service) {
+		s.audit = a
+	}
+}
+
+type requestIDKey struct{}
+
+// ActorFrom returns the actor of ctx, the subject of its Principal, AnonymousActor when the caller is anonymous.
+func ActorFrom(ctx context.Context) string {
+	if principal, ok := PrincipalFrom(ctx); ok && principal.Subject != "" {
+		return principal.Subject
+	}
+	return AnonymousActor
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the ID of the request being served.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// HistoryParams are the raw history paging parameters received from clients.
+type HistoryParams struct {
+	Cursor string
+	Limit  int
+}
+
+type HistoryPage struct {
+	Entries    []model.AuditEntry
+	NextCursor string
+	Limit      int
+}
+
+// History returns a page of the audit entries of the user, newest first. Users removed for good keep their history.
+func (s *Service) History(ctx context.Context, userID string, params HistoryParams) (_ *HistoryPage, err error) {
+	ctx, span := start(ctx, "UserService.History")
+	defer s.end(span, "history", &err)
+	if err := s.policy.Authorize(ctx, PermUsersRead, userID); err != nil {
+		return nil, err
+	}
+	if s.audit == nil {
+		return nil, ErrAuditDisabled
+	}
+	limit, before, err := newHistoryQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	// fetch one extra entry to know whether there is a next page
+	entries, err := s.audit.History(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 && before == "" {
+		if err := s.checkUserExists(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	page := &HistoryPage{Entries: entries, Limit: limit}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(page.Entries[limit-1].ID))
+	}
+	if page.Entries == nil {
+		page.Entries = []model.AuditEntry{}
+	}
+	return page, nil
+}
+
+func newHistoryQuery(params HistoryParams) (int, string, error) {
+	var details []string
+	limit := params.Limit
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit < 0 || limit > MaxPageSize {
+		details = append(details, fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+	}
+	before, err := base64.RawURLEncoding.DecodeString(params.Cursor)
+	if err != nil {
+		details = append(details, "invalid cursor")
+	}
+	if len(details) > 0 {
+		return 0, "", ValidationError{Message: "invalid history parameters", Details: details}
+	}
+	return limit, string(before), nil
+}
+
+// checkUserExists returns ErrUserNotFound unless the user is live or soft-deleted.
+func (s *Service) checkUserExists(ctx context.Context, id string) error {
+	user, err := s.repo.FindById(ctx, id)
+	if err != nil || user != nil {
+		return err
+	}
+	user, err = s.repo.FindDeletedById(ctx, id)
+	if err != nil || user != nil {
+		return err
+	}
+	return ErrUserNotFound
+}
+
+// record adds the change of a user from before to after to the audit log, and its event to the outbox.
+// A nil before is a creation, a nil after a removal.
+func (s *Service) record(ctx context.Context, operation string, before, after *model.User) error {
+	if err := s.emit(ctx, operation, before, after); err != nil {
+		return err
+	}
+	if s.audit == nil {
+		return nil
+	}
+	user := after
+	if user == nil {
+		user = before
+	}
+	return s.audit.Record(ctx, model.AuditEntry{
+		UserID:    user.ID,
+		Operation: operation,
+		Actor:     ActorFrom(ctx),
+		RequestID: RequestIDFrom(ctx),
+		At:        time.Now().UTC(),
+		Changes:   model.Diff(before, after),
+	})
+}
+
+// withinTransaction runs fn in a transaction when the service has a Transactor, so that the change fn
+// makes, its audit entry and its event are stored together.
+func (s *Service) withinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithinTransaction(ctx, fn)
+}
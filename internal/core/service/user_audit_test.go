@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"reflect"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx := ContextWithRequestID(ContextWithPrincipal(context.Background(), Principal{Subject: "admin"}), "req-1")
+	auditedService := func() (*Service, *MockUserRepository, *MockAuditLog) {
+		mockRepo := &MockUserRepository{}
+		auditLog := &MockAuditLog{}
+		return NewUserService(mockRepo, WithAuditLog(auditLog), WithTransactor(&MockTransactor{Repo: mockRepo, Audit: auditLog})), mockRepo, auditLog
+	}
+
+	t.Run("Record every change with a field diff", func(t *testing.T) {
+		service, _, auditLog := auditedService()
+
+		if _, err := service.Save(ctx, validUser); err != nil {
+			t.Fatalf("error saving user: %v", err)
+		}
+		changed := validUser
+		changed.Email = "johnny@doe.com"
+		if _, err := service.Update(ctx, changed); err != nil {
+			t.Fatalf("error updating user: %v", err)
+		}
+		if err := service.Delete(ctx, validUser.ID, false); err != nil {
+			t.Fatalf("error deleting user: %v", err)
+		}
+		if _, err := service.Restore(ctx, validUser.ID); err != nil {
+			t.Fatalf("error restoring user: %v", err)
+		}
+		if err := service.Delete(ctx, validUser.ID, true); err != nil {
+			t.Fatalf("error deleting user: %v", err)
+		}
+
+		operations := []string{model.OperationCreate, model.OperationUpdate, model.OperationDelete, model.OperationRestore, model.OperationDelete}
+		if len(auditLog.Entries) != len(operations) {
+			t.Fatalf("expected: %d entries, result: %v", len(operations), auditLog.Entries)
+		}
+		for i, entry := range auditLog.Entries {
+			if entry.Operation != operations[i] || entry.UserID != validUser.ID || entry.Actor != "admin" || entry.RequestID != "req-1" || entry.At.IsZero() {
+				t.Errorf("expected: %s entry of %s by admin, result: %+v", operations[i], validUser.ID, entry)
+			}
+		}
+		expected := []model.FieldChange{{Field: model.FieldEmail, Before: "john@doe.com", After: "johnny@doe.com"}}
+		if !reflect.DeepEqual(auditLog.Entries[1].Changes, expected) {
+			t.Errorf("expected: %v, result: %v", expected, auditLog.Entries[1].Changes)
+		}
+		if changes := auditLog.Entries[2].Changes; len(changes) != 1 || changes[0].Field != model.FieldDeletedAt || changes[0].Before != nil {
+			t.Errorf("expected deletedAt to be set, result: %v", changes)
+		}
+		if changes := auditLog.Entries[4].Changes; len(changes) != 4 || changes[0].After != nil {
+			t.Errorf("expected every field to be unset, result: %v", changes)
+		}
+	})
+	t.Run("Failed changes are not recorded", func(t *testing.T) {
+		service, mockRepo, auditLog := auditedService()
+		mockRepo.Users = []model.User{validUser}
+
+		stale := validUser
+		stale.Version = 7
+		if _, err := service.Update(ctx, stale); !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("expected: %v, result: %v", ErrVersionConflict, err)
+		}
+		if len(auditLog.Entries) != 0 {
+			t.Errorf("expected no entries, result: %v", auditLog.Entries)
+		}
+	})
+	t.Run("Anonymous callers", func(t *testing.T) {
+		service, _, auditLog := auditedService()
+
+		if _, err := service.Save(context.Background(), validUser); err != nil {
+			t.Fatalf("error saving user: %v", err)
+		}
+		if entry := auditLog.Entries[0]; entry.Actor != AnonymousActor || entry.RequestID != "" {
+			t.Errorf("expected anonymous entry, result: %+v", entry)
+		}
+	})
+}
+
+func TestUserHistory(t *testing.T) {
+	ctx := context.Background()
+	auditLog := &MockAuditLog{}
+	mockRepo := &MockUserRepository{}
+	service := NewUserService(mockRepo, WithAuditLog(auditLog))
+	if _, err := service.Save(ctx, validUser); err != nil {
+		t.Fatalf("error saving user: %v", err)
+	}
+	for _, age := range []int{30, 31} {
+		changed := validUser
+		changed.Age, changed.Version = age, 0
+		if _, err := service.Update(ctx, changed); err != nil {
+			t.Fatalf("error updating user: %v", err)
+		}
+	}
+
+	t.Run("Pages go from the newest entry to the oldest", func(t *testing.T) {
+		page, err := service.History(ctx, validUser.ID, HistoryParams{Limit: 2})
+		if err != nil {
+			t.Fatalf("error reading history: %v", err)
+		}
+		if len(page.Entries) != 2 || page.Entries[0].Changes[0].After != 31 || page.NextCursor == "" {
+			t.Fatalf("expected the two updates and a next cursor, result: %+v", page)
+		}
+		page, err = service.History(ctx, validUser.ID, HistoryParams{Limit: 2, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatalf("error reading history: %v", err)
+		}
+		if len(page.Entries) != 1 || page.Entries[0].Operation != model.OperationCreate || page.NextCursor != "" {
+			t.Errorf("expected the creation only, result: %+v", page)
+		}
+	})
+	t.Run("History of a user removed for good", func(t *testing.T) {
+		mockRepo.Users = nil
+
+		page, err := service.History(ctx, validUser.ID, HistoryParams{})
+		if err != nil || len(page.Entries) != 3 {
+			t.Errorf("expected 3 entries, result: %v, %v", page, err)
+		}
+	})
+	t.Run("Should return error with user not found", func(t *testing.T) {
+		if _, err := service.History(ctx, "unknown", HistoryParams{}); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrUserNotFound, err)
+		}
+	})
+	t.Run("Should return error with invalid parameters", func(t *testing.T) {
+		var validationErr ValidationError
+		if _, err := service.History(ctx, validUser.ID, HistoryParams{Cursor: "!", Limit: 1000}); !errors.As(err, &validationErr) || len(validationErr.Details) != 2 {
+			t.Errorf("expected validation error with 2 details, result: %v", err)
+		}
+	})
+	t.Run("Should return error when the audit log is disabled", func(t *testing.T) {
+		if _, err := NewUserService(mockRepo).History(ctx, validUser.ID, HistoryParams{}); !errors.Is(err, ErrAuditDisabled) {
+			t.Errorf("expected: %v, result: %v", ErrAuditDisabled, err)
+		}
+	})
+}
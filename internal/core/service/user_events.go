@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"log/slog"
+	"time"
+)
+
+const (
+	DefaultRelayInterval  = time.Second
+	DefaultRelayBatchSize = 100
+)
+
+// Outbox stores the events of user changes until they are published.
+type Outbox interface {
+	// Add stores e as pending, giving it an ID when it has none. IDs increase with the adding order.
+	Add(ctx context.Context, e model.Event) error
+	// Pending returns up to limit events not yet published, oldest first.
+	Pending(ctx context.Context, limit int) ([]model.Event, error)
+	MarkPublished(ctx context.Context, id string, at time.Time) error
+}
+
+// Publisher delivers events to their consumers.
+type Publisher interface {
+	Publish(ctx context.Context, e model.Event) error
+}
+
+// WithOutbox adds an event to o for every user change. With a Transactor the event is stored
+// in the transaction of the change, so that no change goes without its event and no event without its change.
+func WithOutbox(o Outbox) Option {
+	return func(s *Service) {
+		s.outbox = o
+	}
+}
+
+var eventTypes = map[string]string{
+	model.OperationCreate:  model.EventUserCreated,
+	model.OperationUpdate:  model.EventUserUpdated,
+	model.OperationRestore: model.EventUserUpdated,
+	model.OperationDelete:  model.EventUserDeleted,
+}
+
+// emit adds the event of a user change to the outbox. A nil after is a removal.
+func (s *Service) emit(ctx context.Context, operation string, before, after *model.User) error {
+	if s.outbox == nil {
+		return nil
+	}
+	user := after
+	if operation == model.OperationDelete || user == nil {
+		user = before
+	}
+	return s.outbox.Add(ctx, model.Event{
+		Type:       eventTypes[operation],
+		UserID:     user.ID,
+		OccurredAt: time.Now().UTC(),
+		RequestID:  RequestIDFrom(ctx),
+		User:       *user,
+	})
+}
+
+// Relay publishes the events of an Outbox, in order and at least once: an event whose publication
+// failed is published again, with the events following it, on the next attempt.
+type Relay struct {
+	outbox    Outbox
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+// NewRelay returns a relay checking outbox for pending events every interval, DefaultRelayInterval when zero.
+func NewRelay(outbox Outbox, publisher Publisher, interval time.Duration) *Relay {
+	if interval <= 0 {
+		interval = DefaultRelayInterval
+	}
+	return &Relay{outbox: outbox, publisher: publisher, interval: interval, batchSize: DefaultRelayBatchSize}
+}
+
+// Run publishes pending events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.PublishPending(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "failed to publish user events", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes the pending events, batch after batch, and returns how many were published.
+// It stops at the first event that could not be published.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		events, err := r.outbox.Pending(ctx, r.batchSize)
+		if err != nil {
+			return published, err
+		}
+		for _, event := range events {
+			if err := r.publisher.Publish(ctx, event); err != nil {
+				return published, err
+			}
+			if err := r.outbox.MarkPublished(ctx, event.ID, time.Now().UTC()); err != nil {
+				return published, err
+			}
+			published++
+		}
+		if len(events) < r.batchSize {
+			return published, nil
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"testing"
+	"time"
+)
+
+func TestUserEvents(t *testing.T) {
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	newService := func() (*Service, *MockUserRepository, *MockOutbox) {
+		mockRepo := &MockUserRepository{}
+		outbox := &MockOutbox{}
+		return NewUserService(mockRepo, WithOutbox(outbox), WithTransactor(&MockTransactor{Repo: mockRepo, Outbox: outbox})), mockRepo, outbox
+	}
+
+	t.Run("Emit an event for every change", func(t *testing.T) {
+		service, _, outbox := newService()
+
+		if _, err := service.Save(ctx, validUser); err != nil {
+			t.Fatalf("error saving user: %v", err)
+		}
+		changed := validUser
+		changed.Age = 40
+		if _, err := service.Update(ctx, changed); err != nil {
+			t.Fatalf("error updating user: %v", err)
+		}
+		if err := service.Delete(ctx, validUser.ID, false); err != nil {
+			t.Fatalf("error deleting user: %v", err)
+		}
+		if _, err := service.Restore(ctx, validUser.ID); err != nil {
+			t.Fatalf("error restoring user: %v", err)
+		}
+		if err := service.Delete(ctx, validUser.ID, true); err != nil {
+			t.Fatalf("error deleting user: %v", err)
+		}
+
+		types := []string{model.EventUserCreated, model.EventUserUpdated, model.EventUserDeleted, model.EventUserUpdated, model.EventUserDeleted}
+		if len(outbox.Events) != len(types) {
+			t.Fatalf("expected: %d events, result: %v", len(types), outbox.Events)
+		}
+		for i, event := range outbox.Events {
+			if event.Type != types[i] || event.UserID != validUser.ID || event.User.ID != validUser.ID || event.RequestID != "req-1" || event.OccurredAt.IsZero() {
+				t.Errorf("expected: %s event of %s, result: %+v", types[i], validUser.ID, event)
+			}
+		}
+		if outbox.Events[1].User.Age != 40 || outbox.Events[1].User.Version != 2 {
+			t.Errorf("expected the updated user, result: %+v", outbox.Events[1].User)
+		}
+		if outbox.Events[2].User.DeletedAt != nil {
+			t.Errorf("expected the user as it was before the delete, result: %+v", outbox.Events[2].User)
+		}
+	})
+	t.Run("Failed changes emit no event", func(t *testing.T) {
+		service, mockRepo, outbox := newService()
+		mockRepo.Users = []model.User{validUser}
+
+		sameName := validUser
+		sameName.ID = "other"
+		if _, err := service.Save(ctx, sameName); !errors.Is(err, ErrUsernameTaken) {
+			t.Errorf("expected: %v, result: %v", ErrUsernameTaken, err)
+		}
+		if err := service.Delete(ctx, "unknown", true); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrUserNotFound, err)
+		}
+		if len(outbox.Events) != 0 {
+			t.Errorf("expected no events, result: %v", outbox.Events)
+		}
+	})
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	newOutbox := func(n int) *MockOutbox {
+		outbox := &MockOutbox{}
+		for i := 0; i < n; i++ {
+			_ = outbox.Add(ctx, model.Event{Type: model.EventUserCreated, UserID: validUser.ID})
+		}
+		return outbox
+	}
+
+	t.Run("Publish pending events in order", func(t *testing.T) {
+		outbox := newOutbox(5)
+		publisher := &MockPublisher{}
+		relay := NewRelay(outbox, publisher, 0)
+		relay.batchSize = 2
+
+		published, err := relay.PublishPending(ctx)
+		if err != nil || published != 5 {
+			t.Fatalf("expected: 5 published events, result: %d, %v", published, err)
+		}
+		for i, event := range publisher.Events {
+			if event.ID != outbox.Events[i].ID {
+				t.Errorf("expected: %s, result: %s", outbox.Events[i].ID, event.ID)
+			}
+		}
+		if published, _ := relay.PublishPending(ctx); published != 0 {
+			t.Errorf("published events should not be published again, result: %d", published)
+		}
+	})
+	t.Run("Stop at the first failure and retry from it", func(t *testing.T) {
+		outbox := newOutbox(3)
+		errUnavailable := errors.New("unavailable")
+		publisher := &MockPublisher{FailAfter: 1, Err: errUnavailable}
+		relay := NewRelay(outbox, publisher, 0)
+
+		published, err := relay.PublishPending(ctx)
+		if !errors.Is(err, errUnavailable) || published != 1 {
+			t.Errorf("expected: 1 published event and %v, result: %d, %v", errUnavailable, published, err)
+		}
+		publisher.Err = nil
+		if published, err := relay.PublishPending(ctx); err != nil || published != 2 {
+			t.Errorf("expected: 2 published events, result: %d, %v", published, err)
+		}
+		if len(publisher.Events) != 3 || publisher.Events[1].ID != outbox.Events[1].ID {
+			t.Errorf("expected events in order, result: %v", publisher.Events)
+		}
+	})
+	t.Run("Run until the context is done", func(t *testing.T) {
+		outbox := newOutbox(1)
+		publisher := &MockPublisher{}
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			NewRelay(outbox, publisher, time.Millisecond).Run(runCtx)
+			close(done)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("relay did not stop")
+		}
+		if len(publisher.Events) != 1 {
+			t.Errorf("expected: 1 published event, result: %v", publisher.Events)
+		}
+	})
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+)
+
+// Export calls fn with every user matching f, in ID order, reading them incrementally from the repository.
+// It stops at the first error returned by fn.
+func (s *Service) Export(ctx context.Context, f UserFilter, fn func(u model.User) error) (err error) {
+	ctx, span := start(ctx, "UserService.Export")
+	defer s.end(span, "export", &err)
+	if err := s.policy.Authorize(ctx, PermUsersRead, ""); err != nil {
+		return err
+	}
+	if details := filterDetails(f); len(details) > 0 {
+		return ValidationError{Message: "invalid export parameters", Details: details}
+	}
+	return s.repo.Stream(ctx, f, fn)
+}
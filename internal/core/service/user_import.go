@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"strings"
+)
+
+// MaxImportSize is the maximum number of users imported at once.
+const MaxImportSize = 10000
+
+var (
+	ErrDuplicateInBatch = errors.New("user with the same first and last name appears earlier in the batch")
+	ErrImportAborted    = errors.New("user not created because another row of the batch failed")
+)
+
+// ImportRow is a user to import. Err is set when the row could not be decoded into a user.
+type ImportRow struct {
+	User model.User
+	Err  error
+}
+
+// ImportResult is the outcome of an import row: the created user or the reason it was not created.
+type ImportResult struct {
+	// Row is the 1-based position of the row in the batch.
+	Row  int
+	User *model.User
+	Err  error
+}
+
+type ImportReport struct {
+	Atomic  bool
+	Created int
+	Failed  int
+	Results []ImportResult
+}
+
+// WithCaseInsensitiveNames compares the names of an import batch regardless of case, for repositories whose
+// name uniqueness is case-insensitive.
+func WithCaseInsensitiveNames() Option {
+	return func(s *Service) {
+		s.caseInsensitiveNames = true
+	}
+}
+
+// rowError aborts an atomic import transaction because of the row at index.
+type rowError struct {
+	index int
+	err   error
+}
+
+func (e rowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.index+1, e.err)
+}
+
+// Import creates the users of rows, each validated as in Save. Names must also be unique within the batch.
+// Without atomic every valid row is created independently; with atomic the users are created in a single
+// transaction and none is created when any row fails.
+func (s *Service) Import(ctx context.Context, rows []ImportRow, atomic bool) (_ *ImportReport, err error) {
+	ctx, span := start(ctx, "UserService.Import")
+	defer s.end(span, "import", &err)
+	if err := s.policy.Authorize(ctx, PermUsersWrite, ""); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ValidationError{Message: "invalid batch", Details: []string{"the batch has no users"}}
+	}
+	if len(rows) > MaxImportSize {
+		return nil, ValidationError{Message: "invalid batch", Details: []string{fmt.Sprintf("the batch has more than %d users", MaxImportSize)}}
+	}
+	if atomic && s.tx == nil {
+		return nil, ErrTransactionsUnsupported
+	}
+	report := &ImportReport{Atomic: atomic, Results: make([]ImportResult, len(rows))}
+	users := make([]*model.User, len(rows))
+	names := map[[2]string]bool{}
+	for i, row := range rows {
+		report.Results[i].Row = i + 1
+		if row.Err != nil {
+			report.Results[i].Err = row.Err
+			continue
+		}
+		user, err := model.NewUser("", row.User.FirstName, row.User.LastName, row.User.Email, row.User.Age)
+		if err != nil {
+			report.Results[i].Err = err
+			continue
+		}
+		name := s.nameKey(*user)
+		if names[name] {
+			report.Results[i].Err = ErrDuplicateInBatch
+			continue
+		}
+		names[name] = true
+		users[i] = user
+	}
+	if atomic {
+		if err := s.importAtomically(ctx, users, report); err != nil {
+			return nil, err
+		}
+	} else {
+		for i, user := range users {
+			if user != nil {
+				report.Results[i].User, report.Results[i].Err = s.Save(ctx, *user)
+			}
+		}
+	}
+	for _, result := range report.Results {
+		if result.Err != nil {
+			report.Failed++
+		} else {
+			report.Created++
+		}
+	}
+	return report, nil
+}
+
+// nameKey identifies the name of u in the batch, the way the repository compares names.
+func (s *Service) nameKey(u model.User) [2]string {
+	if s.caseInsensitiveNames {
+		return [2]string{strings.ToLower(u.FirstName), strings.ToLower(u.LastName)}
+	}
+	return [2]string{u.FirstName, u.LastName}
+}
+
+// importAtomically creates users in a transaction when every row is valid, and marks the rows not created otherwise.
+func (s *Service) importAtomically(ctx context.Context, users []*model.User, report *ImportReport) error {
+	for _, result := range report.Results {
+		if result.Err != nil {
+			abortImport(report)
+			return nil
+		}
+	}
+	created := make([]*model.User, len(users))
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i, user := range users {
+			savedUser, err := s.Save(ctx, *user)
+			if errors.Is(err, ErrUsernameTaken) {
+				return rowError{index: i, err: err}
+			}
+			if err != nil {
+				return err
+			}
+			created[i] = savedUser
+		}
+		return nil
+	})
+	var rowErr rowError
+	if errors.As(err, &rowErr) {
+		report.Results[rowErr.index].Err = rowErr.err
+		abortImport(report)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for i, user := range created {
+		report.Results[i].User = user
+	}
+	return nil
+}
+
+// abortImport marks the rows that did not fail as not created because of the others.
+func abortImport(report *ImportReport) {
+	for i := range report.Results {
+		if report.Results[i].Err == nil {
+			report.Results[i].Err = ErrImportAborted
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func importRows(users ...model.User) []ImportRow {
+	rows := make([]ImportRow, len(users))
+	for i, u := range users {
+		rows[i] = ImportRow{User: u}
+	}
+	return rows
+}
+
+func TestImportUsers(t *testing.T) {
+	john := model.User{FirstName: "John", LastName: "Doe", Email: "john@doe.com", Age: 30}
+	jane := model.User{FirstName: "Jane", LastName: "Doe", Email: "jane@doe.com", Age: 31}
+	invalid := model.User{FirstName: "Jim", LastName: "Doe", Email: "jim", Age: 32}
+	storedJane := savedUser(jane)
+	storedJane.ID = primitive.NewObjectID().Hex()
+	errDecode := model.ValidationErrors{{Field: model.FieldAge, Code: "type", Message: "age must be an integer"}}
+
+	t.Run("Create every valid row and report the others", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{storedJane}}
+		service := NewUserService(mockRepo)
+		rows := append(importRows(john, invalid, john, jane), ImportRow{Err: errDecode})
+
+		report, err := service.Import(context.Background(), rows, false)
+		if err != nil {
+			t.Fatalf("error importing users: %v", err)
+		}
+		if report.Created != 1 || report.Failed != 4 || len(mockRepo.Users) != 2 {
+			t.Errorf("expected 1 created and 4 failed rows, result: %+v", report)
+		}
+		if report.Results[0].User == nil || report.Results[0].User.Version != 1 {
+			t.Errorf("expected created user, result: %+v", report.Results[0])
+		}
+		var fieldErrs model.ValidationErrors
+		if !errors.As(report.Results[1].Err, &fieldErrs) {
+			t.Errorf("expected validation error, result: %v", report.Results[1].Err)
+		}
+		expected := []error{ErrDuplicateInBatch, ErrUsernameTaken, errDecode}
+		for i, err := range expected {
+			if result := report.Results[i+2]; result.Row != i+3 || !reflectErr(result.Err, err) {
+				t.Errorf("expected: row %d %v, result: row %d %v", i+3, err, result.Row, result.Err)
+			}
+		}
+	})
+	t.Run("Compare names regardless of case with case-insensitive names", func(t *testing.T) {
+		shouting := john
+		shouting.FirstName, shouting.LastName = "JOHN", "doe"
+		mockRepo := &MockUserRepository{}
+		service := NewUserService(mockRepo, WithTransactor(&MockTransactor{Repo: mockRepo}), WithCaseInsensitiveNames())
+
+		report, err := service.Import(context.Background(), importRows(john, shouting), true)
+		if err != nil {
+			t.Fatalf("error importing users: %v", err)
+		}
+		if !errors.Is(report.Results[1].Err, ErrDuplicateInBatch) || !errors.Is(report.Results[0].Err, ErrImportAborted) {
+			t.Errorf("expected the second row to be a duplicate, result: %+v", report.Results)
+		}
+		report, _ = NewUserService(&MockUserRepository{}).Import(context.Background(), importRows(john, shouting), false)
+		if report.Created != 2 {
+			t.Errorf("expected names to be case-sensitive by default, result: %+v", report)
+		}
+	})
+	t.Run("Atomic import creates every user", func(t *testing.T) {
+		mockRepo := &MockUserRepository{}
+		service := NewUserService(mockRepo, WithTransactor(&MockTransactor{Repo: mockRepo}))
+
+		report, err := service.Import(context.Background(), importRows(john, jane), true)
+		if err != nil {
+			t.Fatalf("error importing users: %v", err)
+		}
+		if report.Created != 2 || len(mockRepo.Users) != 2 {
+			t.Errorf("expected 2 created users, result: %+v", report)
+		}
+	})
+	t.Run("Atomic import creates no user when a row is invalid", func(t *testing.T) {
+		mockRepo := &MockUserRepository{}
+		service := NewUserService(mockRepo, WithTransactor(&MockTransactor{Repo: mockRepo}))
+
+		report, _ := service.Import(context.Background(), importRows(john, invalid), true)
+		if report.Created != 0 || len(mockRepo.Users) != 0 {
+			t.Errorf("expected no created user, result: %+v", report)
+		}
+		if !errors.Is(report.Results[0].Err, ErrImportAborted) {
+			t.Errorf("expected: %v, result: %v", ErrImportAborted, report.Results[0].Err)
+		}
+	})
+	t.Run("Atomic import rolls back when a name is taken", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{storedJane}}
+		service := NewUserService(mockRepo, WithTransactor(&MockTransactor{Repo: mockRepo}))
+
+		report, _ := service.Import(context.Background(), importRows(john, jane), true)
+		if len(mockRepo.Users) != 1 {
+			t.Errorf("created users should be rolled back, result: %v", mockRepo.Users)
+		}
+		if !errors.Is(report.Results[0].Err, ErrImportAborted) || !errors.Is(report.Results[1].Err, ErrUsernameTaken) {
+			t.Errorf("expected aborted and taken rows, result: %+v", report.Results)
+		}
+	})
+	t.Run("Atomic import requires transactions", func(t *testing.T) {
+		service := NewUserService(&MockUserRepository{})
+
+		_, err := service.Import(context.Background(), importRows(john), true)
+		if !errors.Is(err, ErrTransactionsUnsupported) {
+			t.Errorf("expected: %v, result: %v", ErrTransactionsUnsupported, err)
+		}
+	})
+	t.Run("Should return validation error for an empty batch", func(t *testing.T) {
+		service := NewUserService(&MockUserRepository{})
+
+		_, err := service.Import(context.Background(), nil, false)
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected validation error, result: %v", err)
+		}
+	})
+}
+
+func reflectErr(result, expected error) bool {
+	var fieldErrs model.ValidationErrors
+	if errors.As(expected, &fieldErrs) {
+		return result != nil && result.Error() == expected.Error()
+	}
+	return errors.Is(result, expected)
+}
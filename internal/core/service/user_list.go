@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"strings"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Fields users can be sorted by. SortByID is the default and the tie-breaker for all other fields.
+const (
+	SortByID        = "id"
+	SortByFirstName = "firstName"
+	SortByLastName  = "lastName"
+	SortByEmail     = "email"
+	SortByAge       = "age"
+)
+
+var sortableFields = map[string]bool{
+	SortByID:        true,
+	SortByFirstName: true,
+	SortByLastName:  true,
+	SortByEmail:     true,
+	SortByAge:       true,
+}
+
+// UserFilter narrows down listed users. Zero values are ignored.
+type UserFilter struct {
+	FirstName string
+	LastName  string
+	Email     string
+	MinAge    int
+	MaxAge    int
+}
+
+type UserSort struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor points at the last user of a page; the next page starts right after it.
+// Value holds the sort field of that user in its string form.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	ID    string `json:"id"`
+	Value string `json:"v,omitempty"`
+}
+
+// UserQuery is what a repository needs to fetch one page of users.
+type UserQuery struct {
+	Filter UserFilter
+	Sort   UserSort
+	After  *Cursor
+	Limit  int
+}
+
+// ListParams are the raw listing parameters received from clients.
+// Sort is a field name, optionally prefixed with "-" for descending order.
+type ListParams struct {
+	Filter UserFilter
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+type UserPage struct {
+	Users      []model.User
+	NextCursor string
+	TotalCount int64
+	Limit      int
+}
+
+func (s *Service) List(ctx context.Context, params ListParams) (_ *UserPage, err error) {
+	ctx, span := start(ctx, "UserService.List")
+	defer s.end(span, "list", &err)
+	if err := s.policy.Authorize(ctx, PermUsersRead, ""); err != nil {
+		return nil, err
+	}
+	query, err := newUserQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Count(ctx, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	// fetch one extra user to know whether there is a next page
+	query.Limit++
+	users, err := s.repo.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	page := &UserPage{Users: users, TotalCount: total, Limit: limit}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = encodeCursor(newCursor(query.Sort, page.Users[limit-1]))
+	}
+	if page.Users == nil {
+		page.Users = []model.User{}
+	}
+	return page, nil
+}
+
+func newUserQuery(params ListParams) (UserQuery, error) {
+	var details []string
+	query := UserQuery{Filter: params.Filter, Limit: params.Limit}
+	if query.Limit == 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.Limit < 0 || query.Limit > MaxPageSize {
+		details = append(details, fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+	}
+	details = append(details, filterDetails(params.Filter)...)
+	sort, err := parseUserSort(params.Sort)
+	if err != nil {
+		details = append(details, err.Error())
+	}
+	query.Sort = sort
+	if params.Cursor != "" {
+		cursor, err := decodeCursor(params.Cursor)
+		if err != nil || cursor.Sort != sort.Field || cursor.Desc != sort.Desc {
+			details = append(details, "invalid cursor")
+		}
+		query.After = cursor
+	}
+	if len(details) > 0 {
+		return UserQuery{}, ValidationError{Message: "invalid list parameters", Details: details}
+	}
+	return query, nil
+}
+
+// filterDetails lists what is wrong with f.
+func filterDetails(f UserFilter) []string {
+	var details []string
+	if f.MinAge < 0 || f.MaxAge < 0 {
+		details = append(details, "age range must not be negative")
+	}
+	if f.MaxAge > 0 && f.MinAge > f.MaxAge {
+		details = append(details, "minAge must not be greater than maxAge")
+	}
+	return details
+}
+
+func parseUserSort(s string) (UserSort, error) {
+	if s == "" {
+		return UserSort{Field: SortByID}, nil
+	}
+	sort := UserSort{Field: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	if !sortableFields[sort.Field] {
+		return UserSort{Field: SortByID}, fmt.Errorf("cannot sort by %q", sort.Field)
+	}
+	return sort, nil
+}
+
+func newCursor(sort UserSort, last model.User) Cursor {
+	cursor := Cursor{Sort: sort.Field, Desc: sort.Desc, ID: last.ID}
+	switch sort.Field {
+	case SortByFirstName:
+		cursor.Value = last.FirstName
+	case SortByLastName:
+		cursor.Value = last.LastName
+	case SortByEmail:
+		cursor.Value = last.Email
+	case SortByAge:
+		cursor.Value = fmt.Sprint(last.Age)
+	}
+	return cursor
+}
+
+func encodeCursor(c Cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID == "" {
+		return nil, fmt.Errorf("cursor without id")
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"strconv"
+)
+
+type MockAuditLog struct {
+	Entries []model.AuditEntry
+}
+
+func (m *MockAuditLog) Record(_ context.Context, e model.AuditEntry) error {
+	if e.ID == "" {
+		e.ID = strconv.Itoa(len(m.Entries) + 1)
+	}
+	m.Entries = append(m.Entries, e)
+	return nil
+}
+
+func (m *MockAuditLog) History(_ context.Context, userID string, before string, limit int) ([]model.AuditEntry, error) {
+	var entries []model.AuditEntry
+	older := before == ""
+	for i := len(m.Entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := m.Entries[i]
+		if older && entry.UserID == userID {
+			entries = append(entries, entry)
+		}
+		older = older || entry.ID == before
+	}
+	return entries, nil
+}
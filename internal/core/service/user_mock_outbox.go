@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"strconv"
+	"time"
+)
+
+type MockOutbox struct {
+	Events    []model.Event
+	Published map[string]time.Time
+}
+
+func (m *MockOutbox) Add(_ context.Context, e model.Event) error {
+	if e.ID == "" {
+		e.ID = strconv.Itoa(len(m.Events) + 1)
+	}
+	m.Events = append(m.Events, e)
+	return nil
+}
+
+func (m *MockOutbox) Pending(_ context.Context, limit int) ([]model.Event, error) {
+	var events []model.Event
+	for _, event := range m.Events {
+		if _, published := m.Published[event.ID]; !published && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockOutbox) MarkPublished(_ context.Context, id string, at time.Time) error {
+	if m.Published == nil {
+		m.Published = map[string]time.Time{}
+	}
+	m.Published[id] = at
+	return nil
+}
+
+// MockPublisher records the published events. It fails, with Err, once Events holds FailAfter events.
+type MockPublisher struct {
+	Events    []model.Event
+	FailAfter int
+	Err       error
+}
+
+func (m *MockPublisher) Publish(_ context.Context, e model.Event) error {
+	if m.Err != nil && len(m.Events) >= m.FailAfter {
+		return m.Err
+	}
+	m.Events = append(m.Events, e)
+	return nil
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
-	"errors"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type MockUserRepository struct {
@@ -12,11 +15,11 @@ type MockUserRepository struct {
 
 func (m *MockUserRepository) FindById(_ context.Context, id string) (*model.User, error) {
 	for _, user := range m.Users {
-		if user.ID == id {
+		if user.ID == id && user.DeletedAt == nil {
 			return &user, nil
 		}
 	}
-	return nil, errors.New("user not found")
+	return nil, nil
 }
 func (m *MockUserRepository) Save(_ context.Context, u model.User) (*model.User, error) {
 	m.Users = append(m.Users, u)
@@ -25,23 +28,184 @@ func (m *MockUserRepository) Save(_ context.Context, u model.User) (*model.User,
 func (m *MockUserRepository) Update(_ context.Context, updatedUser model.User) (*model.User, error) {
 	index := -1
 	for i, user := range m.Users {
-		if user.ID == updatedUser.ID {
+		if user.ID == updatedUser.ID && user.DeletedAt == nil && (updatedUser.Version == 0 || updatedUser.Version == user.Version) {
 			index = i
 			break
 		}
 	}
 	if index == -1 {
-		return nil, errors.New("user not found")
+		return nil, nil
 	}
+	updatedUser.Version = m.Users[index].Version + 1
 	m.Users[index] = updatedUser
 	return &m.Users[index], nil
 }
+func (m *MockUserRepository) UpdateFields(ctx context.Context, u model.User, fields []string) (*model.User, error) {
+	existingUser, _ := m.FindById(ctx, u.ID)
+	if existingUser == nil {
+		return nil, nil
+	}
+	existingUser.Version = u.Version
+	for _, field := range fields {
+		switch field {
+		case model.FieldFirstName:
+			existingUser.FirstName = u.FirstName
+		case model.FieldLastName:
+			existingUser.LastName = u.LastName
+		case model.FieldEmail:
+			existingUser.Email = u.Email
+		case model.FieldAge:
+			existingUser.Age = u.Age
+		}
+	}
+	return m.Update(ctx, *existingUser)
+}
 
 func (m *MockUserRepository) ExistsByFirstNameAndLastName(_ context.Context, u model.User) (bool, error) {
 	for _, user := range m.Users {
-		if user.ID != u.ID && user.FirstName == u.FirstName && user.LastName == u.LastName {
+		if user.ID != u.ID && user.DeletedAt == nil && user.FirstName == u.FirstName && user.LastName == u.LastName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockUserRepository) List(_ context.Context, q UserQuery) ([]model.User, error) {
+	var users []model.User
+	for _, user := range m.Users {
+		if matchesFilter(user, q.Filter) {
+			users = append(users, user)
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		return compareUsers(users[i], users[j], q.Sort) < 0
+	})
+	if q.After != nil {
+		for len(users) > 0 && compareUsers(users[0], afterUser(*q.After), q.Sort) <= 0 {
+			users = users[1:]
+		}
+	}
+	if len(users) > q.Limit {
+		users = users[:q.Limit]
+	}
+	return users, nil
+}
+
+func (m *MockUserRepository) Count(_ context.Context, f UserFilter) (int64, error) {
+	var count int64
+	for _, user := range m.Users {
+		if matchesFilter(user, f) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockUserRepository) Stream(ctx context.Context, f UserFilter, fn func(u model.User) error) error {
+	users, _ := m.List(ctx, UserQuery{Filter: f, Limit: len(m.Users)})
+	for _, user := range users {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockUserRepository) FindDeletedById(_ context.Context, id string) (*model.User, error) {
+	for _, user := range m.Users {
+		if user.ID == id && user.DeletedAt != nil {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepository) SoftDelete(_ context.Context, id string, at time.Time) (bool, error) {
+	for i, user := range m.Users {
+		if user.ID == id && user.DeletedAt == nil {
+			m.Users[i].DeletedAt = &at
 			return true, nil
 		}
 	}
 	return false, nil
 }
+
+func (m *MockUserRepository) Delete(_ context.Context, id string) (bool, error) {
+	for i, user := range m.Users {
+		if user.ID == id {
+			m.Users = append(m.Users[:i], m.Users[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockUserRepository) Restore(_ context.Context, id string) (*model.User, error) {
+	for i, user := range m.Users {
+		if user.ID == id && user.DeletedAt != nil {
+			m.Users[i].DeletedAt = nil
+			return &m.Users[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func matchesFilter(u model.User, f UserFilter) bool {
+	return u.DeletedAt == nil &&
+		(f.FirstName == "" || u.FirstName == f.FirstName) &&
+		(f.LastName == "" || u.LastName == f.LastName) &&
+		(f.Email == "" || u.Email == f.Email) &&
+		(f.MinAge == 0 || u.Age >= f.MinAge) &&
+		(f.MaxAge == 0 || u.Age <= f.MaxAge)
+}
+
+func compareUsers(a, b model.User, s UserSort) int {
+	result := strings.Compare(newCursor(s, a).Value, newCursor(s, b).Value)
+	if s.Field == SortByAge {
+		result = a.Age - b.Age
+	}
+	if result == 0 {
+		result = strings.Compare(a.ID, b.ID)
+	}
+	if s.Desc {
+		return -result
+	}
+	return result
+}
+
+func afterUser(c Cursor) model.User {
+	user := model.User{ID: c.ID, FirstName: c.Value, LastName: c.Value, Email: c.Value}
+	user.Age, _ = strconv.Atoi(c.Value)
+	return user
+}
+
+// MockTransactor rolls back the users of Repo, and the entries of Audit and the events of Outbox when set,
+// if the transaction function fails.
+type MockTransactor struct {
+	Repo   *MockUserRepository
+	Audit  *MockAuditLog
+	Outbox *MockOutbox
+}
+
+func (m *MockTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	users := append([]model.User(nil), m.Repo.Users...)
+	var entries []model.AuditEntry
+	if m.Audit != nil {
+		entries = append(entries, m.Audit.Entries...)
+	}
+	var events []model.Event
+	if m.Outbox != nil {
+		events = append(events, m.Outbox.Events...)
+	}
+	if err := fn(ctx); err != nil {
+		m.Repo.Users = users
+		if m.Audit != nil {
+			m.Audit.Entries = entries
+		}
+		if m.Outbox != nil {
+			m.Outbox.Events = events
+		}
+		return err
+	}
+	return nil
+}
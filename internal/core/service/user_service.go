@@ -5,27 +5,57 @@ import (
 	"errors"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
 	"log/slog"
+	"time"
 )
 
 var (
-	ErrUserNotFound  = errors.New("user not found")
-	ErrUsernameTaken = errors.New("user with the same first and last name already exists")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrUsernameTaken   = errors.New("user with the same first and last name already exists")
+	ErrVersionConflict = errors.New("user was modified since the given version")
 )
 
 type UserRepository interface {
 	FindById(ctx context.Context, id string) (*model.User, error)
 	Save(ctx context.Context, u model.User) (*model.User, error)
+	// Update and UpdateFields increment the user version. When u.Version is not zero they only update the user
+	// if it still has that version, and return nil otherwise.
 	Update(ctx context.Context, u model.User) (*model.User, error)
+	UpdateFields(ctx context.Context, u model.User, fields []string) (*model.User, error)
 	ExistsByFirstNameAndLastName(ctx context.Context, u model.User) (bool, error)
+	List(ctx context.Context, q UserQuery) ([]model.User, error)
+	Count(ctx context.Context, f UserFilter) (int64, error)
+	// Stream calls fn with every live user matching f, in ID order, reading them incrementally
+	// instead of loading them all. It stops at the first error returned by fn and returns it.
+	Stream(ctx context.Context, f UserFilter, fn func(u model.User) error) error
+	FindDeletedById(ctx context.Context, id string) (*model.User, error)
+	SoftDelete(ctx context.Context, id string, at time.Time) (bool, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	Restore(ctx context.Context, id string) (*model.User, error)
 }
 type Service struct {
-	repo UserRepository
+	repo    UserRepository
+	tx      Transactor
+	audit   AuditLog
+	outbox  Outbox
+	policy  *Policy
+	metrics Metrics
+	// caseInsensitiveNames makes the names of an import batch unique regardless of case, as in the repository.
+	caseInsensitiveNames bool
 }
 
-func NewUserService(repo UserRepository) *Service {
-	return &Service{repo: repo}
+func NewUserService(repo UserRepository, opts ...Option) *Service {
+	s := &Service{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
+// UserPatch applies a partial change to a copy of the stored user and returns the result.
+type UserPatch func(current model.User) (model.User, error)
+
+// ValidationError rejects request parameters, such as list parameters or a patch document.
+// Invalid user fields are reported as model.ValidationErrors instead.
 type ValidationError struct {
 	Message string
 	Details []string
@@ -35,51 +65,261 @@ func (r ValidationError) Error() string {
 	return r.Message
 }
 
-func (s *Service) FindById(ctx context.Context, id string) (*model.User, error) {
+func (s *Service) FindById(ctx context.Context, id string) (_ *model.User, err error) {
+	ctx, span := start(ctx, "UserService.FindById")
+	defer s.end(span, "find", &err)
+	if err := s.policy.Authorize(ctx, PermUsersRead, id); err != nil {
+		return nil, err
+	}
 	user, err := s.repo.FindById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
-		slog.Warn("user not found")
+		slog.WarnContext(ctx, "user not found")
 		return nil, ErrUserNotFound
 	}
 	return user, nil
 }
 
-func (s *Service) Save(ctx context.Context, u model.User) (*model.User, error) {
-	usernameTaken, err := s.repo.ExistsByFirstNameAndLastName(ctx, u)
-	if err != nil {
+func (s *Service) Save(ctx context.Context, u model.User) (_ *model.User, err error) {
+	ctx, span := start(ctx, "UserService.Save")
+	defer s.end(span, "save", &err)
+	if err := s.policy.Authorize(ctx, PermUsersWrite, ""); err != nil {
 		return nil, err
 	}
-	if usernameTaken {
-		return nil, ErrUsernameTaken
+	if err := u.Validate(); err != nil {
+		return nil, err
 	}
-	savedUser, err := s.repo.Save(ctx, u)
+	u.Version = 1
+	var savedUser *model.User
+	err = s.withinTransaction(ctx, func(ctx context.Context) error {
+		usernameTaken, err := s.repo.ExistsByFirstNameAndLastName(ctx, u)
+		if err != nil {
+			return err
+		}
+		if usernameTaken {
+			return ErrUsernameTaken
+		}
+		if savedUser, err = s.repo.Save(ctx, u); err != nil {
+			return err
+		}
+		return s.record(ctx, model.OperationCreate, nil, savedUser)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return savedUser, nil
 }
 
-func (s *Service) Update(ctx context.Context, updatedUser model.User) (*model.User, error) {
-	existingUser, err := s.repo.FindById(ctx, updatedUser.ID)
+// Update replaces the user fields. A non-zero updatedUser.Version is the version the caller expects to overwrite,
+// ErrVersionConflict is returned when the stored user has a different one.
+func (s *Service) Update(ctx context.Context, updatedUser model.User) (_ *model.User, err error) {
+	ctx, span := start(ctx, "UserService.Update")
+	defer s.end(span, "update", &err)
+	if err := s.policy.Authorize(ctx, PermUsersWrite, updatedUser.ID); err != nil {
+		return nil, err
+	}
+	if err := updatedUser.Validate(); err != nil {
+		return nil, err
+	}
+	var updatedUserResult *model.User
+	err = s.withinTransaction(ctx, func(ctx context.Context) error {
+		existingUser, err := s.repo.FindById(ctx, updatedUser.ID)
+		if err != nil {
+			return err
+		}
+		if existingUser == nil {
+			return ErrUserNotFound
+		}
+		if updatedUser.Version != 0 && updatedUser.Version != existingUser.Version {
+			return ErrVersionConflict
+		}
+		usernameTaken, err := s.repo.ExistsByFirstNameAndLastName(ctx, updatedUser)
+		if err != nil {
+			return err
+		}
+		if usernameTaken {
+			return ErrUsernameTaken
+		}
+		if updatedUserResult, err = s.repo.Update(ctx, updatedUser); err != nil {
+			return err
+		}
+		if updatedUserResult == nil {
+			return notUpdatedErr(updatedUser.Version)
+		}
+		return s.record(ctx, model.OperationUpdate, existingUser, updatedUserResult)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updatedUserResult, nil
+}
+
+// Patch applies patch to the stored user, validates the result and persists only the fields that changed.
+// A non-zero version works as in Update.
+func (s *Service) Patch(ctx context.Context, id string, version int64, patch UserPatch) (_ *model.User, err error) {
+	ctx, span := start(ctx, "UserService.Patch")
+	defer s.end(span, "patch", &err)
+	if err := s.policy.Authorize(ctx, PermUsersWrite, id); err != nil {
+		return nil, err
+	}
+	var patchedUser *model.User
+	err = s.withinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		patchedUser, err = s.patch(ctx, id, version, patch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patchedUser, nil
+}
+
+func (s *Service) patch(ctx context.Context, id string, version int64, patch UserPatch) (*model.User, error) {
+	existingUser, err := s.repo.FindById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	if existingUser == nil {
 		return nil, ErrUserNotFound
 	}
-	usernameTaken, err := s.repo.ExistsByFirstNameAndLastName(ctx, updatedUser)
+	if version != 0 && version != existingUser.Version {
+		return nil, ErrVersionConflict
+	}
+	patched, err := patch(*existingUser)
+	if err != nil {
+		return nil, err
+	}
+	patchedUser, err := model.NewUser(existingUser.ID, patched.FirstName, patched.LastName, patched.Email, patched.Age)
+	if err != nil {
+		return nil, err
+	}
+	patchedUser.Version = version
+	changedFields := existingUser.ChangedFields(*patchedUser)
+	if len(changedFields) == 0 {
+		return existingUser, nil
+	}
+	if patchedUser.FirstName != existingUser.FirstName || patchedUser.LastName != existingUser.LastName {
+		usernameTaken, err := s.repo.ExistsByFirstNameAndLastName(ctx, *patchedUser)
+		if err != nil {
+			return nil, err
+		}
+		if usernameTaken {
+			return nil, ErrUsernameTaken
+		}
+	}
+	updatedUser, err := s.repo.UpdateFields(ctx, *patchedUser, changedFields)
+	if err != nil {
+		return nil, err
+	}
+	if updatedUser == nil {
+		return nil, notUpdatedErr(version)
+	}
+	if err := s.record(ctx, model.OperationUpdate, existingUser, updatedUser); err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
+
+// notUpdatedErr explains why a repository update matched no user: with an expected version,
+// another request changed the user in between, otherwise it was deleted.
+func notUpdatedErr(version int64) error {
+	if version != 0 {
+		return ErrVersionConflict
+	}
+	return ErrUserNotFound
+}
+
+// Delete soft-deletes the user unless hard is set, in which case the user is removed for good,
+// including users that were already soft-deleted.
+func (s *Service) Delete(ctx context.Context, id string, hard bool) (err error) {
+	ctx, span := start(ctx, "UserService.Delete")
+	defer s.end(span, "delete", &err)
+	if err := s.policy.Authorize(ctx, PermUsersDelete, id); err != nil {
+		return err
+	}
+	if hard {
+		if err := s.policy.Authorize(ctx, PermUsersDeleteHard, id); err != nil {
+			return err
+		}
+	}
+	return s.withinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.auditedUser(ctx, id, hard)
+		if err != nil {
+			return err
+		}
+		var deleted bool
+		var after *model.User
+		if hard {
+			deleted, err = s.repo.Delete(ctx, id)
+		} else {
+			at := time.Now().UTC()
+			deleted, err = s.repo.SoftDelete(ctx, id, at)
+			if before != nil {
+				softDeleted := *before
+				softDeleted.DeletedAt = &at
+				after = &softDeleted
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return ErrUserNotFound
+		}
+		if before == nil {
+			return nil
+		}
+		return s.record(ctx, model.OperationDelete, before, after)
+	})
+}
+
+// auditedUser returns the stored user a delete is about to change, if it is audited or has events.
+// Hard deletes also remove soft-deleted users.
+func (s *Service) auditedUser(ctx context.Context, id string, hard bool) (*model.User, error) {
+	if s.audit == nil && s.outbox == nil {
+		return nil, nil
+	}
+	user, err := s.repo.FindById(ctx, id)
+	if err != nil || user != nil || !hard {
+		return user, err
+	}
+	return s.repo.FindDeletedById(ctx, id)
+}
+
+func (s *Service) Restore(ctx context.Context, id string) (_ *model.User, err error) {
+	ctx, span := start(ctx, "UserService.Restore")
+	defer s.end(span, "restore", &err)
+	if err := s.policy.Authorize(ctx, PermUsersDelete, id); err != nil {
+		return nil, err
+	}
+	deletedUser, err := s.repo.FindDeletedById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if usernameTaken {
-		return nil, ErrUsernameTaken
+	if deletedUser == nil {
+		return nil, ErrUserNotFound
 	}
-	updatedUserResult, err := s.repo.Update(ctx, updatedUser)
+	var restoredUser *model.User
+	err = s.withinTransaction(ctx, func(ctx context.Context) error {
+		usernameTaken, err := s.repo.ExistsByFirstNameAndLastName(ctx, *deletedUser)
+		if err != nil {
+			return err
+		}
+		if usernameTaken {
+			return ErrUsernameTaken
+		}
+		if restoredUser, err = s.repo.Restore(ctx, id); err != nil {
+			return err
+		}
+		if restoredUser == nil {
+			return ErrUserNotFound
+		}
+		return s.record(ctx, model.OperationRestore, deletedUser, restoredUser)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return updatedUserResult, nil
+	return restoredUser, nil
 }
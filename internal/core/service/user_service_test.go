@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
@@ -10,9 +11,15 @@ import (
 
 var (
 	user, _   = model.NewUser(primitive.NewObjectID().Hex(), "John", "Doe", "john@doe.com", 21)
-	validUser = *user
+	validUser = savedUser(*user)
 )
 
+// savedUser returns u the way it is stored after being created.
+func savedUser(u model.User) model.User {
+	u.Version = 1
+	return u
+}
+
 func TestSaveUser(t *testing.T) {
 	t.Run("Save valid user", func(t *testing.T) {
 		mockRepo := &MockUserRepository{}
@@ -45,6 +52,23 @@ func TestSaveUser(t *testing.T) {
 			t.Errorf("duplicate user should not be saved in mock repository")
 		}
 	})
+	t.Run("Should return every invalid field", func(t *testing.T) {
+		mockRepo := &MockUserRepository{}
+		service := NewUserService(mockRepo)
+
+		_, err := service.Save(nil, model.User{FirstName: "John", Email: "john.doe", Age: 17})
+		expected := model.ValidationErrors{
+			{Field: model.FieldLastName, Code: model.RuleRequired, Message: "last name is required"},
+			{Field: model.FieldEmail, Code: model.RuleEmail, Message: "invalid email"},
+			{Field: model.FieldAge, Code: model.RuleMinAge, Message: "user must be at least 18 years old"},
+		}
+		if !reflect.DeepEqual(err, expected) {
+			t.Errorf("expected: %v, result: %v", expected, err)
+		}
+		if len(mockRepo.Users) != 0 {
+			t.Errorf("invalid user should not be saved in mock repository")
+		}
+	})
 }
 func TestFindUser(t *testing.T) {
 	t.Run("Find user", func(t *testing.T) {
@@ -85,6 +109,7 @@ func TestUpdateUser(t *testing.T) {
 		if err != nil {
 			t.Errorf("error finding user: %v", err)
 		}
+		updatedUser.Version = validUser.Version + 1
 		if !reflect.DeepEqual(result, updatedUser) {
 			t.Errorf("expected: %v, result: %v", updatedUser, result)
 		}
@@ -109,6 +134,38 @@ func TestUpdateUser(t *testing.T) {
 			t.Errorf("duplicate user should not be updated in mock repository")
 		}
 	})
+	t.Run("Update with the current version", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+
+		updatedUser, _ := model.NewUser(validUser.ID, "John", "Doe", "new@doe.com", 23)
+		updatedUser.Version = validUser.Version
+
+		result, err := service.Update(nil, *updatedUser)
+		if err != nil {
+			t.Fatalf("error updating user: %v", err)
+		}
+		if result.Version != validUser.Version+1 {
+			t.Errorf("expected version %d, result: %d", validUser.Version+1, result.Version)
+		}
+	})
+	t.Run("return conflict when the version is outdated", func(t *testing.T) {
+		stored := validUser
+		stored.Version = 3
+		mockRepo := &MockUserRepository{Users: []model.User{stored}}
+		service := NewUserService(mockRepo)
+
+		updatedUser, _ := model.NewUser(validUser.ID, "John", "Doe", "new@doe.com", 23)
+		updatedUser.Version = 2
+
+		_, err := service.Update(nil, *updatedUser)
+		if !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("expected: %v, result: %v", ErrVersionConflict, err)
+		}
+		if mockRepo.Users[0].Email != validUser.Email {
+			t.Errorf("outdated update should not be persisted")
+		}
+	})
 	t.Run("return error with existing user first and last name", func(t *testing.T) {
 		Users := []model.User{validUser}
 		mockRepo := &MockUserRepository{Users: Users}
@@ -122,3 +179,241 @@ func TestUpdateUser(t *testing.T) {
 		}
 	})
 }
+func TestListUsers(t *testing.T) {
+	newUsers := func() []model.User {
+		var users []model.User
+		for i, name := range []string{"Carl", "Anna", "Bob", "Dave", "Eve"} {
+			u, _ := model.NewUser(primitive.NewObjectID().Hex(), name, "Doe", name+"@doe.com", 20+i)
+			users = append(users, *u)
+		}
+		return users
+	}
+	t.Run("List all pages following the cursor", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: newUsers()}
+		service := NewUserService(mockRepo)
+
+		var names []string
+		params := ListParams{Sort: "firstName", Limit: 2}
+		for i := 0; i < 3; i++ {
+			page, err := service.List(nil, params)
+			if err != nil {
+				t.Fatalf("error listing users: %v", err)
+			}
+			if page.TotalCount != 5 {
+				t.Errorf("expected total count 5, result: %d", page.TotalCount)
+			}
+			for _, u := range page.Users {
+				names = append(names, u.FirstName)
+			}
+			if i < 2 && page.NextCursor == "" {
+				t.Fatalf("expected next cursor on page %d", i+1)
+			}
+			if i == 2 && page.NextCursor != "" {
+				t.Errorf("last page should not have a next cursor")
+			}
+			params.Cursor = page.NextCursor
+		}
+		expected := []string{"Anna", "Bob", "Carl", "Dave", "Eve"}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("expected: %v, result: %v", expected, names)
+		}
+	})
+	t.Run("Filter by age range in descending order", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: newUsers()}
+		service := NewUserService(mockRepo)
+
+		page, err := service.List(nil, ListParams{Filter: UserFilter{MinAge: 21, MaxAge: 23}, Sort: "-age"})
+		if err != nil {
+			t.Fatalf("error listing users: %v", err)
+		}
+		var ages []int
+		for _, u := range page.Users {
+			ages = append(ages, u.Age)
+		}
+		if !reflect.DeepEqual(ages, []int{23, 22, 21}) || page.TotalCount != 3 {
+			t.Errorf("unexpected page: %v", page)
+		}
+	})
+	t.Run("Should return validation error for invalid parameters", func(t *testing.T) {
+		service := NewUserService(&MockUserRepository{})
+
+		for _, params := range []ListParams{{Sort: "password"}, {Limit: 1000}, {Cursor: "not-a-cursor"}} {
+			_, err := service.List(nil, params)
+			var validationErr ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("expected validation error for %+v, result: %v", params, err)
+			}
+		}
+	})
+	t.Run("Should reject cursor issued for another sort", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: newUsers()}
+		service := NewUserService(mockRepo)
+
+		page, _ := service.List(nil, ListParams{Sort: "age", Limit: 1})
+		_, err := service.List(nil, ListParams{Sort: "-age", Limit: 1, Cursor: page.NextCursor})
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected validation error, result: %v", err)
+		}
+	})
+}
+func TestExportUsers(t *testing.T) {
+	t.Run("Export matching users", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+
+		var exported []model.User
+		err := service.Export(nil, UserFilter{LastName: validUser.LastName}, func(u model.User) error {
+			exported = append(exported, u)
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(exported, []model.User{validUser}) {
+			t.Errorf("expected: %v, result: %v, %v", []model.User{validUser}, exported, err)
+		}
+	})
+	t.Run("Should return validation error for invalid filter", func(t *testing.T) {
+		service := NewUserService(&MockUserRepository{})
+
+		err := service.Export(nil, UserFilter{MinAge: 30, MaxAge: 20}, func(model.User) error { return nil })
+		var validationErr ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected validation error, result: %v", err)
+		}
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	t.Run("Soft delete hides the user", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+
+		if err := service.Delete(nil, validUser.ID, false); err != nil {
+			t.Fatalf("error deleting user: %v", err)
+		}
+		if _, err := service.FindById(nil, validUser.ID); err == nil {
+			t.Errorf("soft-deleted user should not be found")
+		}
+		if len(mockRepo.Users) != 1 || mockRepo.Users[0].DeletedAt == nil {
+			t.Errorf("user should be kept with deletedAt set")
+		}
+		if err := service.Delete(nil, validUser.ID, false); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrUserNotFound, err)
+		}
+	})
+	t.Run("Hard delete removes the user", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+
+		if err := service.Delete(nil, validUser.ID, true); err != nil {
+			t.Fatalf("error deleting user: %v", err)
+		}
+		if len(mockRepo.Users) != 0 {
+			t.Errorf("user should be removed from mock repository")
+		}
+	})
+	t.Run("Should return error with user not found", func(t *testing.T) {
+		service := NewUserService(&MockUserRepository{})
+
+		if err := service.Delete(nil, validUser.ID, true); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrUserNotFound, err)
+		}
+	})
+}
+func TestRestoreUser(t *testing.T) {
+	t.Run("Restore soft-deleted user", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+		_ = service.Delete(nil, validUser.ID, false)
+
+		result, err := service.Restore(nil, validUser.ID)
+		if err != nil {
+			t.Fatalf("error restoring user: %v", err)
+		}
+		if !reflect.DeepEqual(result, &validUser) {
+			t.Errorf("expected: %v, result: %v", validUser, result)
+		}
+	})
+	t.Run("Should return error restoring a user that is not deleted", func(t *testing.T) {
+		service := NewUserService(&MockUserRepository{Users: []model.User{validUser}})
+
+		if _, err := service.Restore(nil, validUser.ID); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrUserNotFound, err)
+		}
+	})
+	t.Run("Should return error when the name was taken meanwhile", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+		_ = service.Delete(nil, validUser.ID, false)
+		sameName, _ := model.NewUser(primitive.NewObjectID().Hex(), validUser.FirstName, validUser.LastName, "other@doe.com", 30)
+		mockRepo.Users = append(mockRepo.Users, *sameName)
+
+		if _, err := service.Restore(nil, validUser.ID); !errors.Is(err, ErrUsernameTaken) {
+			t.Errorf("expected: %v, result: %v", ErrUsernameTaken, err)
+		}
+	})
+}
+func TestPatchUser(t *testing.T) {
+	setEmail := func(email string) UserPatch {
+		return func(current model.User) (model.User, error) {
+			current.Email = email
+			return current, nil
+		}
+	}
+	t.Run("Patch only the changed field", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+
+		result, err := service.Patch(nil, validUser.ID, 0, setEmail("patched@doe.com"))
+		if err != nil {
+			t.Fatalf("error patching user: %v", err)
+		}
+		expected := validUser
+		expected.Email = "patched@doe.com"
+		expected.Version++
+		if !reflect.DeepEqual(result, &expected) {
+			t.Errorf("expected: %v, result: %v", expected, result)
+		}
+	})
+	t.Run("Should return validation error for invalid result", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+
+		_, err := service.Patch(nil, validUser.ID, 0, setEmail("not-an-email"))
+		var validationErrs model.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			t.Errorf("expected validation error, result: %v", err)
+		}
+		if mockRepo.Users[0].Email != validUser.Email {
+			t.Errorf("invalid patch should not be persisted")
+		}
+	})
+	t.Run("Should return conflict for outdated version", func(t *testing.T) {
+		mockRepo := &MockUserRepository{Users: []model.User{validUser}}
+		service := NewUserService(mockRepo)
+
+		_, err := service.Patch(nil, validUser.ID, validUser.Version+1, setEmail("patched@doe.com"))
+		if !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("expected: %v, result: %v", ErrVersionConflict, err)
+		}
+	})
+	t.Run("Should return error when patched name is taken", func(t *testing.T) {
+		other, _ := model.NewUser(primitive.NewObjectID().Hex(), "Jane", "Doe", "jane@doe.com", 30)
+		mockRepo := &MockUserRepository{Users: []model.User{validUser, *other}}
+		service := NewUserService(mockRepo)
+
+		_, err := service.Patch(nil, validUser.ID, 0, func(current model.User) (model.User, error) {
+			current.FirstName = "Jane"
+			return current, nil
+		})
+		if !errors.Is(err, ErrUsernameTaken) {
+			t.Errorf("expected: %v, result: %v", ErrUsernameTaken, err)
+		}
+	})
+	t.Run("Should return error with user not found", func(t *testing.T) {
+		service := NewUserService(&MockUserRepository{})
+
+		if _, err := service.Patch(nil, validUser.ID, 0, setEmail("patched@doe.com")); err == nil {
+			t.Errorf("patch should fail for missing user")
+		}
+	})
+}
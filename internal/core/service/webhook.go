@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWebhookMaxAttempts = 8
+	DefaultWebhookBaseDelay   = 10 * time.Second
+	DefaultWebhookMaxDelay    = time.Hour
+	DefaultDispatchInterval   = time.Second
+	DefaultDispatchBatchSize  = 50
+	minWebhookSecretLength    = 16
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+// WebhookRepository stores the webhooks and the deliveries of events to them. It must be safe for concurrent use.
+type WebhookRepository interface {
+	// CreateWebhook stores w, giving it an ID.
+	CreateWebhook(ctx context.Context, w model.Webhook) (*model.Webhook, error)
+	// FindWebhook returns the webhook with that ID, nil when there is none.
+	FindWebhook(ctx context.Context, id string) (*model.Webhook, error)
+	// ListWebhooks returns every webhook, oldest first.
+	ListWebhooks(ctx context.Context) ([]model.Webhook, error)
+	// DeleteWebhook deletes the webhook and its deliveries. It returns false when there is no such webhook.
+	DeleteWebhook(ctx context.Context, id string) (bool, error)
+	// AddDelivery stores d, giving it an ID. A delivery of an event to a webhook is only stored once,
+	// adding it again is not an error.
+	AddDelivery(ctx context.Context, d model.Delivery) error
+	// DueDeliveries returns up to limit pending deliveries whose next attempt is due at now, earliest first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]model.Delivery, error)
+	// SaveDelivery replaces the status, next attempt time and attempts of the stored delivery.
+	SaveDelivery(ctx context.Context, d model.Delivery) error
+	// FindDelivery returns the delivery of the webhook with that ID, nil when there is none.
+	FindDelivery(ctx context.Context, webhookID string, id string) (*model.Delivery, error)
+	// Deliveries returns up to limit deliveries of the webhook, newest first, only those with status when not empty.
+	Deliveries(ctx context.Context, webhookID string, status string, limit int) ([]model.Delivery, error)
+}
+
+// WebhookSender makes a delivery attempt: it sends the event of d to the webhook and returns the response
+// status code, zero without response. Any response but a 2xx is an error.
+type WebhookSender interface {
+	Send(ctx context.Context, w model.Webhook, d model.Delivery) (int, error)
+}
+
+// RetryPolicy spaces out the attempts of failing deliveries: the nth retry waits BaseDelay * 2^(n-1),
+// up to MaxDelay. A delivery is dead once MaxAttempts attempts failed.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Delay is the time to wait after the failure of the given attempt, counted from 1.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// WebhookService manages the webhooks and delivers them the user events it is published.
+// It is the Publisher of the Relay: publishing an event queues a delivery to every subscribed webhook,
+// which Run then attempts until it succeeds or its retries are exhausted.
+type WebhookService struct {
+	repo      WebhookRepository
+	sender    WebhookSender
+	retry     RetryPolicy
+	interval  time.Duration
+	batchSize int
+	now       func() time.Time
+}
+
+type WebhookOption func(s *WebhookService)
+
+// WithRetryPolicy replaces the default retry policy. Zero fields keep their default.
+func WithRetryPolicy(p RetryPolicy) WebhookOption {
+	return func(s *WebhookService) {
+		if p.MaxAttempts > 0 {
+			s.retry.MaxAttempts = p.MaxAttempts
+		}
+		if p.BaseDelay > 0 {
+			s.retry.BaseDelay = p.BaseDelay
+		}
+		if p.MaxDelay > 0 {
+			s.retry.MaxDelay = p.MaxDelay
+		}
+	}
+}
+
+// WithDispatchInterval sets how often Run looks for due deliveries, DefaultDispatchInterval when zero.
+func WithDispatchInterval(interval time.Duration) WebhookOption {
+	return func(s *WebhookService) {
+		if interval > 0 {
+			s.interval = interval
+		}
+	}
+}
+
+func NewWebhookService(repo WebhookRepository, sender WebhookSender, opts ...WebhookOption) *WebhookService {
+	s := &WebhookService{
+		repo:      repo,
+		sender:    sender,
+		retry:     RetryPolicy{MaxAttempts: DefaultWebhookMaxAttempts, BaseDelay: DefaultWebhookBaseDelay, MaxDelay: DefaultWebhookMaxDelay},
+		interval:  DefaultDispatchInterval,
+		batchSize: DefaultDispatchBatchSize,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register validates and stores a webhook. Its events are deduplicated.
+func (s *WebhookService) Register(ctx context.Context, w model.Webhook) (*model.Webhook, error) {
+	if err := validateWebhook(w); err != nil {
+		return nil, err
+	}
+	var events []string
+	for _, event := range model.EventTypes {
+		if w.Subscribed(event) {
+			events = append(events, event)
+		}
+	}
+	w.ID, w.Events, w.CreatedAt = "", events, s.now().UTC()
+	return s.repo.CreateWebhook(ctx, w)
+}
+
+func validateWebhook(w model.Webhook) error {
+	var details []string
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details = append(details, "url must be an absolute http or https URL")
+	}
+	if len(w.Events) == 0 {
+		details = append(details, "events must list at least one of "+strings.Join(model.EventTypes, ", "))
+	}
+	for _, event := range w.Events {
+		if !slices.Contains(model.EventTypes, event) {
+			details = append(details, fmt.Sprintf("unknown event %q", event))
+		}
+	}
+	if len(w.Secret) < minWebhookSecretLength {
+		details = append(details, fmt.Sprintf("secret must be at least %d characters", minWebhookSecretLength))
+	}
+	if len(details) > 0 {
+		return ValidationError{Message: "invalid webhook", Details: details}
+	}
+	return nil
+}
+
+func (s *WebhookService) Webhooks(ctx context.Context) ([]model.Webhook, error) {
+	return s.repo.ListWebhooks(ctx)
+}
+
+func (s *WebhookService) Webhook(ctx context.Context, id string) (*model.Webhook, error) {
+	webhook, err := s.repo.FindWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// Unregister deletes the webhook. Its pending deliveries are dropped.
+func (s *WebhookService) Unregister(ctx context.Context, id string) error {
+	deleted, err := s.repo.DeleteWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeliveryParams are the raw delivery listing parameters received from clients.
+type DeliveryParams struct {
+	Status string
+	Limit  int
+}
+
+// Deliveries returns the latest deliveries of the webhook, newest first. The dead letters of a webhook
+// are its deliveries with the model.DeliveryDead status.
+func (s *WebhookService) Deliveries(ctx context.Context, webhookID string, params DeliveryParams) ([]model.Delivery, error) {
+	var details []string
+	switch params.Status {
+	case "", model.DeliveryPending, model.DeliverySucceeded, model.DeliveryDead:
+	default:
+		details = append(details, fmt.Sprintf("status must be one of %s, %s, %s", model.DeliveryPending, model.DeliverySucceeded, model.DeliveryDead))
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = DefaultPageSize
+	}
+	if limit < 0 || limit > MaxPageSize {
+		details = append(details, fmt.Sprintf("limit must be between 1 and %d", MaxPageSize))
+	}
+	if len(details) > 0 {
+		return nil, ValidationError{Message: "invalid delivery parameters", Details: details}
+	}
+	if _, err := s.Webhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.Deliveries(ctx, webhookID, params.Status, limit)
+}
+
+func (s *WebhookService) Delivery(ctx context.Context, webhookID string, id string) (*model.Delivery, error) {
+	delivery, err := s.repo.FindDelivery(ctx, webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+// Redeliver queues the delivery for an immediate attempt, whatever its status. Attempts count across
+// redeliveries, so a dead delivery gets a single new attempt.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID string, id string) (*model.Delivery, error) {
+	delivery, err := s.Delivery(ctx, webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Status, delivery.NextAttemptAt = model.DeliveryPending, s.now().UTC()
+	if err := s.repo.SaveDelivery(ctx, *delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Publish queues the delivery of e to every webhook subscribed to its type.
+func (s *WebhookService) Publish(ctx context.Context, e model.Event) error {
+	webhooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(e.Type) {
+			continue
+		}
+		delivery := model.Delivery{WebhookID: webhook.ID, Event: e, Status: model.DeliveryPending, NextAttemptAt: now, CreatedAt: now}
+		if err := s.repo.AddDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run attempts the due deliveries until ctx is done.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.DeliverDue(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "failed to deliver webhooks", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue attempts the due deliveries, a batch at a time with the attempts of a batch made concurrently,
+// and returns how many attempts were made.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := s.repo.DueDeliveries(ctx, s.now().UTC(), s.batchSize)
+		if err != nil {
+			return attempted, err
+		}
+		webhooks := map[string]*model.Webhook{}
+		for _, delivery := range deliveries {
+			if _, ok := webhooks[delivery.WebhookID]; !ok {
+				if webhooks[delivery.WebhookID], err = s.repo.FindWebhook(ctx, delivery.WebhookID); err != nil {
+					return attempted, err
+				}
+			}
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, len(deliveries))
+		for i, delivery := range deliveries {
+			// the webhook was deleted while the delivery was added: retire the delivery instead of reading it again
+			if webhooks[delivery.WebhookID] == nil {
+				delivery.Status = model.DeliveryDead
+				if errs[i] = s.repo.SaveDelivery(ctx, delivery); errs[i] != nil {
+					break
+				}
+				continue
+			}
+			wg.Add(1)
+			go func(i int, webhook model.Webhook, delivery model.Delivery) {
+				defer wg.Done()
+				errs[i] = s.attempt(ctx, webhook, delivery)
+			}(i, *webhooks[delivery.WebhookID], delivery)
+			attempted++
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return attempted, err
+		}
+		if len(deliveries) < s.batchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// attempt sends the delivery and saves its outcome. Attempts interrupted by the end of ctx are not recorded.
+func (s *WebhookService) attempt(ctx context.Context, w model.Webhook, d model.Delivery) error {
+	start := s.now().UTC()
+	statusCode, err := s.sender.Send(ctx, w, d)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	attempt := model.DeliveryAttempt{At: start, StatusCode: statusCode, DurationMs: s.now().Sub(start).Milliseconds()}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	d.Attempts = append(d.Attempts, attempt)
+	switch {
+	case err == nil:
+		d.Status = model.DeliverySucceeded
+	case len(d.Attempts) >= s.retry.MaxAttempts:
+		d.Status = model.DeliveryDead
+		slog.WarnContext(ctx, "webhook delivery is dead", "webhook", w.ID, "delivery", d.ID, "attempts", len(d.Attempts), "error", err)
+	default:
+		d.NextAttemptAt = start.Add(s.retry.Delay(len(d.Attempts)))
+	}
+	return s.repo.SaveDelivery(ctx, d)
+}
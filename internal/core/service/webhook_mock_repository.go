@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type MockWebhookRepository struct {
+	mu       sync.Mutex
+	Webhooks []model.Webhook
+	Stored   []model.Delivery
+}
+
+func (m *MockWebhookRepository) CreateWebhook(_ context.Context, w model.Webhook) (*model.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.ID = strconv.Itoa(len(m.Webhooks) + 1)
+	m.Webhooks = append(m.Webhooks, w)
+	return &w, nil
+}
+
+func (m *MockWebhookRepository) FindWebhook(_ context.Context, id string) (*model.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, webhook := range m.Webhooks {
+		if webhook.ID == id {
+			return &webhook, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockWebhookRepository) ListWebhooks(_ context.Context) ([]model.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]model.Webhook{}, m.Webhooks...), nil
+}
+
+func (m *MockWebhookRepository) DeleteWebhook(_ context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, webhook := range m.Webhooks {
+		if webhook.ID == id {
+			m.Webhooks = append(m.Webhooks[:i], m.Webhooks[i+1:]...)
+			deliveries := m.Stored[:0]
+			for _, delivery := range m.Stored {
+				if delivery.WebhookID != id {
+					deliveries = append(deliveries, delivery)
+				}
+			}
+			m.Stored = deliveries
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockWebhookRepository) AddDelivery(_ context.Context, d model.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, delivery := range m.Stored {
+		if delivery.WebhookID == d.WebhookID && delivery.Event.ID == d.Event.ID {
+			return nil
+		}
+	}
+	d.ID = strconv.Itoa(len(m.Stored) + 1)
+	m.Stored = append(m.Stored, d)
+	return nil
+}
+
+func (m *MockWebhookRepository) DueDeliveries(_ context.Context, now time.Time, limit int) ([]model.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deliveries []model.Delivery
+	for _, delivery := range m.Stored {
+		if delivery.Status == model.DeliveryPending && !delivery.NextAttemptAt.After(now) && len(deliveries) < limit {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *MockWebhookRepository) SaveDelivery(_ context.Context, d model.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, delivery := range m.Stored {
+		if delivery.ID == d.ID {
+			m.Stored[i] = d
+			return nil
+		}
+	}
+	return fmt.Errorf("delivery %s not found", d.ID)
+}
+
+func (m *MockWebhookRepository) FindDelivery(_ context.Context, webhookID string, id string) (*model.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, delivery := range m.Stored {
+		if delivery.WebhookID == webhookID && delivery.ID == id {
+			return &delivery, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *MockWebhookRepository) Deliveries(_ context.Context, webhookID string, status string, limit int) ([]model.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deliveries []model.Delivery
+	for i := len(m.Stored) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := m.Stored[i]
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// MockWebhookSender records the sent deliveries and answers them with the next of Responses,
+// or 200 when there are no more. Responses outside 2xx fail the attempt.
+type MockWebhookSender struct {
+	mu        sync.Mutex
+	Sent      []model.Delivery
+	Responses []int
+}
+
+func (m *MockWebhookSender) Send(_ context.Context, _ model.Webhook, d model.Delivery) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Sent = append(m.Sent, d)
+	status := 200
+	if len(m.Responses) > 0 {
+		status, m.Responses = m.Responses[0], m.Responses[1:]
+	}
+	if status < 200 || status > 299 {
+		return status, fmt.Errorf("webhook answered %d", status)
+	}
+	return status, nil
+}
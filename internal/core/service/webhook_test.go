@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/viniciusgferreira/ps-tag-onboarding-go/internal/core/domain/model"
+	"testing"
+	"time"
+)
+
+var validWebhook = model.Webhook{
+	URL:    "https://partner.example.com/hooks",
+	Events: []string{model.EventUserUpdated, model.EventUserCreated, model.EventUserCreated},
+	Secret: "0123456789abcdef",
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for attempt, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 60: 10 * time.Second} {
+		if delay := policy.Delay(attempt); delay != expected {
+			t.Errorf("attempt %d: expected: %s, result: %s", attempt, expected, delay)
+		}
+	}
+}
+
+func TestWebhookService_Register(t *testing.T) {
+	ctx := context.Background()
+	service := NewWebhookService(&MockWebhookRepository{}, &MockWebhookSender{})
+
+	t.Run("Register a webhook", func(t *testing.T) {
+		webhook, err := service.Register(ctx, validWebhook)
+		if err != nil {
+			t.Fatalf("error registering webhook: %v", err)
+		}
+		if webhook.ID == "" || webhook.CreatedAt.IsZero() || len(webhook.Events) != 2 || webhook.Events[0] != model.EventUserCreated {
+			t.Errorf("expected a stored webhook with deduplicated events, result: %+v", webhook)
+		}
+	})
+	t.Run("Should return error with invalid webhook", func(t *testing.T) {
+		var validationErr ValidationError
+		_, err := service.Register(ctx, model.Webhook{URL: "ftp://partner", Events: []string{"UserRenamed"}, Secret: "short"})
+		if !errors.As(err, &validationErr) || len(validationErr.Details) != 3 {
+			t.Errorf("expected validation error with 3 details, result: %v", err)
+		}
+	})
+	t.Run("Should return error with unknown webhook", func(t *testing.T) {
+		if err := service.Unregister(ctx, "unknown"); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrWebhookNotFound, err)
+		}
+		if _, err := service.Deliveries(ctx, "unknown", DeliveryParams{}); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrWebhookNotFound, err)
+		}
+	})
+}
+
+func TestWebhookService_Deliver(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	newService := func(responses ...int) (*WebhookService, *MockWebhookRepository, *MockWebhookSender, *model.Webhook) {
+		repo, sender := &MockWebhookRepository{}, &MockWebhookSender{Responses: responses}
+		service := NewWebhookService(repo, sender, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute}))
+		service.now = func() time.Time { return now }
+		webhook, _ := service.Register(ctx, validWebhook)
+		return service, repo, sender, webhook
+	}
+	created := model.Event{ID: "1", Type: model.EventUserCreated, UserID: validUser.ID, User: validUser}
+
+	t.Run("Deliver events to subscribed webhooks once", func(t *testing.T) {
+		service, repo, sender, _ := newService()
+		for _, event := range []model.Event{created, created, {ID: "2", Type: model.EventUserDeleted}} {
+			if err := service.Publish(ctx, event); err != nil {
+				t.Fatalf("error publishing event: %v", err)
+			}
+		}
+
+		if attempted, err := service.DeliverDue(ctx); err != nil || attempted != 1 {
+			t.Fatalf("expected: 1 attempt, result: %d, %v", attempted, err)
+		}
+		if attempted, _ := service.DeliverDue(ctx); attempted != 0 {
+			t.Errorf("succeeded deliveries should not be attempted again, result: %d", attempted)
+		}
+		delivery := repo.Stored[0]
+		if len(sender.Sent) != 1 || sender.Sent[0].Event.ID != created.ID || delivery.Status != model.DeliverySucceeded ||
+			len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != 200 {
+			t.Errorf("expected a succeeded delivery of %s, result: %+v", created.ID, delivery)
+		}
+	})
+	t.Run("Retry with exponential backoff until the delivery is dead", func(t *testing.T) {
+		service, repo, sender, webhook := newService(500, 503, 500)
+		_ = service.Publish(ctx, created)
+
+		for i, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+			if attempted, err := service.DeliverDue(ctx); err != nil || attempted != 1 {
+				t.Fatalf("expected: 1 attempt, result: %d, %v", attempted, err)
+			}
+			if attempted, _ := service.DeliverDue(ctx); attempted != 0 {
+				t.Errorf("expected no attempt before the retry delay, result: %d", attempted)
+			}
+			delivery := repo.Stored[0]
+			if delivery.Status != model.DeliveryPending || !delivery.NextAttemptAt.Equal(now.Add(delay)) || delivery.Attempts[i].Error == "" {
+				t.Errorf("expected a retry in %s, result: %+v", delay, delivery)
+			}
+			now = delivery.NextAttemptAt
+		}
+		_, _ = service.DeliverDue(ctx)
+
+		dead, _ := service.Deliveries(ctx, webhook.ID, DeliveryParams{Status: model.DeliveryDead})
+		if len(dead) != 1 || len(dead[0].Attempts) != 3 || dead[0].Attempts[2].StatusCode != 500 {
+			t.Fatalf("expected a dead delivery after 3 attempts, result: %+v", dead)
+		}
+		redelivered, err := service.Redeliver(ctx, webhook.ID, dead[0].ID)
+		if err != nil || redelivered.Status != model.DeliveryPending {
+			t.Fatalf("expected a pending delivery, result: %v, %v", redelivered, err)
+		}
+		_, _ = service.DeliverDue(ctx)
+		if delivery, _ := service.Delivery(ctx, webhook.ID, dead[0].ID); delivery.Status != model.DeliverySucceeded || len(sender.Sent) != 4 {
+			t.Errorf("expected a succeeded delivery after 4 attempts, result: %+v", delivery)
+		}
+	})
+	t.Run("Deliveries of unregistered webhooks are dropped", func(t *testing.T) {
+		service, repo, sender, webhook := newService()
+		_ = service.Publish(ctx, created)
+		_ = service.Unregister(ctx, webhook.ID)
+		// added while the webhook was being unregistered
+		_ = repo.AddDelivery(ctx, model.Delivery{WebhookID: webhook.ID, Event: created, Status: model.DeliveryPending, NextAttemptAt: now})
+
+		if attempted, err := service.DeliverDue(ctx); err != nil || attempted != 0 || len(sender.Sent) != 0 {
+			t.Errorf("expected no attempt, result: %d, %v", attempted, err)
+		}
+		if status := repo.Stored[0].Status; status != model.DeliveryDead {
+			t.Errorf("expected: %s, result: %s", model.DeliveryDead, status)
+		}
+	})
+	t.Run("Should return error with invalid parameters", func(t *testing.T) {
+		service, _, _, webhook := newService()
+		var validationErr ValidationError
+		if _, err := service.Deliveries(ctx, webhook.ID, DeliveryParams{Status: "lost", Limit: -1}); !errors.As(err, &validationErr) || len(validationErr.Details) != 2 {
+			t.Errorf("expected validation error with 2 details, result: %v", err)
+		}
+		if _, err := service.Redeliver(ctx, webhook.ID, "unknown"); !errors.Is(err, ErrDeliveryNotFound) {
+			t.Errorf("expected: %v, result: %v", ErrDeliveryNotFound, err)
+		}
+	})
+}